@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"text/tabwriter"
+	"time"
 
 	manager "github.com/socialviolation/mcpv/internal/mcpv"
 	"github.com/spf13/cobra"
@@ -21,15 +23,22 @@ This command allows you to:
 Examples:
   mcpv agents list                    # List detected agents
   mcpv agents add server-name roocode # Add server to specific agent
-  mcpv agents remove server-name      # Remove server from all agents`,
+  mcpv agents remove server-name      # Remove server from all agents
+  mcpv agents status                  # Compare agents with mcpv.json
+  mcpv agents restore                 # Undo the last agent configuration change`,
 }
 
 // agentsListCmd represents the agents list command
 var agentsListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List detected AI agents",
-	Long:  `List all detected AI agents and their configuration file paths.`,
-	RunE:  runAgentsList,
+	Long: `List all detected AI agents, their configuration file paths and where each
+agent's definition came from.
+
+Agent definitions are built in to mcpv and can be overridden or extended, in
+increasing order of precedence, by ~/.config/mcpv/agents.yaml and by the
+"agents" section of the project's mcpv.json.`,
+	RunE: runAgentsList,
 }
 
 // agentsAddCmd represents the agents add command
@@ -61,39 +70,81 @@ Examples:
 	RunE: runAgentsRemove,
 }
 
+// agentsStatusCmd represents the agents status command
+var agentsStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Compare agent configurations with mcpv.json",
+	Long: `Compare each agent's server entries with the servers in mcpv.json.
+
+Every entry is reported as:
+  matches        the entry has the command, args and env mcpv.json specifies
+  missing        the server is in mcpv.json but not configured for the agent
+  differs        the entry was changed; the differing fields are listed
+  unmanaged      the entry was added outside mcpv or for another project and
+                 is left alone
+  orphaned       mcpv wrote the entry, but the server is no longer in mcpv.json
+  not_installed  the server in mcpv.json is not installed yet
+
+Exits with a non-zero status when any entry is missing, differs or is orphaned.
+With --fix, those entries are rewritten or removed.
+
+Examples:
+  mcpv agents status                  # Check detected agents
+  mcpv agents status --agent vscode   # Check one agent
+  mcpv agents status --fix            # Reconcile agents with mcpv.json
+  mcpv agents status --fix --dry-run  # Show what --fix would change`,
+	Args: cobra.NoArgs,
+	RunE: runAgentsStatus,
+}
+
+// agentsRestoreCmd represents the agents restore command
+var agentsRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore agent configurations from the last backup",
+	Long: `Restore agent configuration files from the most recent backup.
+
+mcpv backs up every agent configuration file before changing it. Restoring puts
+back the files saved by the last backup and discards it, so running restore again
+steps further back.
+
+Examples:
+  mcpv agents restore                 # Restore the last backup
+  mcpv agents restore --list          # List available backups`,
+	Args: cobra.NoArgs,
+	RunE: runAgentsRestore,
+}
+
 func runAgentsList(cmd *cobra.Command, args []string) error {
-	mgr, err := manager.NewManager()
+	mgr, err := newManager()
 	if err != nil {
 		return fmt.Errorf("failed to create manager: %w", err)
 	}
 
-	return mgr.ListAgentConfigurations()
+	all, _ := cmd.Flags().GetBool("all")
+
+	if isStructuredOutput() {
+		agents, err := mgr.AgentConfigurations(all)
+		if err != nil {
+			return err
+		}
+		return printResult(agentsResult{Agents: agents})
+	}
+
+	return mgr.ListAgentConfigurations(all)
 }
 
 func runAgentsAdd(cmd *cobra.Command, args []string) error {
 	serverName := args[0]
 	agentTypeStr := args[1]
 
-	mgr, err := manager.NewManager()
+	mgr, err := newManager()
 	if err != nil {
 		return fmt.Errorf("failed to create manager: %w", err)
 	}
 
-	// Get available agent types from the registry
-	registry := mgr.GetAgentConfigManager().GetRegistry()
-	availableTypes := registry.ListAgentTypes()
-
-	// Check if the specified agent type is valid
-	found := false
-	for _, agentType := range availableTypes {
-		if agentType == agentTypeStr {
-			found = true
-			break
-		}
-	}
-
-	if !found {
-		return fmt.Errorf("unsupported agent type: %s. Supported types: %v", agentTypeStr, availableTypes)
+	// Check if the specified agent type is defined
+	if !mgr.IsAgentType(agentTypeStr) {
+		return fmt.Errorf("unsupported agent type: %s. Supported types: %v", agentTypeStr, mgr.AgentTypes())
 	}
 
 	agentType := manager.AgentType(agentTypeStr)
@@ -118,21 +169,21 @@ func runAgentsAdd(cmd *cobra.Command, args []string) error {
 
 	// We need to get the full server details including command and args
 	// This is a limitation - we should store this info when listing servers
-	fmt.Printf("Warning: Adding server without execution details. You may need to manually configure the command and args in the agent config.\n")
+	fmt.Fprintf(messageWriter, "Warning: Adding server without execution details. You may need to manually configure the command and args in the agent config.\n")
 
 	// Add server to specific agent
 	if err := mgr.AddServerToAgent(agentType, targetServer); err != nil {
 		return fmt.Errorf("failed to add server to agent: %w", err)
 	}
 
-	fmt.Printf("Successfully added %s to %s configuration\n", serverName, agentType)
+	fmt.Fprintf(messageWriter, "Successfully added %s to %s configuration\n", serverName, agentType)
 	return nil
 }
 
 func runAgentsRemove(cmd *cobra.Command, args []string) error {
 	serverName := args[0]
 
-	mgr, err := manager.NewManager()
+	mgr, err := newManager()
 	if err != nil {
 		return fmt.Errorf("failed to create manager: %w", err)
 	}
@@ -141,13 +192,179 @@ func runAgentsRemove(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to remove server from agent configurations: %w", err)
 	}
 
-	fmt.Printf("Successfully removed %s from agent configurations\n", serverName)
+	fmt.Fprintf(messageWriter, "Successfully removed %s from agent configurations\n", serverName)
+	return nil
+}
+
+// agentStatusResult lists how agent configurations compare with mcpv.json
+type agentStatusResult struct {
+	Config  string                     `json:"config" yaml:"config"`
+	Entries []manager.AgentEntryStatus `json:"entries" yaml:"entries"`
+	Plan    *manager.Plan              `json:"plan,omitempty" yaml:"plan,omitempty"`
+}
+
+func runAgentsStatus(cmd *cobra.Command, args []string) error {
+	mgr, err := newManagerForCommand(cmd)
+	if err != nil {
+		return err
+	}
+
+	configPath := cmd.Flag("config").Value.String()
+	if configPath == "" {
+		configPath = findConfigFile()
+	}
+	mgr.UseProject(configPath)
+	if _, err := mgr.LoadProjectConfig(configPath); err != nil {
+		return err
+	}
+
+	var agentTypes []manager.AgentType
+	if agentFlag := cmd.Flag("agent").Value.String(); agentFlag != "" {
+		if !mgr.IsAgentType(agentFlag) {
+			return fmt.Errorf("unsupported agent type: %s. Supported types: %v", agentFlag, mgr.AgentTypes())
+		}
+		agentTypes = []manager.AgentType{manager.AgentType(agentFlag)}
+	} else {
+		agentTypes = mgr.DetectAgents()
+	}
+
+	useGlobal, _ := cmd.Flags().GetBool("global")
+	fix, _ := cmd.Flags().GetBool("fix")
+
+	statuses, err := mgr.AgentStatus(configPath, agentTypes, !useGlobal, fix)
+	if err != nil {
+		return fmt.Errorf("failed to check agent configurations: %w", err)
+	}
+
+	if isStructuredOutput() {
+		if err := printResult(agentStatusResult{Config: configPath, Entries: statuses, Plan: mgr.Plan()}); err != nil {
+			return err
+		}
+	} else if len(agentTypes) == 0 {
+		fmt.Fprintln(messageWriter, "No supported AI agents detected.")
+	} else if len(statuses) == 0 {
+		fmt.Fprintln(messageWriter, "No servers configured in mcpv.json or in any agent")
+	} else {
+		w := tabwriter.NewWriter(messageWriter, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "AGENT\tSERVER\tSTATUS\tPATH")
+		fmt.Fprintln(w, "-----\t------\t------\t----")
+		for _, status := range statuses {
+			server := status.Server
+			if server == "" {
+				server = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.Agent, server, status.Describe(), status.Path)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if err := printPlan(mgr); err != nil {
+		return err
+	}
+
+	problems := 0
+	for _, status := range statuses {
+		if status.OutOfSync() {
+			problems++
+		}
+	}
+
+	if problems > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("agent configurations are out of sync with mcpv.json in %d place(s); run 'mcpv agents status --fix' to reconcile", problems)
+	}
+
+	return nil
+}
+
+func runAgentsRestore(cmd *cobra.Command, args []string) error {
+	mgr, err := newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	if list, _ := cmd.Flags().GetBool("list"); list {
+		backups, err := mgr.ListBackups()
+		if err != nil {
+			return fmt.Errorf("failed to list backups: %w", err)
+		}
+
+		if isStructuredOutput() {
+			return printResult(backupsResult{Backups: backups})
+		}
+
+		if len(backups) == 0 {
+			fmt.Fprintln(messageWriter, "No agent configuration backups found")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(messageWriter, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCREATED\tFILES\tDESCRIPTION")
+		fmt.Fprintln(w, "--\t-------\t-----\t-----------")
+		for _, backup := range backups {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", backup.ID, backup.Created.Local().Format(time.DateTime), len(backup.Files), backup.Description)
+		}
+		return w.Flush()
+	}
+
+	backup, err := mgr.RestoreLatestBackup()
+	if err != nil {
+		return fmt.Errorf("failed to restore agent configurations: %w", err)
+	}
+
+	if isStructuredOutput() {
+		return printResult(backupsResult{Backups: []*manager.Backup{backup}})
+	}
+
+	for _, entry := range backup.Files {
+		if entry.Existed {
+			fmt.Fprintf(messageWriter, "✓ Restored %s\n", entry.Path)
+		} else {
+			fmt.Fprintf(messageWriter, "✓ Removed %s (created after the backup)\n", entry.Path)
+		}
+	}
+	fmt.Fprintf(messageWriter, "Restored agent configurations from backup %s (%s)\n", backup.ID, backup.Description)
 	return nil
 }
 
+// targetAgents returns the agents a command acts on: the agent given with
+// --agent, otherwise the default agent from mcpv.json, otherwise all detected
+// agents
+func targetAgents(mgr *manager.Manager, cmd *cobra.Command, configPath string) ([]manager.AgentType, error) {
+	mgr.UseProject(configPath)
+	config, err := mgr.LoadProjectConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if agentFlag := cmd.Flag("agent").Value.String(); agentFlag != "" {
+		if !mgr.IsAgentType(agentFlag) {
+			return nil, fmt.Errorf("unsupported agent type: %s. Supported types: %v", agentFlag, mgr.AgentTypes())
+		}
+		return []manager.AgentType{manager.AgentType(agentFlag)}, nil
+	}
+
+	if config.DefaultAgent != "" {
+		return []manager.AgentType{manager.AgentType(config.DefaultAgent)}, nil
+	}
+
+	return mgr.DetectAgents(), nil
+}
+
 func init() {
 	rootCmd.AddCommand(agentsCmd)
 	agentsCmd.AddCommand(agentsListCmd)
 	agentsCmd.AddCommand(agentsAddCmd)
 	agentsCmd.AddCommand(agentsRemoveCmd)
+	agentsCmd.AddCommand(agentsStatusCmd)
+	agentsCmd.AddCommand(agentsRestoreCmd)
+	agentsListCmd.Flags().Bool("all", false, "Include agents that were not detected")
+	agentsStatusCmd.Flags().StringP("config", "c", "", "Path to mcpv.json config file")
+	agentsStatusCmd.Flags().StringP("agent", "a", "", "Check a specific agent only. If not specified, checks all detected agents")
+	agentsStatusCmd.Flags().BoolP("global", "g", false, "Check the global agent configuration instead of local (project-specific)")
+	agentsStatusCmd.Flags().Bool("fix", false, "Rewrite or remove entries that are out of sync with mcpv.json")
+	agentsStatusCmd.Flags().Bool("dry-run", false, "Print the planned actions and file changes without making them")
+	agentsRestoreCmd.Flags().Bool("list", false, "List available backups instead of restoring")
 }
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	manager "github.com/socialviolation/mcpv/internal/mcpv"
+	"github.com/spf13/cobra"
+)
+
+// bridgeShutdownTimeout is how long requests in progress get to finish when
+// the bridge is stopped
+const bridgeShutdownTimeout = 5 * time.Second
+
+// bridgeCmd represents the bridge command
+var bridgeCmd = &cobra.Command{
+	Use:   "bridge <server>[@version]",
+	Short: "Expose an installed stdio server over Streamable HTTP",
+	Long: `Run an installed server and expose it over the MCP Streamable HTTP transport,
+for clients that cannot launch stdio servers. Clients POST messages to the
+endpoint, may GET an event stream of messages the server sends on its own, and
+DELETE their session when they are done.
+
+Each client session gets its own server process, started when the client
+initializes and stopped when it ends the session or goes without requests
+for --idle-timeout. With --shared, all clients use one process that is
+started and initialized when the bridge starts, and restarted as the
+server's restart policy in mcpv.json says if it exits.
+
+Server output is written to standard error and to a log file shown by
+'mcpv logs'; 'mcpv ps' lists the processes.
+
+The server is resolved and configured as 'mcpv run' would. Only browser pages
+served from loopback addresses may call the bridge. On interrupt, requests in
+progress are given time to finish and the server processes are stopped.
+
+Examples:
+  mcpv bridge github                              # Listen on 127.0.0.1:8808/mcp
+  mcpv bridge github@1.2.0 --listen 127.0.0.1:9000
+  mcpv bridge github --shared                     # One process for all clients`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBridge,
+}
+
+func runBridge(cmd *cobra.Command, args []string) error {
+	mgr, err := newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	configPath := cmd.Flag("config").Value.String()
+	if configPath == "" {
+		configPath = findConfigFile()
+	}
+	listen := cmd.Flag("listen").Value.String()
+	path := cmd.Flag("path").Value.String()
+	shared, _ := cmd.Flags().GetBool("shared")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	idle, _ := cmd.Flags().GetDuration("idle-timeout")
+
+	name, version := manager.ParseServerSpec(args[0])
+	bridge, err := mgr.NewBridge(name, version, configPath, shared, timeout, idle, os.Stderr)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+	defer bridge.Close()
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", listen, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, bridge)
+	server := &http.Server{Handler: mux}
+	// Event streams only end with their session, so they are closed when
+	// shutting down rather than waited for
+	server.RegisterOnShutdown(bridge.CloseStreams)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	mode := "one process per session"
+	if shared {
+		mode = "one shared process"
+	}
+	fmt.Fprintf(messageWriter, "Serving %s@%s at http://%s%s (%s)\n", bridge.Server().Name, bridge.Server().Version, listener.Addr(), path, mode)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-served:
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-bridge.Exited():
+		server.Close()
+		return fmt.Errorf("server %s exited", name)
+	case <-signals:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), bridgeShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			fmt.Fprintf(messageWriter, "Warning: requests still in progress after %s were cancelled\n", bridgeShutdownTimeout)
+			return nil
+		}
+		return fmt.Errorf("failed to shut down: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(bridgeCmd)
+	bridgeCmd.Flags().StringP("config", "c", "", "Path to mcpv.json config file")
+	bridgeCmd.Flags().String("listen", "127.0.0.1:8808", "Address to listen on")
+	bridgeCmd.Flags().String("path", "/mcp", "Path of the MCP endpoint")
+	bridgeCmd.Flags().Bool("shared", false, "Use one server process for all clients instead of one per session")
+	bridgeCmd.Flags().Duration("timeout", manager.DefaultCheckTimeout, "How long the shared server may take to start")
+	bridgeCmd.Flags().Duration("idle-timeout", manager.DefaultBridgeIdleTimeout, "End client sessions without requests for this long (0 keeps them until the client ends them)")
+}
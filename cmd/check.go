@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	manager "github.com/socialviolation/mcpv/internal/mcpv"
+	"github.com/spf13/cobra"
+)
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check [server[@version]...]",
+	Short: "Check that servers start and answer the MCP handshake",
+	Long: `Perform the MCP initialize handshake with servers. Installed servers are started
+as 'mcpv run' would and reached over stdio; remote servers in mcpv.json are
+reached over HTTP. Reports the server's name and version, the protocol version
+it agreed to and its capabilities, or why it failed along with the end of its
+standard error. If no server is specified, checks every server in mcpv.json.
+
+Exits with an error if any server fails the check.
+
+Examples:
+  mcpv check                      # Check all servers from mcpv.json
+  mcpv check github               # Check the version mcpv.json uses
+  mcpv check github@1.2.0         # Check a specific installed version
+  mcpv check --timeout 10s        # Give servers less time to start`,
+	RunE: runCheck,
+}
+
+// checkResult lists the outcome of health checks
+type checkResult struct {
+	Config  string                 `json:"config,omitempty" yaml:"config,omitempty"`
+	Servers []*manager.CheckResult `json:"servers" yaml:"servers"`
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	mgr, err := newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	configPath := cmd.Flag("config").Value.String()
+	if configPath == "" {
+		configPath = findConfigFile()
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	var results []*manager.CheckResult
+	if len(args) == 0 {
+		if results, err = mgr.CheckServers(configPath, timeout); err != nil {
+			return err
+		}
+	} else {
+		for _, arg := range args {
+			name, version := manager.ParseServerSpec(arg)
+			results = append(results, mgr.CheckServer(name, version, configPath, timeout))
+		}
+	}
+
+	if isStructuredOutput() {
+		if err := printResult(checkResult{Config: configPath, Servers: results}); err != nil {
+			return err
+		}
+	} else if len(results) == 0 {
+		fmt.Fprintln(messageWriter, "No servers to check in mcpv.json")
+	} else if err := printCheckResults(results); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.OK {
+			failed++
+		}
+	}
+	if failed > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d of %d servers failed the check", failed, len(results))
+	}
+	return nil
+}
+
+// printCheckResults prints a table of check results followed by the output of
+// servers that failed
+func printCheckResults(results []*manager.CheckResult) error {
+	w := tabwriter.NewWriter(messageWriter, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVER\tVERSION\tSTATUS\tDETAILS")
+	fmt.Fprintln(w, "------\t-------\t------\t-------")
+	for _, result := range results {
+		version := result.Version
+		if version == "" {
+			version = "-"
+		}
+		if result.OK {
+			details := fmt.Sprintf("%s %s, protocol %s", result.ServerInfo.Name, result.ServerInfo.Version, result.ProtocolVersion)
+			if len(result.Capabilities) > 0 {
+				details += ", capabilities: " + strings.Join(result.Capabilities, ", ")
+			}
+			fmt.Fprintf(w, "%s\t%s\tok\t%s\n", result.Server, version, details)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Server, version, statusFailed, result.Error)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if result.Stderr == "" {
+			continue
+		}
+		fmt.Fprintf(messageWriter, "\nOutput of %s@%s:\n", result.Server, result.Version)
+		for _, line := range strings.Split(result.Stderr, "\n") {
+			fmt.Fprintf(messageWriter, "  %s\n", line)
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	checkCmd.Flags().StringP("config", "c", "", "Path to mcpv.json config file")
+	checkCmd.Flags().Duration("timeout", manager.DefaultCheckTimeout, "How long each server may take to answer the handshake")
+}
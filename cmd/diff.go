@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	manager "github.com/socialviolation/mcpv/internal/mcpv"
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <server@version> <server@version>",
+	Short: "Compare the tools, prompts and resources of two server versions",
+	Long: `Inspect two servers, usually two installed versions of one server, and report
+the tools, prompts, resources and resource templates that were added, removed or
+changed, including changes to the input schemas of tools.
+
+Changes that can break agents and prompts relying on the first server are
+flagged: removed tools, prompts and resources, removed parameters and arguments,
+newly required ones, parameter types that no longer accept the old type and
+narrowed enums. The command exits with an error when there are breaking changes,
+so it can gate upgrades in CI.
+
+Either side may also be a file written by 'mcpv inspect -o json'.
+
+Examples:
+  mcpv diff github@1.0.0 github@1.1.0       # Compare two installed versions
+  mcpv diff github-1.0.0.json github@1.1.0  # Compare against a saved inspection
+  mcpv diff github@1.0.0 github@1.1.0 -o json`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiff,
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	mgr, err := newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	configPath := cmd.Flag("config").Value.String()
+	if configPath == "" {
+		configPath = findConfigFile()
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	from, err := loadInspection(mgr, args[0], configPath, timeout)
+	if err != nil {
+		return err
+	}
+	to, err := loadInspection(mgr, args[1], configPath, timeout)
+	if err != nil {
+		return err
+	}
+
+	diff := manager.CompareInspections(from, to)
+
+	if isStructuredOutput() {
+		if err := printResult(diff); err != nil {
+			return err
+		}
+	} else if err := printSurfaceDiff(diff); err != nil {
+		return err
+	}
+
+	if breaking := diff.BreakingChanges(); breaking > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d breaking changes between %s and %s", breaking, diff.From, diff.To)
+	}
+	return nil
+}
+
+// loadInspection inspects a server, or reads an inspection saved as JSON
+func loadInspection(mgr *manager.Manager, arg, configPath string, timeout time.Duration) (*manager.Inspection, error) {
+	if strings.HasSuffix(arg, ".json") {
+		if data, err := os.ReadFile(arg); err == nil {
+			var inspection manager.Inspection
+			if err := json.Unmarshal(data, &inspection); err != nil {
+				return nil, fmt.Errorf("failed to parse inspection %s: %w", arg, err)
+			}
+			return &inspection, nil
+		}
+	}
+
+	name, version := manager.ParseServerSpec(arg)
+	inspection, err := mgr.InspectServer(name, version, configPath, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect %s: %w", arg, err)
+	}
+	return inspection, nil
+}
+
+// printSurfaceDiff prints the changes between two servers
+func printSurfaceDiff(diff *manager.SurfaceDiff) error {
+	if len(diff.Changes) == 0 {
+		fmt.Fprintf(messageWriter, "%s and %s expose the same tools, prompts and resources\n", diff.From, diff.To)
+		return nil
+	}
+
+	fmt.Fprintf(messageWriter, "Changes from %s to %s:\n\n", diff.From, diff.To)
+	w := tabwriter.NewWriter(messageWriter, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tCHANGE\tBREAKING\tDETAILS")
+	fmt.Fprintln(w, "----\t----\t------\t--------\t-------")
+	for _, change := range diff.Changes {
+		breaking := "no"
+		if change.Breaking {
+			breaking = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", change.Kind, change.Name, change.Change, breaking, change.Detail)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(messageWriter, "\n%d changes, %d breaking\n", len(diff.Changes), diff.BreakingChanges())
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringP("config", "c", "", "Path to mcpv.json config file")
+	diffCmd.Flags().Duration("timeout", manager.DefaultCheckTimeout, "How long each server may take to answer")
+}
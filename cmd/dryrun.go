@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+
+	manager "github.com/socialviolation/mcpv/internal/mcpv"
+	"github.com/spf13/cobra"
+)
+
+// newManagerForCommand creates a manager, switching it to dry-run mode when the
+// command's --dry-run flag is set
+func newManagerForCommand(cmd *cobra.Command) (*manager.Manager, error) {
+	mgr, err := newManager()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		mgr.EnableDryRun()
+		fmt.Fprintln(messageWriter, "Dry run: no changes will be made")
+	}
+
+	return mgr, nil
+}
+
+// printPlan prints the plan recorded by a dry run. Structured output embeds the
+// plan in the command result instead.
+func printPlan(mgr *manager.Manager) error {
+	if !mgr.IsDryRun() || isStructuredOutput() {
+		return nil
+	}
+	return mgr.Plan().Print(messageWriter)
+}
+
+// printDone prints a message reporting a completed change. Dry runs leave it
+// out, since the plan printed at the end lists the changes instead.
+func printDone(mgr *manager.Manager, format string, args ...interface{}) {
+	if mgr.IsDryRun() {
+		return
+	}
+	fmt.Fprintf(messageWriter, format, args...)
+}
@@ -21,6 +21,8 @@ Examples:
   mcpv init --agent claude        # Initialize with Claude Desktop as default agent
   mcpv init --agent roocode       # Initialize with RooCode as default agent
   mcpv init --agent cursor        # Initialize with Cursor as default agent
+  mcpv init --agent cursor --launcher shim  # Configure servers as version-independent shims
+  mcpv init --agent cursor --launcher serve # Configure a single mcpv serve entry
 
 Use 'mcpv agents list' to see available agent types.`,
 	RunE: runInit,
@@ -43,38 +45,32 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("default agent is required. Use --agent flag to specify one. Use 'mcpv agents list' to see available types")
 	}
 
-	mgr, err := manager.NewManager()
+	mgr, err := newManager()
 	if err != nil {
 		return fmt.Errorf("failed to create manager: %w", err)
 	}
 
-	// Get available agent types from the registry
-	registry := mgr.GetAgentConfigManager().GetRegistry()
-	availableTypes := registry.ListAgentTypes()
-
-	// Check if the specified agent type is valid
-	found := false
-	for _, agentType := range availableTypes {
-		if agentType == agentFlag {
-			found = true
-			break
-		}
+	// Check if the specified agent type is defined
+	if !mgr.IsAgentType(agentFlag) {
+		return fmt.Errorf("unsupported agent type: %s. Supported types: %v", agentFlag, mgr.AgentTypes())
 	}
 
-	if !found {
-		return fmt.Errorf("unsupported agent type: %s. Supported types: %v", agentFlag, availableTypes)
+	launcher := cmd.Flag("launcher").Value.String()
+	if launcher != "" && launcher != manager.LauncherExec && launcher != manager.LauncherShim && launcher != manager.LauncherServe {
+		return fmt.Errorf("unsupported launcher: %s. Use %s, %s or %s", launcher, manager.LauncherExec, manager.LauncherShim, manager.LauncherServe)
 	}
 
 	config := &manager.ProjectConfig{
 		Servers:      []manager.MCPServer{},
 		DefaultAgent: agentFlag,
+		Launcher:     launcher,
 	}
 
 	if err := mgr.SaveProjectConfig(config, configPath); err != nil {
 		return fmt.Errorf("failed to create mcpv.json: %w", err)
 	}
 
-	fmt.Printf("Created mcpv.json with default agent: %s\n", agentFlag)
+	fmt.Fprintf(messageWriter, "Created mcpv.json with default agent: %s\n", agentFlag)
 
 	return nil
 }
@@ -83,5 +79,6 @@ func init() {
 	rootCmd.AddCommand(initCmd)
 	initCmd.Flags().BoolP("force", "f", false, "Overwrite existing mcpv.json")
 	initCmd.Flags().StringP("agent", "a", "", "Default agent type (required). Use 'mcpv agents list' to see available types")
+	initCmd.Flags().String("launcher", "", "Configure agents to run servers through mcpv: exec, shim or serve. If not specified, agents run servers from their install path")
 	initCmd.MarkFlagRequired("agent")
 }
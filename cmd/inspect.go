@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/socialviolation/mcpv/internal/mcp"
+	manager "github.com/socialviolation/mcpv/internal/mcpv"
+	"github.com/spf13/cobra"
+)
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <server>[@version]",
+	Short: "List the tools, resources and prompts a server exposes",
+	Long: `Connect to a server and list the tools it offers with their input parameters,
+its resources and resource templates, and its prompts. Installed servers are
+started over stdio as 'mcpv run' would; remote servers in mcpv.json are reached
+over HTTP.
+
+Use --output json to get the complete tool input schemas, for example to compare
+the tool surface of two versions.
+
+Examples:
+  mcpv inspect github             # Inspect the version mcpv.json uses
+  mcpv inspect github@1.2.0       # Inspect a specific installed version
+  mcpv inspect github -o json     # Print everything, including schemas, as JSON`,
+	Args: cobra.ExactArgs(1),
+	RunE: runInspect,
+}
+
+func runInspect(cmd *cobra.Command, args []string) error {
+	mgr, err := newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	configPath := cmd.Flag("config").Value.String()
+	if configPath == "" {
+		configPath = findConfigFile()
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	name, version := manager.ParseServerSpec(args[0])
+	inspection, err := mgr.InspectServer(name, version, configPath, timeout)
+	if err != nil {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("failed to inspect %s: %w", args[0], err)
+	}
+
+	if isStructuredOutput() {
+		return printResult(inspection)
+	}
+
+	printInspection(inspection)
+	return nil
+}
+
+// printInspection prints what a server exposes
+func printInspection(inspection *manager.Inspection) {
+	fmt.Fprintf(messageWriter, "%s %s (protocol %s)\n", inspection.ServerInfo.Name, inspection.ServerInfo.Version, inspection.ProtocolVersion)
+
+	fmt.Fprintf(messageWriter, "\nTools (%d):\n", len(inspection.Tools))
+	for _, tool := range inspection.Tools {
+		printInspectedItem(tool.Name, tool.Description)
+		for _, parameter := range toolParameters(tool) {
+			fmt.Fprintf(messageWriter, "      %s\n", parameter)
+		}
+	}
+
+	fmt.Fprintf(messageWriter, "\nResources (%d):\n", len(inspection.Resources))
+	for _, resource := range inspection.Resources {
+		printInspectedItem(resource.URI, resource.Name)
+	}
+
+	fmt.Fprintf(messageWriter, "\nResource templates (%d):\n", len(inspection.ResourceTemplates))
+	for _, template := range inspection.ResourceTemplates {
+		printInspectedItem(template.URITemplate, template.Name)
+	}
+
+	fmt.Fprintf(messageWriter, "\nPrompts (%d):\n", len(inspection.Prompts))
+	for _, prompt := range inspection.Prompts {
+		printInspectedItem(prompt.Name, prompt.Description)
+		for _, argument := range prompt.Arguments {
+			line := argument.Name
+			if argument.Required {
+				line += " (required)"
+			}
+			if argument.Description != "" {
+				line += " - " + argument.Description
+			}
+			fmt.Fprintf(messageWriter, "      %s\n", line)
+		}
+	}
+}
+
+// printInspectedItem prints one tool, resource or prompt with the first line
+// of its description
+func printInspectedItem(name, description string) {
+	description, _, _ = strings.Cut(strings.TrimSpace(description), "\n")
+	if description == "" {
+		fmt.Fprintf(messageWriter, "  %s\n", name)
+		return
+	}
+	fmt.Fprintf(messageWriter, "  %s - %s\n", name, description)
+}
+
+// toolParameters describes the top-level properties of a tool's input schema
+func toolParameters(tool mcp.Tool) []string {
+	properties, _ := tool.InputSchema["properties"].(map[string]interface{})
+	required := map[string]bool{}
+	if names, ok := tool.InputSchema["required"].([]interface{}); ok {
+		for _, name := range names {
+			if name, ok := name.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parameters []string
+	for _, name := range names {
+		property, _ := properties[name].(map[string]interface{})
+		details := []string{}
+		if kind, ok := property["type"].(string); ok {
+			details = append(details, kind)
+		}
+		if required[name] {
+			details = append(details, "required")
+		}
+
+		line := name
+		if len(details) > 0 {
+			line += " (" + strings.Join(details, ", ") + ")"
+		}
+		if description, ok := property["description"].(string); ok && description != "" {
+			line += " - " + description
+		}
+		parameters = append(parameters, line)
+	}
+	return parameters
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+	inspectCmd.Flags().StringP("config", "c", "", "Path to mcpv.json config file")
+	inspectCmd.Flags().Duration("timeout", manager.DefaultCheckTimeout, "How long the server may take to answer")
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	manager "github.com/socialviolation/mcpv/internal/mcpv"
@@ -25,17 +26,37 @@ Examples:
   mcpv install server@1.0.0                 # Install specific server version for all agents
   mcpv install server --agent claude        # Install latest version for Claude Desktop only
   mcpv install server --repo <url> --agent cursor  # Install from repo for Cursor only
+  mcpv install docs --url https://example.com/mcp  # Add a remote server
+  mcpv install gh --url <url> --header 'Authorization=Bearer ${GITHUB_TOKEN}'
+  mcpv install --dry-run                    # Show what would be installed and changed
+  mcpv install server@1.0.0 --check         # Roll back if the server fails its health check
 
 Use 'mcpv agents' to see supported agent types.`,
 	RunE: runInstall,
 }
 
 func runInstall(cmd *cobra.Command, args []string) error {
-	mgr, err := manager.NewManager()
+	mgr, err := newManagerForCommand(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to create manager: %w", err)
+		return err
 	}
 
+	if check, _ := cmd.Flags().GetBool("check"); check {
+		configPath := cmd.Flag("config").Value.String()
+		if configPath == "" {
+			configPath = "mcpv.json"
+		}
+		mgr.EnableInstallCheck(configPath, manager.DefaultCheckTimeout)
+	}
+
+	if err := installServers(mgr, cmd, args); err != nil {
+		return err
+	}
+
+	return printPlan(mgr)
+}
+
+func installServers(mgr *manager.Manager, cmd *cobra.Command, args []string) error {
 	// Get agent flag
 	agentFlag := cmd.Flag("agent").Value.String()
 	var targetAgent manager.AgentType
@@ -44,23 +65,19 @@ func runInstall(cmd *cobra.Command, args []string) error {
 	if agentFlag != "" {
 		agentSpecified = true
 
-		// Get available agent types from the registry
-		registry := mgr.GetAgentConfigManager().GetRegistry()
-		availableTypes := registry.ListAgentTypes()
-
-		// Check if the specified agent type is valid
-		found := false
-		for _, agentType := range availableTypes {
-			if agentType == agentFlag {
-				targetAgent = manager.AgentType(agentFlag)
-				found = true
-				break
+		// Agents defined by the project config are valid targets too
+		if configPath := cmd.Flag("config").Value.String(); configPath != "" {
+			mgr.UseProject(configPath)
+			if _, err := mgr.LoadProjectConfig(configPath); err != nil {
+				return err
 			}
 		}
 
-		if !found {
-			return fmt.Errorf("unsupported agent type: %s. Supported types: %v", agentFlag, availableTypes)
+		// Check if the specified agent type is defined
+		if !mgr.IsAgentType(agentFlag) {
+			return fmt.Errorf("unsupported agent type: %s. Supported types: %v", agentFlag, mgr.AgentTypes())
 		}
+		targetAgent = manager.AgentType(agentFlag)
 	}
 
 	// If no arguments provided, install from mcpv.json
@@ -79,8 +96,16 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		useGlobal, _ := cmd.Flags().GetBool("global")
 		useLocal := !useGlobal
 
+		previouslyInstalled, err := installedServerSet(mgr)
+		if err != nil {
+			return err
+		}
+
 		if agentSpecified {
-			return mgr.InstallFromConfigForAgentWithLocal(configPath, targetAgent, useLocal)
+			if err := mgr.InstallFromConfigForAgentWithLocal(configPath, targetAgent, useLocal); err != nil {
+				return err
+			}
+			return printConfigInstallResults(mgr, configPath, previouslyInstalled, string(targetAgent))
 		}
 
 		// Load config to check for default agent
@@ -95,11 +120,17 @@ func runInstall(cmd *cobra.Command, args []string) error {
 			if useGlobal {
 				configType = "global"
 			}
-			fmt.Printf("Using default agent: %s (%s config)\n", config.DefaultAgent, configType)
-			return mgr.InstallFromConfigForAgentWithLocal(configPath, manager.AgentType(config.DefaultAgent), useLocal)
+			fmt.Fprintf(messageWriter, "Using default agent: %s (%s config)\n", config.DefaultAgent, configType)
+			if err := mgr.InstallFromConfigForAgentWithLocal(configPath, manager.AgentType(config.DefaultAgent), useLocal); err != nil {
+				return err
+			}
+			return printConfigInstallResults(mgr, configPath, previouslyInstalled, config.DefaultAgent)
 		}
 
-		return mgr.InstallFromConfig(configPath)
+		if err := mgr.InstallFromConfig(configPath); err != nil {
+			return err
+		}
+		return printConfigInstallResults(mgr, configPath, previouslyInstalled, "")
 	}
 
 	// Install specific server
@@ -123,10 +154,15 @@ func runInstall(cmd *cobra.Command, args []string) error {
 			if useGlobal {
 				configType = "global"
 			}
-			fmt.Printf("Using default agent: %s (%s config)\n", config.DefaultAgent, configType)
+			fmt.Fprintf(messageWriter, "Using default agent: %s (%s config)\n", config.DefaultAgent, configType)
 		}
 	}
 
+	if url := cmd.Flag("url").Value.String(); url != "" {
+		return addRemoteServers(mgr, cmd, args, configPath, defaultAgentType, useLocal)
+	}
+
+	var results []serverResult
 	for _, arg := range args {
 		name, version := manager.ParseServerSpec(arg)
 
@@ -137,9 +173,9 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		if repoURL == "" {
 			// For now, we'll need the repository URL to be provided
 			// In a real implementation, you might have a registry of known servers
-			fmt.Printf("Installing specific servers requires repository URL.\n")
-			fmt.Printf("Usage: mcpv install %s@%s --repo <repository-url>\n", name, version)
-			fmt.Printf("Or add the server to mcpv.json first with: mcpv init\n")
+			fmt.Fprintf(messageWriter, "Installing specific servers requires repository URL.\n")
+			fmt.Fprintf(messageWriter, "Usage: mcpv install %s@%s --repo <repository-url>\n", name, version)
+			fmt.Fprintf(messageWriter, "Or add the server to mcpv.json first with: mcpv init\n")
 			return fmt.Errorf("repository URL required for server installation")
 		}
 
@@ -154,13 +190,22 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		if agentSpecified {
 			effectiveAgent = targetAgent
 			effectiveAgentSpecified = true
-			fmt.Printf("Installing %s@%s from %s for %s agent (%s config)...\n", name, version, repoURL, agentFlag, configType)
+			fmt.Fprintf(messageWriter, "Installing %s@%s from %s for %s agent (%s config)...\n", name, version, repoURL, agentFlag, configType)
 		} else if defaultAgentType != "" {
 			effectiveAgent = defaultAgentType
 			effectiveAgentSpecified = true
-			fmt.Printf("Installing %s@%s from %s for default agent %s (%s config)...\n", name, version, repoURL, defaultAgentType, configType)
+			fmt.Fprintf(messageWriter, "Installing %s@%s from %s for default agent %s (%s config)...\n", name, version, repoURL, defaultAgentType, configType)
 		} else {
-			fmt.Printf("Installing %s@%s from %s...\n", name, version, repoURL)
+			fmt.Fprintf(messageWriter, "Installing %s@%s from %s...\n", name, version, repoURL)
+		}
+
+		result := serverResult{
+			Name:       name,
+			Version:    version,
+			Repository: repoURL,
+			Path:       filepath.Join(mgr.GetDataDir(), name, version),
+			Status:     statusInstalled,
+			Agent:      string(effectiveAgent),
 		}
 
 		// Install the server and add to config
@@ -168,32 +213,150 @@ func runInstall(cmd *cobra.Command, args []string) error {
 			err := mgr.InstallServerAndAddToConfigForAgentWithLocal(name, version, repoURL, configPath, effectiveAgent, useLocal)
 			if err != nil {
 				if strings.Contains(err.Error(), "already installed") {
-					fmt.Printf("Server %s@%s is already installed\n", name, version)
+					fmt.Fprintf(messageWriter, "Server %s@%s is already installed\n", name, version)
+					result.Status = statusAlreadyInstalled
+					results = append(results, result)
 					continue
 				}
 				return fmt.Errorf("failed to install server %s@%s: %w", name, version, err)
 			}
-			fmt.Printf("Successfully installed %s@%s for %s agent (%s config) and added to %s\n", name, version, effectiveAgent, configType, configPath)
+			printDone(mgr, "Successfully installed %s@%s for %s agent (%s config) and added to %s\n", name, version, effectiveAgent, configType, configPath)
 		} else {
 			err := mgr.InstallServerAndAddToConfig(name, version, repoURL, configPath)
 			if err != nil {
 				if strings.Contains(err.Error(), "already installed") {
-					fmt.Printf("Server %s@%s is already installed\n", name, version)
+					fmt.Fprintf(messageWriter, "Server %s@%s is already installed\n", name, version)
+					result.Status = statusAlreadyInstalled
+					results = append(results, result)
 					continue
 				}
 				return fmt.Errorf("failed to install server %s@%s: %w", name, version, err)
 			}
-			fmt.Printf("Successfully installed %s@%s and added to %s\n", name, version, configPath)
+			printDone(mgr, "Successfully installed %s@%s and added to %s\n", name, version, configPath)
+		}
+		results = append(results, result)
+	}
+
+	if isStructuredOutput() {
+		return printResult(serversResult{Config: configPath, Servers: results, Plan: mgr.Plan()})
+	}
+
+	return nil
+}
+
+// addRemoteServers adds a remote server, reached at the --url flag, to mcpv.json
+// and configures it for the selected agents. Remote servers are not installed.
+func addRemoteServers(mgr *manager.Manager, cmd *cobra.Command, args []string, configPath string, defaultAgent manager.AgentType, useLocal bool) error {
+	if len(args) != 1 {
+		return fmt.Errorf("--url adds a single remote server; specify exactly one server name")
+	}
+	if cmd.Flag("repo").Value.String() != "" {
+		return fmt.Errorf("--url and --repo cannot be used together")
+	}
+
+	headerFlags, _ := cmd.Flags().GetStringArray("header")
+	headers := map[string]string{}
+	for _, header := range headerFlags {
+		name, value, ok := strings.Cut(header, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("invalid header %q: use Name=value", header)
 		}
+		headers[name] = value
 	}
 
+	server := &manager.MCPServer{
+		Name:      args[0],
+		URL:       cmd.Flag("url").Value.String(),
+		Transport: cmd.Flag("transport").Value.String(),
+	}
+	if len(headers) > 0 {
+		server.Headers = headers
+	}
+
+	agentType := defaultAgent
+	if agentFlag := cmd.Flag("agent").Value.String(); agentFlag != "" {
+		agentType = manager.AgentType(agentFlag)
+	}
+
+	fmt.Fprintf(messageWriter, "Adding remote server %s at %s...\n", server.Name, server.URL)
+	if err := mgr.AddRemoteServer(server, configPath, agentType, useLocal); err != nil {
+		return fmt.Errorf("failed to add remote server %s: %w", server.Name, err)
+	}
+	printDone(mgr, "Successfully added %s to %s\n", server.Name, configPath)
+
+	if isStructuredOutput() {
+		result := serverResult{Name: server.Name, URL: server.URL, Status: statusRemote, Agent: string(agentType)}
+		return printResult(serversResult{Config: configPath, Servers: []serverResult{result}, Plan: mgr.Plan()})
+	}
 	return nil
 }
 
+// installedServerSet returns the installed servers keyed by name@version
+func installedServerSet(mgr *manager.Manager) (map[string]bool, error) {
+	servers, err := mgr.ListInstalledServers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list installed servers: %w", err)
+	}
+
+	installed := make(map[string]bool)
+	for _, server := range servers {
+		installed[fmt.Sprintf("%s@%s", server.Name, server.Version)] = true
+	}
+	return installed, nil
+}
+
+// printConfigInstallResults reports the outcome of installing from mcpv.json in
+// the selected structured format. Servers that were present before the install
+// started are reported as already installed.
+func printConfigInstallResults(mgr *manager.Manager, configPath string, previouslyInstalled map[string]bool, agent string) error {
+	if !isStructuredOutput() {
+		return nil
+	}
+
+	config, err := mgr.LoadProjectConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	result := serversResult{Config: configPath, Servers: []serverResult{}, Plan: mgr.Plan()}
+	for _, server := range config.Servers {
+		if server.IsRemote() {
+			result.Servers = append(result.Servers, serverResult{Name: server.Name, URL: server.URL, Status: statusRemote, Agent: agent})
+			continue
+		}
+
+		version := server.Version
+		if version == "" {
+			version = "latest"
+		}
+
+		status := statusInstalled
+		if previouslyInstalled[fmt.Sprintf("%s@%s", server.Name, version)] {
+			status = statusAlreadyInstalled
+		}
+
+		result.Servers = append(result.Servers, serverResult{
+			Name:       server.Name,
+			Version:    version,
+			Repository: server.Repository,
+			Path:       filepath.Join(mgr.GetDataDir(), server.Name, version),
+			Status:     status,
+			Agent:      agent,
+		})
+	}
+
+	return printResult(result)
+}
+
 func init() {
 	rootCmd.AddCommand(installCmd)
 	installCmd.Flags().StringP("config", "c", "", "Path to mcpv.json config file")
 	installCmd.Flags().StringP("repo", "r", "", "Repository URL for the server")
+	installCmd.Flags().String("url", "", "URL of a remote server to add instead of installing one")
+	installCmd.Flags().String("transport", "", "Transport of the remote server: http (default) or sse")
+	installCmd.Flags().StringArray("header", nil, "Header sent to the remote server as Name=value; values may reference ${ENV_VAR} (repeatable)")
 	installCmd.Flags().StringP("agent", "a", "", "Install server for specific agent only. If not specified, uses default agent from config. Use 'mcpv agents' to see available types")
 	installCmd.Flags().BoolP("global", "g", false, "Install to global agent configuration instead of local (project-specific)")
+	installCmd.Flags().Bool("dry-run", false, "Print the planned actions and file changes without making them")
+	installCmd.Flags().Bool("check", false, "Check that each installed server answers the MCP handshake, and remove it again if it does not")
 }
@@ -23,7 +23,7 @@ Examples:
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	mgr, err := manager.NewManager()
+	mgr, err := newManager()
 	if err != nil {
 		return fmt.Errorf("failed to create manager: %w", err)
 	}
@@ -49,12 +49,16 @@ func runList(cmd *cobra.Command, args []string) error {
 
 	if _, err := os.Stat(configPath); err == nil {
 		// mcpv.json exists, show project servers by default
-		fmt.Printf("Project servers (from %s):\n", configPath)
+		if !isStructuredOutput() {
+			fmt.Fprintf(messageWriter, "Project servers (from %s):\n", configPath)
+		}
 		return listProjectServers(mgr, cmd)
 	}
 
 	// No mcpv.json found, show installed servers
-	fmt.Println("Installed servers:")
+	if !isStructuredOutput() {
+		fmt.Fprintln(messageWriter, "Installed servers:")
+	}
 	return listInstalledServers(mgr)
 }
 
@@ -64,12 +68,25 @@ func listInstalledServers(mgr *manager.Manager) error {
 		return fmt.Errorf("failed to list servers: %w", err)
 	}
 
+	if isStructuredOutput() {
+		result := serversResult{Source: "installed", Servers: []serverResult{}}
+		for _, server := range servers {
+			result.Servers = append(result.Servers, serverResult{
+				Name:    server.Name,
+				Version: server.Version,
+				Path:    server.InstallPath,
+				Status:  statusInstalled,
+			})
+		}
+		return printResult(result)
+	}
+
 	if len(servers) == 0 {
-		fmt.Println("No MCP servers installed")
+		fmt.Fprintln(messageWriter, "No MCP servers installed")
 		return nil
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	w := tabwriter.NewWriter(messageWriter, 0, 0, 2, ' ', 0)
 	fmt.Fprintln(w, "NAME\tVERSION\tPATH")
 	fmt.Fprintln(w, "----\t-------\t----")
 
@@ -91,8 +108,8 @@ func listProjectServers(mgr *manager.Manager, cmd *cobra.Command) error {
 		return fmt.Errorf("failed to load project config: %w", err)
 	}
 
-	if len(config.Servers) == 0 {
-		fmt.Println("No servers configured in project")
+	if len(config.Servers) == 0 && !isStructuredOutput() {
+		fmt.Fprintln(messageWriter, "No servers configured in project")
 		return nil
 	}
 
@@ -108,23 +125,30 @@ func listProjectServers(mgr *manager.Manager, cmd *cobra.Command) error {
 		installedMap[key] = true
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "NAME\tVERSION\tREPOSITORY\tSTATUS\tPATH")
-	fmt.Fprintln(w, "----\t-------\t----------\t------\t----")
+	result := serversResult{Source: "project", Config: configPath, Servers: []serverResult{}}
 
 	for _, server := range config.Servers {
+		if server.IsRemote() {
+			result.Servers = append(result.Servers, serverResult{
+				Name:   server.Name,
+				URL:    server.URL,
+				Status: statusRemote,
+			})
+			continue
+		}
+
 		version := server.Version
 		if version == "" {
 			version = "latest"
 		}
 
-		status := "Not Installed"
+		status := statusNotInstalled
 		installPath := ""
 
 		// Find the install path if installed
 		for _, installedServer := range installed {
 			if installedServer.Name == server.Name && installedServer.Version == version {
-				status = "Installed"
+				status = statusInstalled
 				installPath = installedServer.InstallPath
 				break
 			}
@@ -135,7 +159,34 @@ func listProjectServers(mgr *manager.Manager, cmd *cobra.Command) error {
 			installPath = filepath.Join(mgr.GetDataDir(), server.Name, version)
 		}
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", server.Name, version, server.Repository, status, installPath)
+		result.Servers = append(result.Servers, serverResult{
+			Name:       server.Name,
+			Version:    version,
+			Repository: server.Repository,
+			Path:       installPath,
+			Status:     status,
+		})
+	}
+
+	if isStructuredOutput() {
+		return printResult(result)
+	}
+
+	w := tabwriter.NewWriter(messageWriter, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tREPOSITORY\tSTATUS\tPATH")
+	fmt.Fprintln(w, "----\t-------\t----------\t------\t----")
+
+	for _, server := range result.Servers {
+		if server.Status == statusRemote {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", server.Name, "-", server.URL, "Remote", "-")
+			continue
+		}
+
+		status := "Not Installed"
+		if server.Status == statusInstalled {
+			status = "Installed"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", server.Name, server.Version, server.Repository, status, server.Path)
 	}
 
 	return w.Flush()
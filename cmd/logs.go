@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// logFollowInterval is how often a followed log is checked for new output
+const logFollowInterval = 500 * time.Millisecond
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:   "logs <server>",
+	Short: "Show the log of a server run by mcpv serve or mcpv bridge",
+	Long: `Show the log of a local server run by 'mcpv serve' or 'mcpv bridge': what the
+server wrote to standard error and when mcpv started, restarted or stopped it,
+each line with its time. Logs are kept in the mcpv state directory and rotated
+once they grow past 10MB.
+
+Examples:
+  mcpv logs github                # Show the whole log
+  mcpv logs github -n 50          # Show the last 50 lines
+  mcpv logs github -f             # Keep showing new lines as they are written`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLogs,
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	mgr, err := newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	follow, _ := cmd.Flags().GetBool("follow")
+	lines, _ := cmd.Flags().GetInt("lines")
+
+	path := mgr.LogPath(args[0])
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no log for %s; servers are logged when mcpv serve or mcpv bridge runs them", args[0])
+		}
+		return fmt.Errorf("failed to read log: %w", err)
+	}
+
+	cmd.SilenceUsage = true
+	os.Stdout.Write(lastLines(data, lines))
+	if !follow {
+		return nil
+	}
+	return followLog(path, int64(len(data)))
+}
+
+// lastLines returns the last n lines of data, or all of it when n is not
+// positive
+func lastLines(data []byte, n int) []byte {
+	if n <= 0 {
+		return data
+	}
+	end := len(data)
+	if end > 0 && data[end-1] == '\n' {
+		end--
+	}
+	start := end
+	for ; n > 0 && start > 0; n-- {
+		start = bytes.LastIndexByte(data[:start], '\n')
+		if start < 0 {
+			return data
+		}
+	}
+	if start == end {
+		return data[end:]
+	}
+	return data[start+1:]
+}
+
+// followLog writes what is appended to a log from offset on until mcpv is
+// interrupted, starting over when the log is rotated
+func followLog(path string, offset int64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open log: %w", err)
+	}
+	defer func() { file.Close() }()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read log: %w", err)
+	}
+
+	for {
+		if _, err := io.Copy(os.Stdout, file); err != nil {
+			return fmt.Errorf("failed to read log: %w", err)
+		}
+		time.Sleep(logFollowInterval)
+
+		current, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		opened, err := file.Stat()
+		if err != nil || !os.SameFile(opened, current) {
+			if rotated, err := os.Open(path); err == nil {
+				file.Close()
+				file = rotated
+			}
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+	logsCmd.Flags().BoolP("follow", "f", false, "Keep showing new lines as they are written")
+	logsCmd.Flags().IntP("lines", "n", 0, "Show only the last n lines")
+}
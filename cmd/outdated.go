@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	manager "github.com/socialviolation/mcpv/internal/mcpv"
+	"github.com/spf13/cobra"
+)
+
+// outdatedCmd represents the outdated command
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "Show servers with newer versions available",
+	Long: `Compare servers against the version tags published by their repositories.
+
+For each server in mcpv.json (or each installed server when there is no mcpv.json),
+shows the current installed version, the wanted version (the highest tag matching
+the version constraint in mcpv.json) and the latest tag. Exits with a non-zero
+status when any server is behind or its tags cannot be fetched, so it can be
+used to alert in CI.
+
+Examples:
+  mcpv outdated                   # Check servers from mcpv.json
+  mcpv outdated --output json     # Report as JSON`,
+	RunE: runOutdated,
+}
+
+// outdatedResult lists the outcome of an outdated check
+type outdatedResult struct {
+	Config  string                   `json:"config,omitempty" yaml:"config,omitempty"`
+	Servers []manager.OutdatedServer `json:"servers" yaml:"servers"`
+}
+
+func runOutdated(cmd *cobra.Command, args []string) error {
+	mgr, err := newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	configPath := cmd.Flag("config").Value.String()
+	if configPath == "" {
+		configPath = findConfigFile()
+	}
+
+	report, err := mgr.CheckOutdated(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to check for outdated servers: %w", err)
+	}
+
+	if isStructuredOutput() {
+		if err := printResult(outdatedResult{Config: configPath, Servers: report}); err != nil {
+			return err
+		}
+	} else if len(report) == 0 {
+		fmt.Fprintln(messageWriter, "No servers to check")
+	} else {
+		w := tabwriter.NewWriter(messageWriter, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tCURRENT\tWANTED\tLATEST\tREPOSITORY")
+		fmt.Fprintln(w, "----\t-------\t------\t------\t----------")
+
+		for _, server := range report {
+			current := server.Current
+			if current == "" {
+				current = "missing"
+			}
+			if server.Error != "" {
+				fmt.Fprintf(w, "%s\t%s\t-\t-\t%s (%s)\n", server.Name, current, server.Repository, server.Error)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", server.Name, current, server.Wanted, server.Latest, server.Repository)
+		}
+
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	outdated, failed := 0, 0
+	for _, server := range report {
+		if server.Error != "" {
+			failed++
+		} else if server.Outdated {
+			outdated++
+		}
+	}
+
+	switch {
+	case failed > 0 && outdated > 0:
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d server(s) outdated, %d server(s) could not be checked", outdated, failed)
+	case failed > 0:
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d server(s) could not be checked", failed)
+	case outdated > 0:
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d server(s) outdated", outdated)
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(outdatedCmd)
+	outdatedCmd.Flags().StringP("config", "c", "", "Path to mcpv.json config file")
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// newTaggedRepo creates a repository with a commit tagged for each of the
+// given tags and returns its path
+func newTaggedRepo(t *testing.T, tags ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	for _, tag := range tags {
+		writeFile(t, filepath.Join(dir, "VERSION"), tag)
+		if _, err := worktree.Add("VERSION"); err != nil {
+			t.Fatal(err)
+		}
+		hash, err := worktree.Commit("release "+tag, &git.CommitOptions{Author: signature})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.CreateTag(tag, hash, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestOutdatedExitStatus(t *testing.T) {
+	repository := newTaggedRepo(t, "v1.0.0", "v1.1.0")
+
+	tests := []struct {
+		name    string
+		config  string
+		lock    string
+		wantErr string
+	}{
+		{
+			name:   "up to date",
+			config: `{"servers": [{"name": "srv", "version": "^1.0.0", "repository": "` + repository + `"}]}`,
+			lock:   `{"servers": [{"name": "srv", "version": "v1.1.0", "repository": "` + repository + `"}]}`,
+		},
+		{
+			name:    "locked behind",
+			config:  `{"servers": [{"name": "srv", "version": "^1.0.0", "repository": "` + repository + `"}]}`,
+			lock:    `{"servers": [{"name": "srv", "version": "v1.0.0", "repository": "` + repository + `"}]}`,
+			wantErr: "1 server(s) outdated",
+		},
+		{
+			name:    "tags unavailable",
+			config:  `{"servers": [{"name": "srv", "version": "v1.0.0", "repository": "` + filepath.Join(repository, "missing") + `"}]}`,
+			lock:    `{"servers": [{"name": "srv", "version": "v1.0.0"}]}`,
+			wantErr: "1 server(s) could not be checked",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, project := newTestProject(t)
+			writeFile(t, filepath.Join(project, "mcpv.json"), tt.config)
+			writeFile(t, filepath.Join(project, "mcpv.lock"), tt.lock)
+
+			out, err := executeCommandErr("outdated", "--config", filepath.Join(project, "mcpv.json"))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected success, got %v\n%s", err, out)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v\n%s", tt.wantErr, err, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	manager "github.com/socialviolation/mcpv/internal/mcpv"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by the global --output flag
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+var (
+	// outputFormat holds the value of the global --output flag
+	outputFormat = outputText
+
+	// resultWriter receives structured results
+	resultWriter io.Writer = os.Stdout
+
+	// messageWriter receives progress messages and text output. It is stderr
+	// while a structured format is active, so that stdout only ever carries
+	// the result document.
+	messageWriter io.Writer = os.Stdout
+)
+
+// serverResult is the structured representation of a server in command output
+type serverResult struct {
+	Name       string `json:"name" yaml:"name"`
+	Version    string `json:"version" yaml:"version"`
+	Previous   string `json:"previous,omitempty" yaml:"previous,omitempty"`
+	Repository string `json:"repository,omitempty" yaml:"repository,omitempty"`
+	URL        string `json:"url,omitempty" yaml:"url,omitempty"`
+	Path       string `json:"path,omitempty" yaml:"path,omitempty"`
+	Status     string `json:"status" yaml:"status"`
+	Agent      string `json:"agent,omitempty" yaml:"agent,omitempty"`
+	Error      string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// serversResult wraps a list of servers together with where they came from
+type serversResult struct {
+	Source  string         `json:"source,omitempty" yaml:"source,omitempty"`
+	Config  string         `json:"config,omitempty" yaml:"config,omitempty"`
+	Servers []serverResult `json:"servers" yaml:"servers"`
+	Plan    *manager.Plan  `json:"plan,omitempty" yaml:"plan,omitempty"`
+}
+
+// agentsResult lists the detected agents and their configuration paths
+type agentsResult struct {
+	Agents []manager.AgentConfiguration `json:"agents" yaml:"agents"`
+}
+
+// backupsResult lists agent configuration backups
+type backupsResult struct {
+	Backups []*manager.Backup `json:"backups" yaml:"backups"`
+}
+
+// processesResult lists the server processes run by mcpv serve and mcpv bridge
+type processesResult struct {
+	Processes []manager.ProcessStatus `json:"processes" yaml:"processes"`
+}
+
+// errorResult is written to stderr when a command fails in a structured format
+type errorResult struct {
+	Error string `json:"error" yaml:"error"`
+}
+
+// Server statuses reported in structured output
+const (
+	statusInstalled        = "installed"
+	statusNotInstalled     = "not_installed"
+	statusRemote           = "remote"
+	statusAlreadyInstalled = "already_installed"
+	statusUpdated          = "updated"
+	statusUpToDate         = "up_to_date"
+	statusRemoved          = "removed"
+	statusUnregistered     = "unregistered"
+	statusSkipped          = "skipped"
+	statusFailed           = "failed"
+)
+
+// setupOutput validates the --output flag and sends progress messages to
+// stderr for structured output
+func setupOutput(cmd *cobra.Command, args []string) error {
+	switch outputFormat {
+	case outputText:
+		return nil
+	case outputJSON, outputYAML:
+	default:
+		return fmt.Errorf("unsupported output format: %s. Supported formats: %s, %s, %s", outputFormat, outputText, outputJSON, outputYAML)
+	}
+
+	messageWriter = os.Stderr
+	return nil
+}
+
+// newManager creates a manager writing its progress messages to messageWriter
+func newManager() (*manager.Manager, error) {
+	mgr, err := manager.NewManager()
+	if err != nil {
+		return nil, err
+	}
+	mgr.SetOutput(messageWriter)
+	return mgr, nil
+}
+
+// isStructuredOutput reports whether results should be emitted as JSON or YAML
+func isStructuredOutput() bool {
+	return outputFormat == outputJSON || outputFormat == outputYAML
+}
+
+// printResult writes a command result in the selected structured format
+func printResult(v interface{}) error {
+	return encodeResult(resultWriter, v)
+}
+
+// printError reports a failed command on stderr: in the selected structured
+// format, or as text followed by the command's usage unless the command
+// silenced it
+func printError(cmd *cobra.Command, err error) {
+	if isStructuredOutput() {
+		_ = encodeResult(os.Stderr, errorResult{Error: err.Error()})
+		return
+	}
+	cmd.PrintErrln(cmd.ErrPrefix(), err.Error())
+	if !cmd.SilenceUsage {
+		cmd.PrintErrln(cmd.UsageString())
+	}
+}
+
+func encodeResult(w io.Writer, v interface{}) error {
+	switch outputFormat {
+	case outputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+		return enc.Close()
+	default:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+		return nil
+	}
+}
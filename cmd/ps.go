@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// psCmd represents the ps command
+var psCmd = &cobra.Command{
+	Use:   "ps",
+	Short: "List the server processes run by mcpv serve and mcpv bridge",
+	Long: `List the local server processes that running 'mcpv serve' and 'mcpv bridge'
+instances have started, with their process ID, uptime, number of restarts and
+how the last process exited. Servers that were not restarted after exiting are
+listed as stopped.
+
+Examples:
+  mcpv ps                         # List running servers
+  mcpv ps -o json                 # List them as JSON`,
+	Args: cobra.NoArgs,
+	RunE: runPs,
+}
+
+func runPs(cmd *cobra.Command, args []string) error {
+	mgr, err := newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	processes, err := mgr.Processes()
+	if err != nil {
+		return err
+	}
+
+	if isStructuredOutput() {
+		return printResult(processesResult{Processes: processes})
+	}
+
+	if len(processes) == 0 {
+		fmt.Fprintln(messageWriter, "No servers are running under mcpv serve or mcpv bridge")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(messageWriter, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tSTATE\tPID\tUPTIME\tRESTARTS\tLAST EXIT\tOWNER")
+	fmt.Fprintln(w, "----\t-------\t-----\t---\t------\t--------\t---------\t-----")
+
+	for _, process := range processes {
+		pid, uptime := "-", "-"
+		if process.PID != 0 {
+			pid = fmt.Sprint(process.PID)
+			uptime = time.Since(process.Started).Round(time.Second).String()
+		}
+		lastExit := process.LastExit
+		if lastExit == "" {
+			lastExit = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s (pid %d)\n",
+			process.Server, process.Version, process.State, pid, uptime, process.Restarts, lastExit, process.Owner, process.OwnerPID)
+	}
+
+	return w.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(psCmd)
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	manager "github.com/socialviolation/mcpv/internal/mcpv"
 	"github.com/spf13/cobra"
@@ -12,57 +13,125 @@ var removeCmd = &cobra.Command{
 	Use:     "remove <server@version>",
 	Aliases: []string{"rm"},
 	Short:   "Remove installed MCP servers",
-	Long:    `Remove installed MCP servers by specifying the server name and version.`,
-	Args:    cobra.MinimumNArgs(1),
-	RunE:    runRemove,
+	Long: `Remove installed MCP servers by specifying the server name and version.
+
+The server is removed from mcpv.json and from the agent given with --agent,
+otherwise the default agent from mcpv.json, otherwise all detected agents. Use
+--keep-files to unregister the server without deleting its installed files.
+
+Examples:
+  mcpv remove server@1.0.0              # Remove a specific version
+  mcpv remove server                    # Remove all versions
+  mcpv remove server --agent cursor     # Remove from Cursor's configuration only
+  mcpv remove server --global           # Remove from global agent configurations
+  mcpv remove server --keep-files       # Unregister but keep the installed files
+  mcpv remove server --dry-run          # Show what would be removed and changed`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runRemove,
 }
 
 func runRemove(cmd *cobra.Command, args []string) error {
-	mgr, err := manager.NewManager()
+	mgr, err := newManagerForCommand(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to create manager: %w", err)
+		return err
 	}
 
+	if err := removeServers(mgr, cmd, args); err != nil {
+		return err
+	}
+
+	return printPlan(mgr)
+}
+
+func removeServers(mgr *manager.Manager, cmd *cobra.Command, args []string) error {
 	configPath := cmd.Flag("config").Value.String()
 	if configPath == "" {
 		configPath = "mcpv.json"
 	}
 
+	agentTypes, err := targetAgents(mgr, cmd, configPath)
+	if err != nil {
+		return err
+	}
+	useGlobal, _ := cmd.Flags().GetBool("global")
+	keepFiles, _ := cmd.Flags().GetBool("keep-files")
+
+	result := serversResult{Config: configPath, Servers: []serverResult{}, Plan: mgr.Plan()}
 	for _, arg := range args {
 		name, version := manager.ParseServerSpec(arg)
 
 		if version == "" {
 			// Remove all versions of the server
-			if err := removeAllVersions(mgr, name, configPath); err != nil {
+			removed, err := removeAllVersions(mgr, name, configPath, keepFiles)
+			if err != nil {
 				return err
 			}
+			result.Servers = append(result.Servers, removed...)
 		} else {
 			// Remove specific version
-			fmt.Printf("Removing %s@%s...\n", name, version)
-			if err := mgr.RemoveServer(name, version); err != nil {
-				// Continue even if server removal fails - we still want to remove from config
-				fmt.Printf("Warning: Failed to remove installed server %s@%s: %v\n", name, version, err)
-			} else {
-				fmt.Printf("Successfully removed %s@%s\n", name, version)
+			fmt.Fprintf(messageWriter, "Removing %s@%s...\n", name, version)
+			result.Servers = append(result.Servers, removeInstalledVersion(mgr, name, version, keepFiles))
+
+			declared, err := declaredVersions(mgr, name, configPath)
+			if err != nil {
+				return err
+			}
+			inUse, ok, err := mgr.DeclaredVersionInUse(configPath, name, version)
+			if err != nil {
+				return err
+			}
+			if len(declared) > 0 && !ok {
+				// mcpv.json declares another version, which agents keep using
+				fmt.Fprintf(messageWriter, "Keeping %s in agent configurations: %s declares %s@%s\n", name, configPath, name, strings.Join(declared, ", "))
+				continue
 			}
 
-			// Always remove from mcpv.json config regardless of installation status
-			if err := removeFromConfig(mgr, name, version, configPath); err != nil {
-				fmt.Printf("Warning: Failed to remove %s@%s from %s: %v\n", name, version, configPath, err)
-			} else {
-				fmt.Printf("✓ Removed %s@%s from %s\n", name, version, configPath)
+			if ok {
+				if err := removeFromConfig(mgr, name, inUse, configPath); err != nil {
+					fmt.Fprintf(messageWriter, "Warning: Failed to remove %s@%s from %s: %v\n", name, inUse, configPath, err)
+				} else {
+					printDone(mgr, "✓ Removed %s@%s from %s\n", name, inUse, configPath)
+				}
 			}
 		}
+
+		if err := mgr.RemoveServerFromAgents(name, agentTypes, !useGlobal); err != nil {
+			fmt.Fprintf(messageWriter, "Warning: Failed to remove %s from agent configurations: %v\n", name, err)
+		}
+	}
+
+	if isStructuredOutput() {
+		return printResult(result)
 	}
 
 	return nil
 }
 
-func removeAllVersions(mgr *manager.Manager, serverName string, configPath string) error {
+// removeInstalledVersion removes an installed server version and reports the
+// outcome. With keepFiles set, the installed files are left in place.
+func removeInstalledVersion(mgr *manager.Manager, name, version string, keepFiles bool) serverResult {
+	if keepFiles {
+		fmt.Fprintf(messageWriter, "Keeping installed files of %s@%s\n", name, version)
+		return serverResult{Name: name, Version: version, Status: statusUnregistered}
+	}
+
+	result := serverResult{Name: name, Version: version, Status: statusRemoved}
+	if err := mgr.RemoveServerFiles(name, version); err != nil {
+		// Continue even if server removal fails - we still want to remove from config
+		fmt.Fprintf(messageWriter, "Warning: Failed to remove installed server %s@%s: %v\n", name, version, err)
+		result.Status = statusNotInstalled
+		result.Error = err.Error()
+	} else {
+		printDone(mgr, "Successfully removed %s@%s\n", name, version)
+	}
+	return result
+}
+
+func removeAllVersions(mgr *manager.Manager, serverName string, configPath string, keepFiles bool) ([]serverResult, error) {
 	// Get all installed servers
 	servers, err := mgr.ListInstalledServers()
 	if err != nil {
-		return fmt.Errorf("failed to list installed servers: %w", err)
+		return nil, fmt.Errorf("failed to list installed servers: %w", err)
 	}
 
 	// Find all versions of the specified server
@@ -74,32 +143,45 @@ func removeAllVersions(mgr *manager.Manager, serverName string, configPath strin
 	}
 
 	if len(versionsToRemove) == 0 {
-		fmt.Printf("Warning: server %s is not installed, but will still remove from config\n", serverName)
+		fmt.Fprintf(messageWriter, "Warning: server %s is not installed, but will still remove from config\n", serverName)
 	}
 
 	// Remove each version
+	var results []serverResult
 	for _, version := range versionsToRemove {
-		fmt.Printf("Removing %s@%s...\n", serverName, version)
-		if err := mgr.RemoveServer(serverName, version); err != nil {
-			fmt.Printf("Warning: Failed to remove installed server %s@%s: %v\n", serverName, version, err)
-		} else {
-			fmt.Printf("Successfully removed %s@%s\n", serverName, version)
-		}
+		fmt.Fprintf(messageWriter, "Removing %s@%s...\n", serverName, version)
+		results = append(results, removeInstalledVersion(mgr, serverName, version, keepFiles))
 
 		// Always remove from mcpv.json config regardless of installation status
 		if err := removeFromConfig(mgr, serverName, version, configPath); err != nil {
-			fmt.Printf("Warning: Failed to remove %s@%s from %s: %v\n", serverName, version, configPath, err)
+			fmt.Fprintf(messageWriter, "Warning: Failed to remove %s@%s from %s: %v\n", serverName, version, configPath, err)
 		} else {
-			fmt.Printf("✓ Removed %s@%s from %s\n", serverName, version, configPath)
+			printDone(mgr, "✓ Removed %s@%s from %s\n", serverName, version, configPath)
 		}
 	}
 
 	// Also try to remove any entries from config that might not have been installed
 	if err := removeAllVersionsFromConfig(mgr, serverName, configPath); err != nil {
-		fmt.Printf("Warning: Failed to remove all versions of %s from %s: %v\n", serverName, configPath, err)
+		fmt.Fprintf(messageWriter, "Warning: Failed to remove all versions of %s from %s: %v\n", serverName, configPath, err)
 	}
 
-	return nil
+	return results, nil
+}
+
+// declaredVersions returns the versions of a server declared in mcpv.json
+func declaredVersions(mgr *manager.Manager, serverName, configPath string) ([]string, error) {
+	config, err := mgr.LoadProjectConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var versions []string
+	for _, server := range config.Servers {
+		if server.Name == serverName {
+			versions = append(versions, server.Version)
+		}
+	}
+	return versions, nil
 }
 
 // removeFromConfig removes a specific server version from mcpv.json
@@ -125,7 +207,10 @@ func removeFromConfig(mgr *manager.Manager, serverName, version, configPath stri
 	}
 
 	config.Servers = updatedServers
-	return mgr.SaveProjectConfig(config, configPath)
+	if err := mgr.SaveProjectConfig(config, configPath); err != nil {
+		return err
+	}
+	return mgr.UnlockServer(configPath, serverName)
 }
 
 // removeAllVersionsFromConfig removes all versions of a server from mcpv.json
@@ -151,7 +236,10 @@ func removeAllVersionsFromConfig(mgr *manager.Manager, serverName, configPath st
 		if err := mgr.SaveProjectConfig(config, configPath); err != nil {
 			return err
 		}
-		fmt.Printf("✓ Removed %d version(s) of %s from %s\n", removedCount, serverName, configPath)
+		if err := mgr.UnlockServer(configPath, serverName); err != nil {
+			return err
+		}
+		printDone(mgr, "✓ Removed %d version(s) of %s from %s\n", removedCount, serverName, configPath)
 	}
 
 	return nil
@@ -160,6 +248,8 @@ func removeAllVersionsFromConfig(mgr *manager.Manager, serverName, configPath st
 func init() {
 	rootCmd.AddCommand(removeCmd)
 	removeCmd.Flags().StringP("config", "c", "", "Path to mcpv.json config file")
-	removeCmd.Flags().StringP("agent", "a", "", "Remove server from specific agent only. If not specified, uses default agent from config")
+	removeCmd.Flags().StringP("agent", "a", "", "Remove server from specific agent only. If not specified, uses default agent from config or all detected agents")
 	removeCmd.Flags().BoolP("global", "g", false, "Remove from global agent configuration instead of local (project-specific)")
+	removeCmd.Flags().Bool("keep-files", false, "Unregister the server from mcpv.json and agents without deleting its installed files")
+	removeCmd.Flags().Bool("dry-run", false, "Print the planned actions and file changes without making them")
 }
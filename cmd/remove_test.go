@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// newTestProject points mcpv's data, config and state at temporary
+// directories and works in an empty project directory it returns
+func newTestProject(t *testing.T) (dataDir, project string) {
+	t.Helper()
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	data := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", data)
+
+	project = t.TempDir()
+	t.Chdir(project)
+	return filepath.Join(data, "mcpv"), project
+}
+
+// executeCommand runs mcpv with the given arguments and returns its messages
+func executeCommand(t *testing.T, args ...string) string {
+	t.Helper()
+
+	out, err := executeCommandErr(args...)
+	if err != nil {
+		t.Fatalf("mcpv %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return out
+}
+
+// executeCommandErr runs mcpv with the given arguments and returns its
+// messages and the error it exits with
+func executeCommandErr(args ...string) (string, error) {
+	var out bytes.Buffer
+	previous := messageWriter
+	messageWriter = &out
+	defer func() { messageWriter = previous }()
+
+	defer resetFlags(rootCmd)
+
+	rootCmd.SetArgs(args)
+	err := rootCmd.Execute()
+	return out.String(), err
+}
+
+// resetFlags returns the flags of a command and its subcommands to their
+// defaults, so that one run does not leak flags into the next
+func resetFlags(cmd *cobra.Command) {
+	reset := func(flag *pflag.Flag) {
+		if flag.Changed {
+			if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+				sliceValue.Replace(nil)
+			} else {
+				flag.Value.Set(flag.DefValue)
+			}
+			flag.Changed = false
+		}
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
+// writeFile writes a file, creating its directory
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// cursorServers returns the server entries of a project's Cursor configuration
+func cursorServers(t *testing.T, project string) map[string]interface{} {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(project, ".cursor", "mcp.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config struct {
+		MCPServers map[string]interface{} `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	return config.MCPServers
+}
+
+func TestRemoveOldVersionKeepsAgentEntry(t *testing.T) {
+	dataDir, project := newTestProject(t)
+	writeFile(t, filepath.Join(project, "mcpv.json"), `{"servers": [{"name": "fake", "version": "v2.0.0", "repository": "https://example.com/fake.git", "command": "node"}]}`)
+	writeFile(t, filepath.Join(project, ".cursor", "mcp.json"), `{"mcpServers": {"fake": {"command": "node"}}}`)
+	for _, version := range []string{"v1.0.0", "v2.0.0"} {
+		if err := os.MkdirAll(filepath.Join(dataDir, "fake", version), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := executeCommand(t, "remove", "fake@v1.0.0", "--agent", "cursor")
+	if strings.Contains(out, "not found in config") {
+		t.Errorf("removing a version mcpv.json does not declare reported it missing from the config:\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "fake", "v1.0.0")); !os.IsNotExist(err) {
+		t.Errorf("fake@v1.0.0 was not removed: %v", err)
+	}
+	if _, ok := cursorServers(t, project)["fake"]; !ok {
+		t.Errorf("removing fake@v1.0.0 removed the entry of the declared fake@v2.0.0 from cursor:\n%s", out)
+	}
+
+	// Removing the declared version unregisters the server
+	executeCommand(t, "remove", "fake@v2.0.0", "--agent", "cursor")
+	if _, ok := cursorServers(t, project)["fake"]; ok {
+		t.Error("removing the declared fake@v2.0.0 kept its entry in cursor")
+	}
+	data, err := os.ReadFile(filepath.Join(project, "mcpv.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "fake") {
+		t.Errorf("fake@v2.0.0 is still declared in mcpv.json:\n%s", data)
+	}
+}
+
+func TestRemoveDryRunReportsNothingDone(t *testing.T) {
+	dataDir, project := newTestProject(t)
+	writeFile(t, filepath.Join(project, "mcpv.json"), `{"servers": [{"name": "fake", "version": "v1.0.0", "repository": "https://example.com/fake.git", "command": "node"}]}`)
+	writeFile(t, filepath.Join(project, ".cursor", "mcp.json"), `{"mcpServers": {"fake": {"command": "node"}}}`)
+	if err := os.MkdirAll(filepath.Join(dataDir, "fake", "v1.0.0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	out := executeCommand(t, "remove", "fake@v1.0.0", "--agent", "cursor", "--dry-run")
+	for _, done := range []string{"Successfully", "✓"} {
+		if strings.Contains(out, done) {
+			t.Errorf("a dry run reported a change as done (%q):\n%s", done, out)
+		}
+	}
+	if !strings.Contains(out, "Planned") {
+		t.Errorf("a dry run did not print its plan:\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "fake", "v1.0.0")); err != nil {
+		t.Errorf("a dry run removed fake@v1.0.0: %v", err)
+	}
+	if _, ok := cursorServers(t, project)["fake"]; !ok {
+		t.Error("a dry run removed fake from cursor")
+	}
+}
+
+func TestRemoveVersionAllowedByRange(t *testing.T) {
+	dataDir, project := newTestProject(t)
+	writeFile(t, filepath.Join(project, "mcpv.json"), `{"servers": [{"name": "fake", "version": "^1.0.0", "repository": "https://example.com/fake.git", "command": "node"}]}`)
+	writeFile(t, filepath.Join(project, "mcpv.lock"), `{"servers": [{"name": "fake", "version": "v1.2.0", "repository": "https://example.com/fake.git"}]}`)
+	writeFile(t, filepath.Join(project, ".cursor", "mcp.json"), `{"mcpServers": {"fake": {"command": "node"}}}`)
+	for _, version := range []string{"v1.1.0", "v1.2.0"} {
+		if err := os.MkdirAll(filepath.Join(dataDir, "fake", version), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// v1.1.0 matches the range, but the project is locked to v1.2.0
+	out := executeCommand(t, "remove", "fake@v1.1.0", "--agent", "cursor")
+	if _, ok := cursorServers(t, project)["fake"]; !ok {
+		t.Errorf("removing fake@v1.1.0 removed the entry of the locked fake@v1.2.0 from cursor:\n%s", out)
+	}
+
+	// v1.2.0 is the version in use, so the server is unregistered
+	out = executeCommand(t, "remove", "fake@v1.2.0", "--agent", "cursor")
+	if _, err := os.Stat(filepath.Join(dataDir, "fake", "v1.2.0")); !os.IsNotExist(err) {
+		t.Errorf("fake@v1.2.0 was not removed: %v", err)
+	}
+	if _, ok := cursorServers(t, project)["fake"]; ok {
+		t.Errorf("removing the locked fake@v1.2.0 kept its entry in cursor:\n%s", out)
+	}
+	data, err := os.ReadFile(filepath.Join(project, "mcpv.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "fake") {
+		t.Errorf("fake is still declared in mcpv.json after removing the version in use:\n%s", data)
+	}
+}
+
+func TestRemoveAgentAndKeepFiles(t *testing.T) {
+	dataDir, project := newTestProject(t)
+	writeFile(t, filepath.Join(project, "mcpv.json"), `{"servers": [{"name": "fake", "version": "v1.0.0", "repository": "https://example.com/fake.git", "command": "node"}]}`)
+	writeFile(t, filepath.Join(project, ".cursor", "mcp.json"), `{"mcpServers": {"fake": {"command": "node"}}}`)
+	writeFile(t, filepath.Join(project, ".vscode", "mcp.json"), `{"servers": {"fake": {"command": "node"}}}`)
+	if err := os.MkdirAll(filepath.Join(dataDir, "fake", "v1.0.0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	executeCommand(t, "remove", "fake@v1.0.0", "--agent", "cursor", "--keep-files")
+	if _, ok := cursorServers(t, project)["fake"]; ok {
+		t.Error("fake is still in cursor")
+	}
+	data, err := os.ReadFile(filepath.Join(project, ".vscode", "mcp.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "fake") {
+		t.Errorf("removing fake from cursor removed it from vscode:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "fake", "v1.0.0")); err != nil {
+		t.Errorf("--keep-files removed the installed files of fake@v1.0.0: %v", err)
+	}
+}
+
+func TestRemoveGlobal(t *testing.T) {
+	_, project := newTestProject(t)
+	home := os.Getenv("HOME")
+	writeFile(t, filepath.Join(project, "mcpv.json"), `{"servers": []}`)
+	writeFile(t, filepath.Join(project, ".cursor", "mcp.json"), `{"mcpServers": {"fake": {"command": "node"}}}`)
+	writeFile(t, filepath.Join(home, ".cursor", "mcp.json"), `{"mcpServers": {"fake": {"command": "node"}}}`)
+
+	executeCommand(t, "remove", "fake@v1.0.0", "--agent", "cursor", "--global", "--keep-files")
+	data, err := os.ReadFile(filepath.Join(home, ".cursor", "mcp.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "fake") {
+		t.Errorf("--global left fake in the user-level cursor configuration:\n%s", data)
+	}
+	if _, ok := cursorServers(t, project)["fake"]; !ok {
+		t.Error("--global removed fake from the project's cursor configuration")
+	}
+}
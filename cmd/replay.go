@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+
+	manager "github.com/socialviolation/mcpv/internal/mcpv"
+	"github.com/spf13/cobra"
+)
+
+// replayCmd represents the replay command
+var replayCmd = &cobra.Command{
+	Use:   "replay <server>[@version] <recording>",
+	Short: "Replay a recorded session against a server and compare responses",
+	Long: `Start an installed server as 'mcpv run' would and send it the client's messages
+from a recording made with 'mcpv run --record', waiting for the response to each
+request and comparing it with the recorded response. Requests the server makes
+of the client are answered with the client's recorded responses.
+
+Fields that change from run to run, such as timestamps, can be left out of the
+comparison with --ignore, a dot-separated path into the response in which *
+matches any one key or array index and ** any number of them.
+
+Exits with an error if any response differs, so recordings can serve as
+regression tests, for example when upgrading a server.
+
+Examples:
+  mcpv replay github session.jsonl                 # Replay against the version from mcpv.json
+  mcpv replay github@1.3.0 session.jsonl           # Replay against another version
+  mcpv replay github session.jsonl --ignore 'result.content.*.text' --ignore '**.timestamp'`,
+	Args: cobra.ExactArgs(2),
+	RunE: runReplay,
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	mgr, err := newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	configPath := cmd.Flag("config").Value.String()
+	if configPath == "" {
+		configPath = findConfigFile()
+	}
+	ignore, _ := cmd.Flags().GetStringArray("ignore")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	messages, err := manager.ReadRecording(args[1])
+	if err != nil {
+		return err
+	}
+
+	name, version := manager.ParseServerSpec(args[0])
+	result, err := mgr.Replay(name, version, configPath, messages, ignore, timeout)
+	if err != nil {
+		return err
+	}
+
+	if isStructuredOutput() {
+		if err := printResult(result); err != nil {
+			return err
+		}
+	} else {
+		for _, mismatch := range result.Mismatches {
+			fmt.Fprintf(messageWriter, "%s (id %s):\n", mismatch.Method, mismatch.ID)
+			for _, difference := range mismatch.Differences {
+				fmt.Fprintf(messageWriter, "  %s\n", difference)
+			}
+		}
+		fmt.Fprintf(messageWriter, "Replayed %d requests against %s@%s: %d responses differ\n", result.Requests, result.Server, result.Version, len(result.Mismatches))
+	}
+
+	if len(result.Mismatches) > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d of %d responses differ from the recording", len(result.Mismatches), result.Requests)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+	replayCmd.Flags().StringP("config", "c", "", "Path to mcpv.json config file")
+	replayCmd.Flags().StringArray("ignore", nil, "Path of a response field to leave out of the comparison (repeatable)")
+	replayCmd.Flags().Duration("timeout", manager.DefaultCheckTimeout, "How long the server may take to answer each request")
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 
 	"github.com/socialviolation/asciiban/ascii"
+	manager "github.com/socialviolation/mcpv/internal/mcpv"
 	"github.com/spf13/cobra"
 )
 
@@ -31,14 +32,16 @@ Examples:
   mcpv install server@1.0.0       # Install specific server version
   mcpv list                       # List installed servers
   mcpv update server              # Update server to latest version
-  mcpv remove server@1.0.0        # Remove specific server version`,
+  mcpv remove server@1.0.0        # Remove specific server version
+  mcpv list --output json         # List servers as JSON`,
+	PersistentPreRunE: setupOutput,
 	Run: func(cmd *cobra.Command, args []string) {
 		ascii.Draw(
 			ascii.WithMessage("mcpv."),
 			ascii.WithFont(ascii.FontUnivers),
 			ascii.WithPalette(ascii.PaletteLime),
 		)
-		fmt.Printf("Version: %s\nCommit: %s\n", Version, Commit)
+		fmt.Fprintf(messageWriter, "Version: %s\nCommit: %s\n", Version, Commit)
 		_ = cmd.Help()
 	},
 }
@@ -46,8 +49,14 @@ Examples:
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
+	manager.ClientInfo.Version = Version
+
+	// Errors and usage are reported by printError, in the requested format
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	cmd, err := rootCmd.ExecuteC()
 	if err != nil {
+		printError(cmd, err)
 		os.Exit(1)
 	}
 }
@@ -58,4 +67,7 @@ func init() {
 
 	// Add version flag
 	rootCmd.Flags().BoolP("version", "v", false, "Show version information")
+
+	// Add output format flag
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputText, "Output format: text, json or yaml")
 }
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	manager "github.com/socialviolation/mcpv/internal/mcpv"
+	"github.com/spf13/cobra"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run <server>[@version] [-- args...]",
+	Short: "Run an installed server over stdio",
+	Long: `Run an installed MCP server with standard input and output attached, as an
+agent would. Arguments after -- are passed on to the server.
+
+Without a version, the version locked or pinned for the server in mcpv.json is
+run, otherwise the highest installed version. Args and env from mcpv.json are
+applied, with variables and secrets resolved. The exit code is the server's.
+
+Because 'mcpv run <server>' does not depend on install paths, it can be used as
+the command of a server in agent configurations.
+
+With --record, every JSON-RPC message exchanged over stdio is written to a file,
+one per line with a timestamp and its sender, for debugging or for use with
+'mcpv replay'.
+
+Examples:
+  mcpv run github                 # Run the version from mcpv.json
+  mcpv run github@1.2.0           # Run a specific version
+  mcpv run github -- --verbose    # Pass extra arguments to the server
+  mcpv run github --record session.jsonl  # Record the session`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runRun,
+}
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:   "exec [--config <path>] <server> [args...]",
+	Short: "Run a server with the version of the nearest mcpv.json",
+	Long: `Run a server like 'mcpv run', taking the version from the nearest mcpv.json and
+mcpv.lock in the current directory or its parents, or from the mcpv.json given
+with --config before the server name. All arguments after the server name are
+passed on to the server.
+
+Agents run servers this way when mcpv.json sets "launcher" to "exec" or "shim",
+so their configurations keep working when servers are upgraded or old versions
+are removed. Servers with a tools filter the agent cannot apply itself are also
+run this way: mcpv then relays the session, hiding the tools the filter does not
+allow and refusing calls to them, and connects to remote servers over HTTP.
+Entries in user-level agent configurations name the project's mcpv.json with
+--config, since agents do not start them from the project directory.
+
+Examples:
+  mcpv exec github                # Run the version the project uses
+  mcpv exec github --verbose      # Pass arguments to the server
+  mcpv exec --config ~/work/app/mcpv.json github  # Use a specific mcpv.json`,
+	Args:               cobra.MinimumNArgs(1),
+	DisableFlagParsing: true,
+	RunE:               runExec,
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	if dash := cmd.ArgsLenAtDash(); dash > 1 || (dash < 0 && len(args) > 1) {
+		return fmt.Errorf("pass server arguments after --, for example: mcpv run %s -- %s", args[0], args[1])
+	}
+
+	mgr, err := newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	configPath := cmd.Flag("config").Value.String()
+	if configPath == "" {
+		configPath = findConfigFile()
+	}
+
+	name, version := manager.ParseServerSpec(args[0])
+	server, err := mgr.ResolveServer(name, version, configPath)
+	if err != nil {
+		return err
+	}
+
+	cmd.SilenceUsage = true
+	return runServer(server, args[1:], cmd.Flag("record").Value.String())
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	if args[0] == "-h" || args[0] == "--help" {
+		return cmd.Help()
+	}
+
+	// Flag parsing is disabled so arguments after the server name reach the
+	// server; only a --config before it belongs to mcpv
+	configPath := ""
+	switch {
+	case args[0] == "--config" || args[0] == "-c":
+		if len(args) < 3 {
+			return fmt.Errorf("usage: mcpv exec --config <path> <server> [args...]")
+		}
+		configPath, args = args[1], args[2:]
+	case strings.HasPrefix(args[0], "--config="):
+		if len(args) < 2 {
+			return fmt.Errorf("usage: mcpv exec --config <path> <server> [args...]")
+		}
+		configPath, args = strings.TrimPrefix(args[0], "--config="), args[1:]
+	}
+	if configPath == "" {
+		configPath = findNearestConfigFile()
+	}
+
+	mgr, err := newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	name, version := manager.ParseServerSpec(args[0])
+	server, err := mgr.ConnectableServer(name, version, configPath)
+	if err != nil {
+		return err
+	}
+
+	cmd.SilenceUsage = true
+	if server.IsRemote() || server.Tools != nil {
+		return proxyServer(mgr, server, args[1:])
+	}
+	return runServer(server, args[1:], "")
+}
+
+// proxyServer relays standard input and output to a resolved server through
+// mcpv, which applies its tools filter and connects to remote servers, and
+// exits with a local server's exit code
+func proxyServer(mgr *manager.Manager, server *manager.MCPServer, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	code, err := mgr.ProxyServer(ctx, server, args, os.Stdin, os.Stdout, os.Stderr)
+	if err != nil {
+		return fmt.Errorf("failed to run %s: %w", server.Name, err)
+	}
+	os.Exit(code)
+	return nil
+}
+
+// runServer runs a resolved server and exits with its exit code. With a
+// recording path, the messages exchanged with the server are recorded there.
+func runServer(server *manager.MCPServer, args []string, recordPath string) error {
+	process := manager.ServerCommand(server, args...)
+	var recorder *manager.Recorder
+	if recordPath != "" {
+		var err error
+		if recorder, err = manager.NewRecorder(recordPath); err != nil {
+			return err
+		}
+
+		process.Stdin = io.TeeReader(os.Stdin, recorder.Writer(manager.FromClient))
+		process.Stdout = io.MultiWriter(os.Stdout, recorder.Writer(manager.FromServer))
+		// Standard input is copied by a goroutine that only notices the
+		// server exited on its next read; do not wait for it
+		process.WaitDelay = time.Second
+	}
+
+	code, err := runServerProcess(process)
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: the recording is incomplete: %v\n", err)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run %s@%s: %w", server.Name, server.Version, err)
+	}
+	os.Exit(code)
+	return nil
+}
+
+// findNearestConfigFile returns the mcpv.json in the current directory or the
+// closest of its parents, falling back to the user's global config
+func findNearestConfigFile() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return findConfigFile()
+	}
+
+	for {
+		configPath := filepath.Join(dir, "mcpv.json")
+		if _, err := os.Stat(configPath); err == nil {
+			return configPath
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return findConfigFile()
+		}
+		dir = parent
+	}
+}
+
+// runServerProcess runs a server with the standard streams of mcpv attached,
+// unless they are set already, passing on interrupt and termination signals,
+// and returns its exit code
+func runServerProcess(process *exec.Cmd) (int, error) {
+	if process.Stdin == nil {
+		process.Stdin = os.Stdin
+	}
+	if process.Stdout == nil {
+		process.Stdout = os.Stdout
+	}
+	process.Stderr = os.Stderr
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	if err := process.Start(); err != nil {
+		return 0, err
+	}
+
+	go func() {
+		for sig := range signals {
+			_ = process.Process.Signal(sig)
+		}
+	}()
+
+	err := process.Wait()
+	if errors.Is(err, exec.ErrWaitDelay) {
+		return process.ProcessState.ExitCode(), nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(execCmd)
+	runCmd.Flags().StringP("config", "c", "", "Path to mcpv.json config file")
+	runCmd.Flags().String("record", "", "Record the JSON-RPC messages exchanged with the server to this file")
+}
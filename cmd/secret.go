@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	manager "github.com/socialviolation/mcpv/internal/mcpv"
+	"github.com/spf13/cobra"
+)
+
+// secretCmd represents the secret command
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage server credentials in the OS secret store",
+	Long: `Manage credentials for MCP servers without storing them in mcpv.json.
+
+Secrets are kept in the Secret Service (GNOME Keyring, KWallet) through
+secret-tool when it is available, and otherwise in a file encrypted with a key
+kept in $XDG_STATE_HOME/mcpv, or with MCPV_SECRETS_PASSPHRASE when it is set.
+Set MCPV_SECRET_STORE to secret-service or file to choose the store.
+
+A secret stored for a server is added to the env of the server's agent entries
+and can be referenced as ${NAME} in its env, args and headers. Run 'mcpv sync'
+after changing secrets to update agent configurations.
+
+Examples:
+  mcpv secret set github GITHUB_TOKEN          # Prompt for the value
+  echo "$TOKEN" | mcpv secret set github GITHUB_TOKEN
+  mcpv secret list                             # List stored secrets
+  mcpv secret remove github GITHUB_TOKEN       # Delete a secret`,
+}
+
+// secretSetCmd represents the secret set command
+var secretSetCmd = &cobra.Command{
+	Use:   "set <server> <NAME>",
+	Short: "Store a secret for a server",
+	Long: `Store a secret for a server. The value is read from standard input, prompting
+without echo when it is a terminal, so it never appears in the shell history.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runSecretSet,
+}
+
+// secretListCmd represents the secret list command
+var secretListCmd = &cobra.Command{
+	Use:   "list [server]",
+	Short: "List stored secrets",
+	Long:  `List the names of stored secrets, for one server or all of them. Values are never shown.`,
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runSecretList,
+}
+
+// secretRemoveCmd represents the secret remove command
+var secretRemoveCmd = &cobra.Command{
+	Use:     "remove <server> <NAME>",
+	Aliases: []string{"rm"},
+	Short:   "Delete a stored secret",
+	Args:    cobra.ExactArgs(2),
+	RunE:    runSecretRemove,
+}
+
+// secretsResult lists stored secrets
+type secretsResult struct {
+	Store   string           `json:"store" yaml:"store"`
+	Secrets []manager.Secret `json:"secrets" yaml:"secrets"`
+}
+
+func runSecretSet(cmd *cobra.Command, args []string) error {
+	server, name := args[0], args[1]
+
+	mgr, err := newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	value, err := readSecretValue(fmt.Sprintf("Value for %s of %s: ", name, server))
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return fmt.Errorf("no value given for %s", name)
+	}
+
+	if err := mgr.SetSecret(server, name, value); err != nil {
+		return err
+	}
+
+	if isStructuredOutput() {
+		return printResult(secretsResult{Store: mgr.SecretStoreName(), Secrets: []manager.Secret{{Server: server, Name: name}}})
+	}
+
+	fmt.Fprintf(messageWriter, "✓ Stored %s for %s in %s\n", name, server, mgr.SecretStoreName())
+	fmt.Fprintln(messageWriter, "Run 'mcpv sync' to update agent configurations")
+	return nil
+}
+
+func runSecretList(cmd *cobra.Command, args []string) error {
+	mgr, err := newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	server := ""
+	if len(args) == 1 {
+		server = args[0]
+	}
+
+	secrets, err := mgr.ListSecrets(server)
+	if err != nil {
+		return err
+	}
+
+	if isStructuredOutput() {
+		return printResult(secretsResult{Store: mgr.SecretStoreName(), Secrets: secrets})
+	}
+
+	if len(secrets) == 0 {
+		fmt.Fprintln(messageWriter, "No secrets stored")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(messageWriter, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVER\tNAME")
+	fmt.Fprintln(w, "------\t----")
+	for _, secret := range secrets {
+		fmt.Fprintf(w, "%s\t%s\n", secret.Server, secret.Name)
+	}
+	return w.Flush()
+}
+
+func runSecretRemove(cmd *cobra.Command, args []string) error {
+	server, name := args[0], args[1]
+
+	mgr, err := newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	removed, err := mgr.RemoveSecret(server, name)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("no secret %s stored for %s", name, server)
+	}
+
+	if isStructuredOutput() {
+		return printResult(secretsResult{Store: mgr.SecretStoreName(), Secrets: []manager.Secret{{Server: server, Name: name}}})
+	}
+
+	fmt.Fprintf(messageWriter, "✓ Removed %s of %s\n", name, server)
+	return nil
+}
+
+// readSecretValue reads a secret from standard input. On a terminal it
+// prompts and turns off echo while the value is typed.
+func readSecretValue(prompt string) (string, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	if info.Mode()&os.ModeCharDevice == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	if err := stty("-echo"); err == nil {
+		defer func() {
+			stty("echo")
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// stty changes the settings of the terminal on standard input
+func stty(setting string) error {
+	cmd := exec.Command("stty", setting)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
+
+func init() {
+	rootCmd.AddCommand(secretCmd)
+	secretCmd.AddCommand(secretSetCmd)
+	secretCmd.AddCommand(secretListCmd)
+	secretCmd.AddCommand(secretRemoveCmd)
+}
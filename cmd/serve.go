@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	manager "github.com/socialviolation/mcpv/internal/mcpv"
+	"github.com/spf13/cobra"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve every server of mcpv.json to an agent as one MCP server",
+	Long: `Act as an MCP server over stdio that runs every server of the nearest mcpv.json
+and exposes their tools, prompts and resources together. Local servers are
+started as 'mcpv exec' would; remote servers are reached over HTTP.
+
+Tools and prompts are renamed after their server, so the create_issue tool of
+the github server becomes github__create_issue. Resources keep their URIs.
+Servers that fail to start are reported on standard error and left out, and
+the standard error of each server is passed on with its name as a prefix.
+
+Local servers that exit are restarted as the restart policy of their entry in
+mcpv.json says, by default after failures, up to 5 times in a row with a
+growing delay. Their output is also written to a log file shown by 'mcpv logs',
+and 'mcpv ps' lists them.
+
+Agents are configured with a single "mcpv" entry running 'mcpv serve' when
+mcpv.json sets "launcher" to "serve".
+
+Examples:
+  mcpv serve                      # Serve the servers of the nearest mcpv.json
+  mcpv serve --config ~/mcpv.json # Serve the servers of another config`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	mgr, err := newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	configPath := cmd.Flag("config").Value.String()
+	if configPath == "" {
+		configPath = findNearestConfigFile()
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	cmd.SilenceUsage = true
+	gateway, err := mgr.StartGateway(configPath, timeout, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer gateway.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := gateway.Serve(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringP("config", "c", "", "Path to mcpv.json config file")
+	serveCmd.Flags().Duration("timeout", manager.DefaultCheckTimeout, "How long each server may take to start")
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	manager "github.com/socialviolation/mcpv/internal/mcpv"
+	"github.com/spf13/cobra"
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Make agent configurations match mcpv.json",
+	Long: `Make the MCP servers configured for each agent match mcpv.json exactly.
+
+Servers in mcpv.json that are not installed yet are installed first. Then, for
+each target agent:
+  - servers missing from the agent's configuration are added
+  - entries that differ from mcpv.json are rewritten
+  - entries mcpv wrote for servers no longer in mcpv.json are removed
+
+Entries added to an agent's configuration by hand are never touched.
+
+Syncs the agent given with --agent, otherwise the default agent from mcpv.json,
+otherwise all detected agents.
+
+Examples:
+  mcpv sync                       # Sync the default or detected agents
+  mcpv sync --agent cursor        # Sync Cursor only
+  mcpv sync --global              # Sync global agent configurations
+  mcpv sync --dry-run             # Show what sync would change`,
+	Args: cobra.NoArgs,
+	RunE: runSync,
+}
+
+// syncResult lists what a sync changed
+type syncResult struct {
+	Config    string                     `json:"config" yaml:"config"`
+	Installed []string                   `json:"installed,omitempty" yaml:"installed,omitempty"`
+	Entries   []manager.AgentEntryStatus `json:"entries" yaml:"entries"`
+	Plan      *manager.Plan              `json:"plan,omitempty" yaml:"plan,omitempty"`
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	mgr, err := newManagerForCommand(cmd)
+	if err != nil {
+		return err
+	}
+
+	configPath := cmd.Flag("config").Value.String()
+	if configPath == "" {
+		configPath = findConfigFile()
+	}
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return fmt.Errorf("no mcpv.json found in current directory. Use 'mcpv init' to create one")
+	}
+	agentTypes, err := targetAgents(mgr, cmd, configPath)
+	if err != nil {
+		return err
+	}
+
+	useGlobal, _ := cmd.Flags().GetBool("global")
+
+	result, err := mgr.SyncAgents(configPath, agentTypes, !useGlobal)
+	if err != nil {
+		return fmt.Errorf("failed to sync agent configurations: %w", err)
+	}
+
+	if isStructuredOutput() {
+		if err := printResult(syncResult{Config: configPath, Installed: result.Installed, Entries: result.Entries, Plan: mgr.Plan()}); err != nil {
+			return err
+		}
+	} else if len(agentTypes) == 0 {
+		fmt.Fprintln(messageWriter, "No supported AI agents detected.")
+	} else {
+		for _, installed := range result.Installed {
+			printDone(mgr, "✓ Installed %s\n", installed)
+		}
+		if err := printSyncChanges(result.Entries); err != nil {
+			return err
+		}
+	}
+
+	if err := printPlan(mgr); err != nil {
+		return err
+	}
+
+	problems := 0
+	for _, status := range result.Entries {
+		if status.OutOfSync() {
+			problems++
+		}
+	}
+
+	if problems > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("failed to sync %d agent configuration entries", problems)
+	}
+
+	return nil
+}
+
+// printSyncChanges lists the entries a sync added, updated or removed, and
+// the entries it could not sync
+func printSyncChanges(entries []manager.AgentEntryStatus) error {
+	w := tabwriter.NewWriter(messageWriter, 0, 0, 2, ' ', 0)
+	unchanged, unmanaged := 0, 0
+	changes := 0
+
+	for _, status := range entries {
+		var action string
+		switch {
+		case status.Fixed && status.Status == manager.EntryMissing:
+			action = "added"
+		case status.Fixed && status.Status == manager.EntryDiffers:
+			action = "updated"
+		case status.Fixed && status.Status == manager.EntryOrphaned:
+			action = "removed"
+		case status.Status == manager.EntryMatches:
+			unchanged++
+			continue
+		case status.Status == manager.EntryUnmanaged:
+			unmanaged++
+			continue
+		default:
+			action = status.Describe()
+		}
+
+		if changes == 0 {
+			fmt.Fprintln(w, "AGENT\tSERVER\tACTION\tPATH")
+			fmt.Fprintln(w, "-----\t------\t------\t----")
+		}
+		changes++
+
+		server := status.Server
+		if server == "" {
+			server = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.Agent, server, action, status.Path)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if changes == 0 {
+		fmt.Fprintln(messageWriter, "Agent configurations already match mcpv.json")
+	}
+	fmt.Fprintf(messageWriter, "%d unchanged, %d unmanaged left alone\n", unchanged, unmanaged)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().StringP("config", "c", "", "Path to mcpv.json config file")
+	syncCmd.Flags().StringP("agent", "a", "", "Sync a specific agent only. If not specified, syncs the default agent or all detected agents")
+	syncCmd.Flags().BoolP("global", "g", false, "Sync the global agent configuration instead of local (project-specific)")
+	syncCmd.Flags().Bool("dry-run", false, "Print the planned actions and file changes without making them")
+}
@@ -12,26 +12,59 @@ var updateCmd = &cobra.Command{
 	Use:   "update [server]",
 	Short: "Update MCP servers to latest versions",
 	Long: `Update MCP servers to their latest versions. If no server is specified,
-updates all servers configured in mcpv.json.`,
+updates all servers configured in mcpv.json.
+
+The repository of a server is looked up in mcpv.json, then mcpv.lock, then the
+install manifest of its installed versions. Servers pinned to an exact version
+are moved to the latest release; version ranges such as ^1.2.0 are resolved to
+the highest matching release. mcpv.json, mcpv.lock and agent configurations are
+switched to the new version. The previous version is kept unless --remove-old is set.
+
+Examples:
+  mcpv update                     # Update all servers from mcpv.json
+  mcpv update server              # Update a single server
+  mcpv update server --remove-old # Update and delete the previous version
+  mcpv update --dry-run           # Show what would be updated and changed`,
 	RunE: runUpdate,
 }
 
 func runUpdate(cmd *cobra.Command, args []string) error {
-	mgr, err := manager.NewManager()
+	mgr, err := newManagerForCommand(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to create manager: %w", err)
+		return err
 	}
 
+	if err := updateServers(mgr, cmd, args); err != nil {
+		return err
+	}
+
+	return printPlan(mgr)
+}
+
+func updateServers(mgr *manager.Manager, cmd *cobra.Command, args []string) error {
 	// If no arguments provided, update all servers from mcpv.json
 	if len(args) == 0 {
 		return updateFromConfig(mgr, cmd)
 	}
 
+	configPath := cmd.Flag("config").Value.String()
+	if configPath == "" {
+		configPath = "mcpv.json"
+	}
+	removeOld, _ := cmd.Flags().GetBool("remove-old")
+
 	// Update specific servers
+	result := serversResult{Config: configPath, Servers: []serverResult{}, Plan: mgr.Plan()}
 	for _, serverName := range args {
-		if err := updateServer(mgr, serverName); err != nil {
-			return err
+		serverStatus, err := updateServer(mgr, serverName, configPath, removeOld)
+		if err != nil {
+			return fmt.Errorf("failed to update %s: %w", serverName, err)
 		}
+		result.Servers = append(result.Servers, serverStatus)
+	}
+
+	if isStructuredOutput() {
+		return printResult(result)
 	}
 
 	return nil
@@ -42,42 +75,106 @@ func updateFromConfig(mgr *manager.Manager, cmd *cobra.Command) error {
 	if configPath == "" {
 		configPath = "mcpv.json"
 	}
+	removeOld, _ := cmd.Flags().GetBool("remove-old")
 
 	config, err := mgr.LoadProjectConfig(configPath)
 	if err != nil {
 		return fmt.Errorf("failed to load project config: %w", err)
 	}
 
-	if len(config.Servers) == 0 {
-		fmt.Println("No servers configured in mcpv.json")
+	if len(config.Servers) == 0 && !isStructuredOutput() {
+		fmt.Fprintln(messageWriter, "No servers configured in mcpv.json")
 		return nil
 	}
 
+	result := serversResult{Config: configPath, Servers: []serverResult{}, Plan: mgr.Plan()}
 	for _, server := range config.Servers {
+		if server.IsRemote() {
+			fmt.Fprintf(messageWriter, "Skipping %s: remote server\n", server.Name)
+			result.Servers = append(result.Servers, serverResult{
+				Name:   server.Name,
+				URL:    server.URL,
+				Status: statusSkipped,
+				Error:  "remote server",
+			})
+			continue
+		}
 		if server.Repository == "" {
-			fmt.Printf("Skipping %s: no repository specified\n", server.Name)
+			fmt.Fprintf(messageWriter, "Skipping %s: no repository specified\n", server.Name)
+			result.Servers = append(result.Servers, serverResult{
+				Name:    server.Name,
+				Version: server.Version,
+				Status:  statusSkipped,
+				Error:   "no repository specified",
+			})
 			continue
 		}
 
-		fmt.Printf("Updating %s...\n", server.Name)
-		if err := mgr.UpdateServer(server.Name, server.Repository); err != nil {
-			fmt.Printf("Failed to update %s: %v\n", server.Name, err)
-			continue
+		serverStatus, err := updateServer(mgr, server.Name, configPath, removeOld)
+		if err != nil {
+			fmt.Fprintf(messageWriter, "Failed to update %s: %v\n", server.Name, err)
+			serverStatus = serverResult{
+				Name:       server.Name,
+				Version:    server.Version,
+				Repository: server.Repository,
+				Status:     statusFailed,
+				Error:      err.Error(),
+			}
 		}
-		fmt.Printf("Successfully updated %s\n", server.Name)
+		result.Servers = append(result.Servers, serverStatus)
+	}
+
+	if isStructuredOutput() {
+		if err := printResult(result); err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, server := range result.Servers {
+		if server.Status == statusFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d server(s) could not be updated", failed)
 	}
 
 	return nil
 }
 
-func updateServer(mgr *manager.Manager, serverName string) error {
-	// For now, we need the repository URL which we don't have
-	// In a real implementation, you might store this information
-	// or have a registry of known servers
-	return fmt.Errorf("updating specific servers requires repository information. Use 'mcpv update' to update all servers from mcpv.json")
+// updateServer updates a single server using the repository recorded in mcpv.json,
+// the lockfile or the install manifest
+func updateServer(mgr *manager.Manager, serverName, configPath string, removeOld bool) (serverResult, error) {
+	fmt.Fprintf(messageWriter, "Updating %s...\n", serverName)
+	update, err := mgr.UpdateServerByName(serverName, configPath, removeOld)
+	if err != nil {
+		return serverResult{}, err
+	}
+
+	result := serverResult{
+		Name:       update.Name,
+		Version:    update.Version,
+		Previous:   update.PreviousVersion,
+		Repository: update.Repository,
+		Status:     statusUpToDate,
+	}
+
+	if update.Updated {
+		result.Status = statusUpdated
+		printDone(mgr, "Successfully updated %s from %s to %s\n", serverName, update.PreviousVersion, update.Version)
+	}
+	if update.RemovedPrevious {
+		printDone(mgr, "✓ Removed %s@%s\n", serverName, update.PreviousVersion)
+	}
+
+	return result, nil
 }
 
 func init() {
 	rootCmd.AddCommand(updateCmd)
 	updateCmd.Flags().StringP("config", "c", "", "Path to mcpv.json config file")
+	updateCmd.Flags().Bool("remove-old", false, "Remove the previously installed version after updating")
+	updateCmd.Flags().Bool("dry-run", false, "Print the planned actions and file changes without making them")
 }
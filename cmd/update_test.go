@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdateFailureNamesServerOnce(t *testing.T) {
+	_, project := newTestProject(t)
+	missing := filepath.Join(project, "missing")
+	writeFile(t, filepath.Join(project, "mcpv.json"), `{"servers": [{"name": "gh", "version": "^1.0.0", "repository": "`+missing+`"}]}`)
+
+	out, err := executeCommandErr("update")
+	if err == nil {
+		t.Fatalf("updating from a missing repository succeeded:\n%s", out)
+	}
+	if !strings.Contains(out, "Failed to update gh: ") || strings.Count(strings.ToLower(out), "failed to update gh") != 1 {
+		t.Errorf("expected gh's failure to be reported once:\n%s", out)
+	}
+
+	_, err = executeCommandErr("update", "gh")
+	if err == nil || strings.Count(err.Error(), "failed to update gh") != 1 {
+		t.Errorf("expected gh's failure to be reported once, got %v", err)
+	}
+}
@@ -5,13 +5,15 @@ go 1.24.3
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/cobra v1.9.1
-	github.com/spf13/pflag v1.0.6 // indirect
+	github.com/spf13/pflag v1.0.6
 )
 
 require (
 	github.com/Masterminds/semver/v3 v3.2.1
 	github.com/go-git/go-git/v5 v5.12.0
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/socialviolation/asciiban v0.3.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -38,5 +40,4 @@ require (
 	golang.org/x/sys v0.18.0 // indirect
 	golang.org/x/tools v0.13.0 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
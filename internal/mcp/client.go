@@ -0,0 +1,272 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// Transport carries messages between a client and one server
+type Transport interface {
+	// Send delivers a message to the server
+	Send(ctx context.Context, msg *Message) error
+	// Receive blocks until the server sends a message. It returns io.EOF once
+	// the server has gone away.
+	Receive() (*Message, error)
+	// Close ends the connection and releases its resources
+	Close() error
+}
+
+// Client makes requests to an MCP server over a transport. Requests the
+// server makes of the client are answered as unsupported, except ping.
+type Client struct {
+	transport Transport
+	info      Implementation
+
+	mu           sync.Mutex
+	nextID       int64
+	pending      map[string]chan *Message
+	err          error
+	notification func(msg *Message)
+}
+
+// NewClient starts reading messages from the transport. info identifies the
+// client to the server when the session is initialized.
+func NewClient(transport Transport, info Implementation) *Client {
+	c := &Client{transport: transport, info: info, pending: map[string]chan *Message{}}
+	go c.receive()
+	return c
+}
+
+// receive dispatches messages from the server until the transport fails
+func (c *Client) receive() {
+	for {
+		msg, err := c.transport.Receive()
+		if err != nil {
+			c.mu.Lock()
+			c.err = err
+			for _, ch := range c.pending {
+				close(ch)
+			}
+			c.pending = nil
+			c.mu.Unlock()
+			return
+		}
+
+		switch {
+		case msg.IsResponse():
+			c.mu.Lock()
+			ch := c.pending[string(msg.ID)]
+			delete(c.pending, string(msg.ID))
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- msg
+			}
+		case msg.IsRequest():
+			reply := NewErrorResponse(msg.ID, CodeMethodNotFound, "method %s is not supported by this client", msg.Method)
+			if msg.Method == "ping" {
+				reply, _ = NewResponse(msg.ID, struct{}{})
+			}
+			go c.transport.Send(context.Background(), reply)
+		case msg.IsNotification():
+			c.mu.Lock()
+			handle := c.notification
+			c.mu.Unlock()
+			if handle != nil {
+				handle(msg)
+			}
+		}
+	}
+}
+
+// OnNotification sets a function called with each notification the server
+// sends, replacing any set before
+func (c *Client) OnNotification(handle func(msg *Message)) {
+	c.mu.Lock()
+	c.notification = handle
+	c.mu.Unlock()
+}
+
+// Call sends a request and decodes the result of its response into result,
+// which may be nil to discard it
+func (c *Client) Call(ctx context.Context, method string, params, result interface{}) error {
+	c.mu.Lock()
+	if c.pending == nil {
+		c.mu.Unlock()
+		return c.closedError(method)
+	}
+	c.nextID++
+	id := json.RawMessage(strconv.FormatInt(c.nextID, 10))
+	ch := make(chan *Message, 1)
+	c.pending[string(id)] = ch
+	c.mu.Unlock()
+
+	request, err := NewRequest(id, method, params)
+	if err == nil {
+		err = c.transport.Send(ctx, request)
+	}
+	if err != nil {
+		c.forget(id)
+		return fmt.Errorf("failed to send %s: %w", method, err)
+	}
+
+	select {
+	case response, ok := <-ch:
+		if !ok {
+			return c.closedError(method)
+		}
+		if response.Error != nil {
+			return fmt.Errorf("%s failed: %w", method, response.Error)
+		}
+		if result != nil {
+			if err := json.Unmarshal(response.Result, result); err != nil {
+				return fmt.Errorf("failed to decode %s result: %w", method, err)
+			}
+		}
+		return nil
+	case <-ctx.Done():
+		c.forget(id)
+		go c.Notify(context.Background(), "notifications/cancelled", map[string]interface{}{"requestId": id, "reason": ctx.Err().Error()})
+		return fmt.Errorf("no response to %s: %w", method, ctx.Err())
+	}
+}
+
+// Notify sends a notification
+func (c *Client) Notify(ctx context.Context, method string, params interface{}) error {
+	notification, err := NewRequest(nil, method, params)
+	if err != nil {
+		return err
+	}
+	if err := c.transport.Send(ctx, notification); err != nil {
+		return fmt.Errorf("failed to send %s: %w", method, err)
+	}
+	return nil
+}
+
+// Initialize performs the MCP handshake: it sends initialize, then
+// notifications/initialized once the server has answered
+func (c *Client) Initialize(ctx context.Context) (*InitializeResult, error) {
+	params := InitializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]json.RawMessage{},
+		ClientInfo:      c.info,
+	}
+
+	var result InitializeResult
+	if err := c.Call(ctx, "initialize", params, &result); err != nil {
+		return nil, err
+	}
+	if result.ProtocolVersion == "" {
+		return nil, fmt.Errorf("initialize result has no protocol version")
+	}
+
+	if err := c.Notify(ctx, "notifications/initialized", nil); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close closes the transport
+func (c *Client) Close() error {
+	return c.transport.Close()
+}
+
+// forget stops waiting for the response to a request
+func (c *Client) forget(id json.RawMessage) {
+	c.mu.Lock()
+	delete(c.pending, string(id))
+	c.mu.Unlock()
+}
+
+// closedError explains why a request got no response
+func (c *Client) closedError(method string) error {
+	c.mu.Lock()
+	err := c.err
+	c.mu.Unlock()
+
+	if err == nil || err == io.EOF {
+		return fmt.Errorf("server closed the connection before answering %s", method)
+	}
+	return fmt.Errorf("connection to server failed before answering %s: %w", method, err)
+}
+
+// ListTools returns every tool the server offers
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	err := c.listAll(ctx, "tools/list", "tools", func(items json.RawMessage) error {
+		var page []Tool
+		err := json.Unmarshal(items, &page)
+		tools = append(tools, page...)
+		return err
+	})
+	return tools, err
+}
+
+// ListResources returns every resource the server offers
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	var resources []Resource
+	err := c.listAll(ctx, "resources/list", "resources", func(items json.RawMessage) error {
+		var page []Resource
+		err := json.Unmarshal(items, &page)
+		resources = append(resources, page...)
+		return err
+	})
+	return resources, err
+}
+
+// ListResourceTemplates returns every resource template the server offers
+func (c *Client) ListResourceTemplates(ctx context.Context) ([]ResourceTemplate, error) {
+	var templates []ResourceTemplate
+	err := c.listAll(ctx, "resources/templates/list", "resourceTemplates", func(items json.RawMessage) error {
+		var page []ResourceTemplate
+		err := json.Unmarshal(items, &page)
+		templates = append(templates, page...)
+		return err
+	})
+	return templates, err
+}
+
+// ListPrompts returns every prompt the server offers
+func (c *Client) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	var prompts []Prompt
+	err := c.listAll(ctx, "prompts/list", "prompts", func(items json.RawMessage) error {
+		var page []Prompt
+		err := json.Unmarshal(items, &page)
+		prompts = append(prompts, page...)
+		return err
+	})
+	return prompts, err
+}
+
+// listAll calls a paginated list method until the server returns no further
+// cursor, passing the items of each page under key to add
+func (c *Client) listAll(ctx context.Context, method, key string, add func(items json.RawMessage) error) error {
+	cursor := ""
+	for {
+		var params interface{}
+		if cursor != "" {
+			params = map[string]string{"cursor": cursor}
+		}
+
+		var page map[string]json.RawMessage
+		if err := c.Call(ctx, method, params, &page); err != nil {
+			return err
+		}
+		if items, ok := page[key]; ok {
+			if err := add(items); err != nil {
+				return fmt.Errorf("failed to decode %s result: %w", method, err)
+			}
+		}
+
+		cursor = ""
+		if next, ok := page["nextCursor"]; ok {
+			json.Unmarshal(next, &cursor)
+		}
+		if cursor == "" {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers of the Streamable HTTP transport
+const (
+	SessionIDHeader       = "Mcp-Session-Id"
+	ProtocolVersionHeader = "MCP-Protocol-Version"
+)
+
+// httpCloseTimeout bounds the request that ends a session on close
+const httpCloseTimeout = 5 * time.Second
+
+// HTTPTransport talks to a server over the Streamable HTTP transport: every
+// message is POSTed to the server's URL, which answers with JSON or with an
+// event stream carrying the response
+type HTTPTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu              sync.Mutex
+	sessionID       string
+	protocolVersion string
+	initializeIDs   map[string]bool
+
+	incoming  chan *Message
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewHTTPTransport returns a transport to the server at url, sending headers
+// with every request
+func NewHTTPTransport(url string, headers map[string]string) *HTTPTransport {
+	return &HTTPTransport{
+		url:           url,
+		headers:       headers,
+		client:        &http.Client{},
+		initializeIDs: map[string]bool{},
+		incoming:      make(chan *Message, 16),
+		done:          make(chan struct{}),
+	}
+}
+
+// Send POSTs a message and queues the messages the server answers with
+func (t *HTTPTransport) Send(ctx context.Context, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if msg.Method == "initialize" {
+		t.mu.Lock()
+		t.initializeIDs[string(msg.ID)] = true
+		t.mu.Unlock()
+	}
+
+	req, err := t.newRequest(ctx, http.MethodPost, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	if err := httpError(resp); err != nil {
+		return err
+	}
+	if id := resp.Header.Get(SessionIDHeader); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/event-stream":
+		go func() {
+			defer resp.Body.Close()
+			readEvents(resp.Body, func(event, data string) {
+				if event == "" || event == "message" {
+					t.deliver([]byte(data))
+				}
+			})
+		}()
+	case "application/json":
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		t.deliver(body)
+	default:
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// Receive returns the next message the server sent
+func (t *HTTPTransport) Receive() (*Message, error) {
+	select {
+	case msg := <-t.incoming:
+		return msg, nil
+	case <-t.done:
+		return nil, io.EOF
+	}
+}
+
+// Close ends the session with the server, if it started one
+func (t *HTTPTransport) Close() error {
+	t.closeOnce.Do(func() {
+		t.mu.Lock()
+		sessionID := t.sessionID
+		t.mu.Unlock()
+
+		if sessionID != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), httpCloseTimeout)
+			if req, err := t.newRequest(ctx, http.MethodDelete, nil); err == nil {
+				if resp, err := t.client.Do(req); err == nil {
+					resp.Body.Close()
+				}
+			}
+			cancel()
+		}
+		close(t.done)
+	})
+	return nil
+}
+
+// newRequest returns a request to the server with the session's headers
+func (t *HTTPTransport) newRequest(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.url, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessionID != "" {
+		req.Header.Set(SessionIDHeader, t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set(ProtocolVersionHeader, t.protocolVersion)
+	}
+	return req, nil
+}
+
+// deliver queues the message, or batch of messages, in a response body
+func (t *HTTPTransport) deliver(data []byte) {
+	for _, msg := range decodeMessages(data) {
+		if msg.IsResponse() {
+			t.mu.Lock()
+			if t.initializeIDs[string(msg.ID)] {
+				var result InitializeResult
+				if json.Unmarshal(msg.Result, &result) == nil {
+					t.protocolVersion = result.ProtocolVersion
+				}
+				delete(t.initializeIDs, string(msg.ID))
+			}
+			t.mu.Unlock()
+		}
+
+		select {
+		case t.incoming <- msg:
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// decodeMessages decodes a message or a JSON array of messages, dropping
+// anything that is not a JSON-RPC message
+func decodeMessages(data []byte) []*Message {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var batch []*Message
+		if json.Unmarshal(data, &batch) != nil {
+			return nil
+		}
+		return batch
+	}
+
+	var msg Message
+	if json.Unmarshal(data, &msg) != nil || msg.JSONRPC == "" {
+		return nil
+	}
+	return []*Message{&msg}
+}
+
+// httpError returns an error describing a failed response, closing its body
+func httpError(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if message := strings.TrimSpace(string(body)); message != "" {
+		return fmt.Errorf("server returned %s: %s", resp.Status, message)
+	}
+	return fmt.Errorf("server returned %s", resp.Status)
+}
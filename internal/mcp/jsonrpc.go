@@ -0,0 +1,85 @@
+// Package mcp speaks the Model Context Protocol: JSON-RPC 2.0 messages
+// exchanged with MCP servers over stdio or HTTP.
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// JSON-RPC error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Message is a JSON-RPC 2.0 request, notification or response
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// IsRequest reports whether the message is a request expecting a response
+func (m *Message) IsRequest() bool {
+	return m.Method != "" && m.ID != nil
+}
+
+// IsNotification reports whether the message is a notification
+func (m *Message) IsNotification() bool {
+	return m.Method != "" && m.ID == nil
+}
+
+// IsResponse reports whether the message is a response to a request
+func (m *Message) IsResponse() bool {
+	return m.Method == "" && m.ID != nil
+}
+
+// NewRequest returns a request with the given ID and params
+func NewRequest(id json.RawMessage, method string, params interface{}) (*Message, error) {
+	msg := &Message{JSONRPC: "2.0", ID: id, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s params: %w", method, err)
+		}
+		msg.Params = data
+	}
+	return msg, nil
+}
+
+// NewResponse returns a successful response to the request with the given ID
+func NewResponse(id json.RawMessage, result interface{}) (*Message, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	return &Message{JSONRPC: "2.0", ID: id, Result: data}, nil
+}
+
+// NewErrorResponse returns an error response to the request with the given ID
+func NewErrorResponse(id json.RawMessage, code int, format string, args ...interface{}) *Message {
+	return &Message{JSONRPC: "2.0", ID: id, Error: &Error{Code: code, Message: fmt.Sprintf(format, args...)}}
+}
+
+// Errorf returns a JSON-RPC error object for a handler to return
+func Errorf(code int, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Error is a JSON-RPC error object
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
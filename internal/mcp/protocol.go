@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// ProtocolVersion is the MCP revision mcpv requests when it initializes a session
+const ProtocolVersion = "2025-06-18"
+
+// supportedProtocolVersions are the MCP revisions mcpv can serve
+var supportedProtocolVersions = []string{"2024-11-05", "2025-03-26", ProtocolVersion}
+
+// NegotiateProtocolVersion returns the revision a server answers a client
+// requesting the given one with: the same revision if it is supported,
+// otherwise the latest
+func NegotiateProtocolVersion(requested string) string {
+	for _, version := range supportedProtocolVersions {
+		if version == requested {
+			return version
+		}
+	}
+	return ProtocolVersion
+}
+
+// Implementation names a client or server and its version
+type Implementation struct {
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version" yaml:"version"`
+}
+
+// InitializeParams are sent by the client to open a session
+type InitializeParams struct {
+	ProtocolVersion string                     `json:"protocolVersion"`
+	Capabilities    map[string]json.RawMessage `json:"capabilities"`
+	ClientInfo      Implementation             `json:"clientInfo"`
+}
+
+// InitializeResult is the server's answer to initialize
+type InitializeResult struct {
+	ProtocolVersion string                     `json:"protocolVersion"`
+	Capabilities    map[string]json.RawMessage `json:"capabilities"`
+	ServerInfo      Implementation             `json:"serverInfo"`
+	Instructions    string                     `json:"instructions,omitempty"`
+}
+
+// CapabilityNames returns the names of the capabilities the server offers
+func (r *InitializeResult) CapabilityNames() []string {
+	names := make([]string, 0, len(r.Capabilities))
+	for name := range r.Capabilities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Tool is a tool a server offers
+type Tool struct {
+	Name         string                 `json:"name" yaml:"name"`
+	Title        string                 `json:"title,omitempty" yaml:"title,omitempty"`
+	Description  string                 `json:"description,omitempty" yaml:"description,omitempty"`
+	InputSchema  map[string]interface{} `json:"inputSchema" yaml:"inputSchema"`
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty" yaml:"outputSchema,omitempty"`
+	Annotations  map[string]interface{} `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+}
+
+// Resource is a resource a server offers
+type Resource struct {
+	URI         string `json:"uri" yaml:"uri"`
+	Name        string `json:"name" yaml:"name"`
+	Title       string `json:"title,omitempty" yaml:"title,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty" yaml:"mimeType,omitempty"`
+}
+
+// ResourceTemplate describes a family of resources by URI template
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate" yaml:"uriTemplate"`
+	Name        string `json:"name" yaml:"name"`
+	Title       string `json:"title,omitempty" yaml:"title,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty" yaml:"mimeType,omitempty"`
+}
+
+// Prompt is a prompt template a server offers
+type Prompt struct {
+	Name        string           `json:"name" yaml:"name"`
+	Title       string           `json:"title,omitempty" yaml:"title,omitempty"`
+	Description string           `json:"description,omitempty" yaml:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty" yaml:"arguments,omitempty"`
+}
+
+// PromptArgument is an argument of a prompt
+type PromptArgument struct {
+	Name        string `json:"name" yaml:"name"`
+	Title       string `json:"title,omitempty" yaml:"title,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool   `json:"required,omitempty" yaml:"required,omitempty"`
+}
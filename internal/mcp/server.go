@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Handler answers the requests and notifications a client sends a server
+type Handler interface {
+	// HandleRequest returns the result of a request, or an error. An *Error
+	// is sent to the client as it is; other errors are reported as internal
+	// errors. ctx is cancelled if the client cancels the request.
+	HandleRequest(ctx context.Context, method string, params json.RawMessage) (interface{}, error)
+	// HandleNotification is called with each notification from the client
+	HandleNotification(method string, params json.RawMessage)
+}
+
+// Server answers the requests of one client over a transport. Each request is
+// handled in its own goroutine; ping is answered by the server itself.
+type Server struct {
+	transport Transport
+	handler   Handler
+
+	mu       sync.Mutex
+	inFlight map[string]context.CancelFunc
+	handling sync.WaitGroup
+}
+
+// NewServer returns a server that passes the client's requests to handler
+func NewServer(transport Transport, handler Handler) *Server {
+	return &Server{transport: transport, handler: handler, inFlight: map[string]context.CancelFunc{}}
+}
+
+// Serve handles messages from the client until it disconnects or ctx is
+// done, then waits for the requests being handled to be answered. It returns
+// nil when the client disconnected.
+func (s *Server) Serve(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	received := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := s.transport.Receive()
+			if err != nil {
+				received <- err
+				return
+			}
+			s.dispatch(ctx, msg)
+		}
+	}()
+
+	var err error
+	select {
+	case err = <-received:
+		if err == io.EOF {
+			err = nil
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	cancel()
+	s.handling.Wait()
+	return err
+}
+
+// Notify sends a notification to the client
+func (s *Server) Notify(ctx context.Context, method string, params interface{}) error {
+	notification, err := NewRequest(nil, method, params)
+	if err != nil {
+		return err
+	}
+	return s.transport.Send(ctx, notification)
+}
+
+// dispatch handles one message from the client
+func (s *Server) dispatch(ctx context.Context, msg *Message) {
+	switch {
+	case msg.IsRequest() && msg.Method == "ping":
+		reply, _ := NewResponse(msg.ID, struct{}{})
+		s.transport.Send(ctx, reply)
+	case msg.IsRequest():
+		requestCtx, cancel := context.WithCancel(ctx)
+		s.mu.Lock()
+		s.inFlight[string(msg.ID)] = cancel
+		s.mu.Unlock()
+
+		s.handling.Add(1)
+		go func() {
+			defer s.handling.Done()
+			defer s.finish(msg.ID)
+			reply := s.answer(requestCtx, msg)
+			if requestCtx.Err() != nil && ctx.Err() == nil {
+				// The client cancelled the request and expects no response
+				return
+			}
+			s.transport.Send(context.Background(), reply)
+		}()
+	case msg.IsNotification() && msg.Method == "notifications/cancelled":
+		var params struct {
+			RequestID json.RawMessage `json:"requestId"`
+		}
+		if json.Unmarshal(msg.Params, &params) == nil {
+			s.finish(params.RequestID)
+		}
+	case msg.IsNotification():
+		s.handler.HandleNotification(msg.Method, msg.Params)
+	}
+}
+
+// answer passes a request to the handler and returns the response to send
+func (s *Server) answer(ctx context.Context, msg *Message) *Message {
+	result, err := s.handler.HandleRequest(ctx, msg.Method, msg.Params)
+	if err != nil {
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) {
+			return &Message{JSONRPC: "2.0", ID: msg.ID, Error: rpcErr}
+		}
+		return NewErrorResponse(msg.ID, CodeInternalError, "%v", err)
+	}
+
+	if result == nil {
+		result = struct{}{}
+	}
+	reply, err := NewResponse(msg.ID, result)
+	if err != nil {
+		return NewErrorResponse(msg.ID, CodeInternalError, "%v", err)
+	}
+	return reply
+}
+
+// finish cancels the context of a request that was answered or cancelled
+func (s *Server) finish(id json.RawMessage) {
+	s.mu.Lock()
+	cancel := s.inFlight[string(id)]
+	delete(s.inFlight, string(id))
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
@@ -0,0 +1,188 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// SSETransport talks to a server over the HTTP+SSE transport that preceded
+// Streamable HTTP: the server sends messages on a long-lived event stream,
+// which first announces the endpoint messages are POSTed to
+type SSETransport struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+
+	incoming  chan *Message
+	done      chan struct{}
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+// DialSSE opens the event stream of the server at streamURL and waits for it
+// to announce its message endpoint
+func DialSSE(ctx context.Context, streamURL string, headers map[string]string) (*SSETransport, error) {
+	base, err := url.Parse(streamURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server URL: %w", err)
+	}
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	t := &SSETransport{
+		headers:  headers,
+		client:   &http.Client{},
+		incoming: make(chan *Message, 16),
+		done:     make(chan struct{}),
+		cancel:   cancel,
+	}
+
+	endpoints := make(chan string, 1)
+	failed := make(chan error, 1)
+	go func() {
+		resp, err := t.client.Do(req)
+		if err == nil {
+			err = httpError(resp)
+		}
+		if err != nil {
+			failed <- err
+			return
+		}
+		defer resp.Body.Close()
+
+		readEvents(resp.Body, func(event, data string) {
+			switch event {
+			case "endpoint":
+				if endpoint, err := base.Parse(data); err == nil {
+					select {
+					case endpoints <- endpoint.String():
+					default:
+					}
+				}
+			case "", "message":
+				for _, msg := range decodeMessages([]byte(data)) {
+					select {
+					case t.incoming <- msg:
+					case <-t.done:
+						return
+					}
+				}
+			}
+		})
+		failed <- io.EOF
+		t.Close()
+	}()
+
+	select {
+	case t.endpoint = <-endpoints:
+		return t, nil
+	case err := <-failed:
+		cancel()
+		if err == io.EOF {
+			return nil, fmt.Errorf("event stream ended before announcing an endpoint")
+		}
+		return nil, err
+	case <-ctx.Done():
+		cancel()
+		return nil, fmt.Errorf("no endpoint announced: %w", ctx.Err())
+	}
+}
+
+// Send POSTs a message to the endpoint; the response arrives on the stream
+func (t *SSETransport) Send(ctx context.Context, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	if err := httpError(resp); err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Receive returns the next message from the event stream
+func (t *SSETransport) Receive() (*Message, error) {
+	select {
+	case msg := <-t.incoming:
+		return msg, nil
+	case <-t.done:
+		return nil, io.EOF
+	}
+}
+
+// Close closes the event stream
+func (t *SSETransport) Close() error {
+	t.closeOnce.Do(func() {
+		t.cancel()
+		close(t.done)
+	})
+	return nil
+}
+
+// readEvents parses a text/event-stream, calling handle with the type and
+// data of each event
+func readEvents(r io.Reader, handle func(event, data string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var event string
+	var data []string
+	dispatch := func() {
+		if len(data) > 0 {
+			handle(event, strings.Join(data, "\n"))
+		}
+		event, data = "", nil
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		switch {
+		case line == "":
+			dispatch()
+		case strings.HasPrefix(line, ":"):
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				event = value
+			case "data":
+				data = append(data, value)
+			}
+		}
+	}
+	dispatch()
+	return scanner.Err()
+}
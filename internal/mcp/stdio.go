@@ -0,0 +1,231 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// stdioStopTimeout is how long a server may take to exit after its standard
+// input is closed before it is killed
+const stdioStopTimeout = 2 * time.Second
+
+// stderrTailSize is how much of a server's standard error is kept
+const stderrTailSize = 8 * 1024
+
+// StdioTransport runs a server as a child process and exchanges
+// newline-delimited messages over its standard input and output
+type StdioTransport struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr *tailBuffer
+
+	// stdoutPipe is the read end of the server's standard output, closed
+	// once the server has exited and the transport is closed
+	stdoutPipe *os.File
+
+	writeMu sync.Mutex
+	exited  chan struct{}
+	waitErr error
+}
+
+// StartStdio starts a command as a server. The end of its standard error is
+// kept to explain failures; it is also written to cmd.Stderr when that is set.
+func StartStdio(cmd *exec.Cmd) (*StdioTransport, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	// A pipe of our own, rather than StdoutPipe, so output the server wrote
+	// before exiting can still be read after Wait returns
+	stdout, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdout = stdoutWriter
+
+	t := &StdioTransport{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReader(stdout),
+		stderr: &tailBuffer{limit: stderrTailSize},
+		exited: make(chan struct{}),
+
+		stdoutPipe: stdout,
+	}
+	if cmd.WaitDelay == 0 {
+		cmd.WaitDelay = stdioStopTimeout
+	}
+	if cmd.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, t.stderr)
+	} else {
+		cmd.Stderr = t.stderr
+	}
+
+	err = cmd.Start()
+	stdoutWriter.Close()
+	if err != nil {
+		stdout.Close()
+		return nil, err
+	}
+
+	go func() {
+		t.waitErr = cmd.Wait()
+		close(t.exited)
+	}()
+	return t, nil
+}
+
+// Send writes a message as one line to the server's standard input
+func (t *StdioTransport) Send(ctx context.Context, msg *Message) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	return writeLine(t.stdin, msg)
+}
+
+// Receive reads the next message from the server's standard output. Lines
+// that are not JSON-RPC messages, such as stray log output, are skipped.
+func (t *StdioTransport) Receive() (*Message, error) {
+	msg, err := readLine(t.stdout)
+	if errors.Is(err, os.ErrClosed) {
+		// The transport was closed while waiting for the server
+		return nil, io.EOF
+	}
+	return msg, err
+}
+
+// Close closes the server's standard input and waits for it to exit, killing
+// it if it does not exit in time, then closes its standard output
+func (t *StdioTransport) Close() error {
+	t.stdin.Close()
+
+	select {
+	case <-t.exited:
+	case <-time.After(stdioStopTimeout):
+		t.cmd.Process.Kill()
+		<-t.exited
+	}
+	t.stdoutPipe.Close()
+	return nil
+}
+
+// PID returns the process ID of the server
+func (t *StdioTransport) PID() int {
+	return t.cmd.Process.Pid
+}
+
+// Exited is closed once the server process has exited
+func (t *StdioTransport) Exited() <-chan struct{} {
+	return t.exited
+}
+
+// ExitError describes how the server exited, once it has
+func (t *StdioTransport) ExitError() error {
+	select {
+	case <-t.exited:
+		if t.waitErr != nil {
+			return fmt.Errorf("server exited: %w", t.waitErr)
+		}
+		return fmt.Errorf("server exited")
+	default:
+		return nil
+	}
+}
+
+// Stderr returns the end of what the server wrote to standard error
+func (t *StdioTransport) Stderr() string {
+	return t.stderr.String()
+}
+
+// StreamTransport exchanges newline-delimited messages over a reader and a
+// writer, such as the standard input and output of mcpv itself when it serves
+// a client
+type StreamTransport struct {
+	r *bufio.Reader
+	w io.WriteCloser
+
+	writeMu sync.Mutex
+}
+
+// NewStreamTransport reads messages from r and writes them to w
+func NewStreamTransport(r io.Reader, w io.WriteCloser) *StreamTransport {
+	return &StreamTransport{r: bufio.NewReader(r), w: w}
+}
+
+// Send writes a message as one line
+func (t *StreamTransport) Send(ctx context.Context, msg *Message) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	return writeLine(t.w, msg)
+}
+
+// Receive reads the next message, skipping lines that are not messages
+func (t *StreamTransport) Receive() (*Message, error) {
+	return readLine(t.r)
+}
+
+// Close closes the writer
+func (t *StreamTransport) Close() error {
+	return t.w.Close()
+}
+
+// writeLine writes a message as one line
+func writeLine(w io.Writer, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// readLine reads the next line that holds a JSON-RPC message
+func readLine(r *bufio.Reader) (*Message, error) {
+	for {
+		line, err := r.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] == '{' {
+			var msg Message
+			if json.Unmarshal(line, &msg) == nil && msg.JSONRPC != "" {
+				return &msg, nil
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// tailBuffer keeps the last bytes written to it
+type tailBuffer struct {
+	mu    sync.Mutex
+	limit int
+	data  []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.data = append(b.data, p...)
+	if len(b.data) > b.limit {
+		b.data = append([]byte(nil), b.data[len(b.data)-b.limit:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.data)
+}
@@ -0,0 +1,677 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// agentDefinitionsFile mirrors the layout of agents.yaml. Agents are decoded
+// one at a time so an override only replaces the fields it sets.
+type agentDefinitionsFile struct {
+	Version string               `yaml:"version"`
+	Agents  map[string]yaml.Node `yaml:"agents"`
+}
+
+// Sources of agent definitions, from lowest to highest precedence
+const (
+	AgentSourceEmbedded = "embedded"
+	AgentSourceUser     = "user"
+	AgentSourceProject  = "project"
+)
+
+// AgentDefinition describes where an agent keeps its MCP server configuration
+type AgentDefinition struct {
+	Name        string        `yaml:"name,omitempty" json:"name,omitempty"`
+	Type        string        `yaml:"type,omitempty" json:"type,omitempty"`
+	Description string        `yaml:"description,omitempty" json:"description,omitempty"`
+	Config      AgentFileSpec `yaml:"config,omitempty" json:"config"`
+
+	// Source records which layer the definition came from and SourcePath the
+	// file that defined it
+	Source     string `yaml:"-" json:"-"`
+	SourcePath string `yaml:"-" json:"-"`
+}
+
+// AgentFileSpec locates an agent's configuration file and describes its layout.
+// Path is the project-level file, relative to the project. Paths lists
+// candidate user-level locations, one per platform; without them the
+// user-level file is Path relative to the home directory. Agents with only
+// Paths always use their user-level configuration.
+type AgentFileSpec struct {
+	Path  string   `yaml:"path,omitempty" json:"path,omitempty"`
+	Paths []string `yaml:"paths,omitempty" json:"paths,omitempty"`
+
+	// Format is the file format: json (the default, which also accepts
+	// comments and trailing commas), yaml or toml
+	Format string `yaml:"format,omitempty" json:"format,omitempty"`
+
+	// RootKey is the dotted path of the table holding server entries,
+	// mcpServers by default
+	RootKey string `yaml:"root_key,omitempty" json:"root_key,omitempty"`
+
+	// Layout is how entries are stored under the root key: map (the
+	// default), keyed by server name, or list, where each entry carries the
+	// server name in the NameKey field. Lists are supported for yaml only.
+	Layout  string `yaml:"layout,omitempty" json:"layout,omitempty"`
+	NameKey string `yaml:"name_key,omitempty" json:"name_key,omitempty"`
+
+	// Entry maps the fields of a server entry
+	Entry *AgentEntrySpec `yaml:"entry,omitempty" json:"entry,omitempty"`
+
+	// Template is the content a new file starts from, for agents that
+	// require fields besides the server entries
+	Template string `yaml:"template,omitempty" json:"template,omitempty"`
+}
+
+// AgentEntrySpec names the fields of a server entry in an agent's
+// configuration. Empty names fall back to mcpv's own field names; optional
+// fields are only written when named.
+type AgentEntrySpec struct {
+	Command string `yaml:"command,omitempty" json:"command,omitempty"`
+	Args    string `yaml:"args,omitempty" json:"args,omitempty"`
+	Env     string `yaml:"env,omitempty" json:"env,omitempty"`
+	URL     string `yaml:"url,omitempty" json:"url,omitempty"`
+	Headers string `yaml:"headers,omitempty" json:"headers,omitempty"`
+
+	// URLs names the URL field per transport, for agents that use a
+	// different field for each; other transports use URL
+	URLs map[string]string `yaml:"urls,omitempty" json:"urls,omitempty"`
+
+	// Type is the field naming the server's transport, such as "type" or
+	// "transport". Transports maps mcpv's transport names (stdio, http, sse)
+	// to the values the agent expects; unmapped transports are written as is
+	// and transports mapped to "" leave the field out.
+	Type       string            `yaml:"type,omitempty" json:"type,omitempty"`
+	Transports map[string]string `yaml:"transports,omitempty" json:"transports,omitempty"`
+
+	// Remote is how remote servers are configured: native (the default)
+	// writes the URL and headers, mcp-remote runs them through the mcp-remote
+	// stdio proxy for agents that only launch local commands
+	Remote string `yaml:"remote,omitempty" json:"remote,omitempty"`
+
+	// EnvReference is how the agent refers to an environment variable in
+	// env, args and headers, with %s standing for the name, such as
+	// "${env:%s}". Agents without one always get the variables' values.
+	EnvReference string `yaml:"env_reference,omitempty" json:"env_reference,omitempty"`
+
+	// Disabled and AlwaysAllow name fields initialized to false and an empty
+	// list when an entry is created. Users manage them afterwards.
+	Disabled    string `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+	AlwaysAllow string `yaml:"always_allow,omitempty" json:"always_allow,omitempty"`
+
+	// IncludeTools and ExcludeTools name fields listing the only tools the
+	// agent uses of a server and the tools it leaves out, for agents that
+	// filter tools themselves. They receive a server's tools filter when the
+	// agent can apply it; otherwise mcpv applies it when running the server.
+	IncludeTools string `yaml:"include_tools,omitempty" json:"include_tools,omitempty"`
+	ExcludeTools string `yaml:"exclude_tools,omitempty" json:"exclude_tools,omitempty"`
+
+	// Fields are constant fields written into every entry
+	Fields map[string]interface{} `yaml:"fields,omitempty" json:"fields,omitempty"`
+}
+
+// Supported agent configuration file formats
+const (
+	agentFormatJSON = "json"
+	agentFormatYAML = "yaml"
+	agentFormatTOML = "toml"
+)
+
+// Supported layouts of server entries
+const (
+	agentLayoutMap  = "map"
+	agentLayoutList = "list"
+)
+
+// Ways of configuring remote servers
+const (
+	agentRemoteNative    = "native"
+	agentRemoteMCPRemote = "mcp-remote"
+)
+
+// parseAgentDefinitions parses agent definitions in the agents.yaml format
+func parseAgentDefinitions(data []byte, source, sourcePath string) (map[string]*AgentDefinition, error) {
+	definitions := map[string]*AgentDefinition{}
+	if err := mergeAgentDefinitions(definitions, data, source, sourcePath); err != nil {
+		return nil, err
+	}
+	return definitions, nil
+}
+
+// mergeAgentDefinitions overlays definitions in the agents.yaml format onto
+// existing ones. Fields an override sets replace the inherited values.
+func mergeAgentDefinitions(definitions map[string]*AgentDefinition, data []byte, source, sourcePath string) error {
+	var file agentDefinitionsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse agent definitions %s: %w", sourcePath, err)
+	}
+
+	for key, node := range file.Agents {
+		definition, err := overlayAgentDefinition(definitions[key], node.Decode)
+		if err != nil {
+			return fmt.Errorf("failed to parse definition for agent %s in %s: %w", key, sourcePath, err)
+		}
+		if err := definition.finish(key, source, sourcePath); err != nil {
+			return err
+		}
+		definitions[key] = definition
+	}
+
+	return nil
+}
+
+// overlayAgentDefinition applies an override, decoded by decode, onto a copy of base
+func overlayAgentDefinition(base *AgentDefinition, decode func(interface{}) error) (*AgentDefinition, error) {
+	definition := &AgentDefinition{}
+	if base != nil {
+		var err error
+		if definition, err = base.clone(); err != nil {
+			return nil, err
+		}
+	}
+	if err := decode(definition); err != nil {
+		return nil, err
+	}
+	return definition, nil
+}
+
+// clone returns a deep copy of the definition
+func (d *AgentDefinition) clone() (*AgentDefinition, error) {
+	data, err := yaml.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	clone := &AgentDefinition{}
+	if err := yaml.Unmarshal(data, clone); err != nil {
+		return nil, err
+	}
+	clone.Source = d.Source
+	clone.SourcePath = d.SourcePath
+	return clone, nil
+}
+
+// finish fills defaults, records the definition's source and validates it
+func (d *AgentDefinition) finish(key, source, sourcePath string) error {
+	if d.Type == "" {
+		d.Type = key
+	}
+	if d.Name == "" {
+		d.Name = key
+	}
+	d.Source = source
+	d.SourcePath = sourcePath
+
+	if err := d.Config.validate(); err != nil {
+		return fmt.Errorf("invalid definition for agent %s in %s: %w", key, sourcePath, err)
+	}
+	return nil
+}
+
+// loadAgentDefinitions reads the embedded agent definitions and overlays the
+// user's definitions from ~/.config/mcpv/agents.yaml
+func loadAgentDefinitions() (map[string]*AgentDefinition, error) {
+	definitions, err := parseAgentDefinitions([]byte(getEmbeddedAgentsYAML()), AgentSourceEmbedded, "agents.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	userPath, err := userAgentsPath()
+	if err != nil {
+		return definitions, nil
+	}
+	data, err := os.ReadFile(userPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return definitions, nil
+		}
+		return nil, fmt.Errorf("failed to read agent definitions %s: %w", userPath, err)
+	}
+
+	if err := mergeAgentDefinitions(definitions, data, AgentSourceUser, userPath); err != nil {
+		return nil, err
+	}
+	return definitions, nil
+}
+
+// userAgentsPath returns the location of the user's agent definitions
+func userAgentsPath() (string, error) {
+	if xdgConfigHome := os.Getenv("XDG_CONFIG_HOME"); xdgConfigHome != "" {
+		return filepath.Join(xdgConfigHome, "mcpv", "agents.yaml"), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".config", "mcpv", "agents.yaml"), nil
+}
+
+// projectAgentDefinitions overlays the agent definitions of a project's
+// mcpv.json onto the embedded and user definitions
+func (m *Manager) projectAgentDefinitions(agents map[string]*AgentDefinition, configPath string) (map[string]*AgentDefinition, error) {
+	definitions := make(map[string]*AgentDefinition, len(m.agentDefinitions))
+	for key, definition := range m.agentDefinitions {
+		definitions[key] = definition
+	}
+
+	configPath = absPath(configPath)
+	for key, override := range agents {
+		if override == nil {
+			continue
+		}
+		data, err := json.Marshal(override)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read definition for agent %s in %s: %w", key, configPath, err)
+		}
+
+		definition, err := overlayAgentDefinition(definitions[key], func(v interface{}) error {
+			return json.Unmarshal(data, v)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read definition for agent %s in %s: %w", key, configPath, err)
+		}
+		if err := definition.finish(key, AgentSourceProject, configPath); err != nil {
+			return nil, err
+		}
+		definitions[key] = definition
+	}
+
+	return definitions, nil
+}
+
+// definitions returns the agent definitions of the selected project. If its
+// mcpv.json cannot be read, the embedded and user definitions are returned
+// with a warning.
+func (m *Manager) definitions() map[string]*AgentDefinition {
+	project, err := m.projectSettings()
+	if err != nil {
+		if !m.projectWarned {
+			fmt.Fprintf(m.out, "Warning: ignoring the agent definitions in %s: %v\n", m.projectPath, err)
+			m.projectWarned = true
+		}
+		return m.agentDefinitions
+	}
+	return project.agentDefinitions
+}
+
+// AgentTypes returns the types of all defined agents in order
+func (m *Manager) AgentTypes() []string {
+	definitions := m.definitions()
+	types := make([]string, 0, len(definitions))
+	for key := range definitions {
+		types = append(types, key)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// IsAgentType reports whether an agent type is defined
+func (m *Manager) IsAgentType(agentType string) bool {
+	_, ok := m.definitions()[agentType]
+	return ok
+}
+
+// DetectAgents returns the agents whose configuration directory exists, either
+// in the current project or, for agents without project configuration, in the
+// user's home
+func (m *Manager) DetectAgents() []AgentType {
+	definitions := m.definitions()
+	var detected []AgentType
+	for _, agentType := range m.AgentTypes() {
+		path, err := definitions[agentType].configPath(true)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Dir(path)); err == nil {
+			detected = append(detected, AgentType(agentType))
+		}
+	}
+	return detected
+}
+
+// agentDefinition returns the definition of an agent type
+func (m *Manager) agentDefinition(agentType AgentType) (*AgentDefinition, error) {
+	project, err := m.projectSettings()
+	if err != nil {
+		return nil, err
+	}
+	definition, ok := project.agentDefinitions[string(agentType)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported agent type: %s", agentType)
+	}
+	return definition, nil
+}
+
+// configPath resolves the agent's configuration file. Local configuration lives
+// in the current project; agents without a project-level file always use their
+// user-level configuration.
+func (d *AgentDefinition) configPath(useLocal bool) (string, error) {
+	if d.Config.Path != "" && (useLocal || len(d.Config.Paths) == 0) {
+		if useLocal {
+			return d.Config.Path, nil
+		}
+
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		return filepath.Join(homeDir, d.Config.Path), nil
+	}
+
+	var candidates []string
+	for _, path := range d.Config.Paths {
+		expanded, err := expandConfigPath(path)
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, expanded)
+	}
+
+	// Prefer the candidate whose directory exists on this machine
+	for _, candidate := range candidates {
+		if _, err := os.Stat(filepath.Dir(candidate)); err == nil {
+			return candidate, nil
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0], nil
+	}
+
+	return "", fmt.Errorf("no configuration path defined for agent %s", d.Type)
+}
+
+// expandConfigPath expands ~ and %VAR% references in an agent configuration path
+func expandConfigPath(path string) (string, error) {
+	if strings.HasPrefix(path, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(homeDir, path[2:])
+	}
+
+	for {
+		start := strings.Index(path, "%")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(path[start+1:], "%")
+		if end < 0 {
+			break
+		}
+		name := path[start+1 : start+1+end]
+		value := os.Getenv(name)
+		if value == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		path = path[:start] + value + path[start+2+end:]
+	}
+
+	return filepath.FromSlash(path), nil
+}
+
+// validate checks the layout of an agent's configuration file
+func (s *AgentFileSpec) validate() error {
+	switch s.format() {
+	case agentFormatJSON, agentFormatYAML, agentFormatTOML:
+	default:
+		return fmt.Errorf("unsupported config format %q", s.Format)
+	}
+
+	for _, key := range s.rootKey() {
+		if key == "" {
+			return fmt.Errorf("invalid root key %q", s.RootKey)
+		}
+	}
+
+	switch s.layout() {
+	case agentLayoutMap:
+	case agentLayoutList:
+		if s.format() != agentFormatYAML {
+			return fmt.Errorf("list layout is only supported for yaml")
+		}
+	default:
+		return fmt.Errorf("unsupported entry layout %q", s.Layout)
+	}
+
+	switch s.entry().remote() {
+	case agentRemoteNative, agentRemoteMCPRemote:
+	default:
+		return fmt.Errorf("unsupported remote mode %q", s.entry().Remote)
+	}
+	return nil
+}
+
+// layout returns how entries are stored under the root key
+func (s *AgentFileSpec) layout() string {
+	if s.Layout == "" {
+		return agentLayoutMap
+	}
+	return strings.ToLower(s.Layout)
+}
+
+// nameKey returns the field holding the server name in list layouts
+func (s *AgentFileSpec) nameKey() string {
+	if s.NameKey == "" {
+		return "name"
+	}
+	return s.NameKey
+}
+
+// format returns the configuration file format
+func (s *AgentFileSpec) format() string {
+	if s.Format == "" {
+		return agentFormatJSON
+	}
+	return strings.ToLower(s.Format)
+}
+
+// rootKey returns the path of keys leading to the server entries
+func (s *AgentFileSpec) rootKey() []string {
+	if s.RootKey == "" {
+		return []string{agentServersKey}
+	}
+	return strings.Split(s.RootKey, ".")
+}
+
+// entryFields returns the fields mcpv manages in an agent's entry for a
+// server. Fields with a nil value are removed from existing entries. Fields
+// users manage are only included when the entry is being created.
+func (d *AgentDefinition) entryFields(server *MCPServer, create bool) orderedObject {
+	entry := d.Config.entry()
+	var fields orderedObject
+
+	if server.IsRemote() && entry.remote() == agentRemoteMCPRemote {
+		server = mcpRemoteServer(server)
+	}
+
+	if entry.Type != "" {
+		var transport interface{}
+		if value := entry.transport(server.transport()); value != "" {
+			transport = value
+		}
+		fields = append(fields, jsoncField{Key: entry.Type, Value: transport})
+	}
+
+	if server.IsRemote() {
+		var headers interface{}
+		if len(server.Headers) > 0 {
+			headers = server.Headers
+		}
+
+		urlField := entry.urlField(server.transport())
+		for _, name := range entry.urlFields() {
+			var url interface{}
+			if name == urlField {
+				url = server.URL
+			}
+			fields = append(fields, jsoncField{Key: name, Value: url})
+		}
+		fields = append(fields,
+			jsoncField{Key: fieldName(entry.Headers, "headers"), Value: headers},
+			jsoncField{Key: fieldName(entry.Command, "command"), Value: nil},
+			jsoncField{Key: fieldName(entry.Args, "args"), Value: nil},
+			jsoncField{Key: fieldName(entry.Env, "env"), Value: nil},
+		)
+	} else {
+		args := server.Args
+		if args == nil {
+			args = []string{}
+		}
+		var env interface{}
+		if len(server.Env) > 0 {
+			env = server.Env
+		}
+		fields = append(fields,
+			jsoncField{Key: fieldName(entry.Command, "command"), Value: server.Command},
+			jsoncField{Key: fieldName(entry.Args, "args"), Value: args},
+			jsoncField{Key: fieldName(entry.Env, "env"), Value: env},
+		)
+		for _, name := range entry.urlFields() {
+			fields = append(fields, jsoncField{Key: name, Value: nil})
+		}
+		fields = append(fields, jsoncField{Key: fieldName(entry.Headers, "headers"), Value: nil})
+	}
+
+	if create {
+		if entry.Disabled != "" {
+			fields = append(fields, jsoncField{Key: entry.Disabled, Value: false})
+		}
+		if entry.AlwaysAllow != "" {
+			fields = append(fields, jsoncField{Key: entry.AlwaysAllow, Value: []string{}})
+		}
+	}
+
+	var include, exclude interface{}
+	if server.Tools != nil && entry.filtersTools(server.Tools) {
+		if len(server.Tools.Allow) > 0 {
+			include = server.Tools.Allow
+		}
+		if len(server.Tools.Deny) > 0 {
+			exclude = server.Tools.Deny
+		}
+	}
+	if entry.IncludeTools != "" {
+		fields = append(fields, jsoncField{Key: entry.IncludeTools, Value: include})
+	}
+	if entry.ExcludeTools != "" {
+		fields = append(fields, jsoncField{Key: entry.ExcludeTools, Value: exclude})
+	}
+
+	keys := make([]string, 0, len(entry.Fields))
+	for key := range entry.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fields = append(fields, jsoncField{Key: key, Value: entry.Fields[key]})
+	}
+
+	return fields
+}
+
+// filtersTools reports whether the agent can apply a tools filter itself: it
+// has the fields the filter needs and the filter names tools exactly
+func (e *AgentEntrySpec) filtersTools(filter *ToolFilter) bool {
+	if len(filter.Allow) > 0 && e.IncludeTools == "" {
+		return false
+	}
+	if len(filter.Deny) > 0 && e.ExcludeTools == "" {
+		return false
+	}
+	return filter.exact()
+}
+
+// mcpRemoteServer returns a local server that proxies a remote one over stdio
+// with mcp-remote
+func mcpRemoteServer(server *MCPServer) *MCPServer {
+	transport := "http-only"
+	if server.transport() == TransportSSE {
+		transport = "sse-only"
+	}
+	args := []string{"-y", "mcp-remote", server.URL, "--transport", transport}
+
+	names := make([]string, 0, len(server.Headers))
+	for name := range server.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "--header", fmt.Sprintf("%s:%s", name, server.Headers[name]))
+	}
+
+	return &MCPServer{Name: server.Name, Command: "npx", Args: args}
+}
+
+// entry returns the entry field mapping, which may be omitted
+func (s *AgentFileSpec) entry() *AgentEntrySpec {
+	if s.Entry == nil {
+		return &AgentEntrySpec{}
+	}
+	return s.Entry
+}
+
+// transport returns the value an agent expects for an mcpv transport name
+func (e *AgentEntrySpec) transport(name string) string {
+	if value, ok := e.Transports[name]; ok {
+		return value
+	}
+	return name
+}
+
+// remote returns how the agent configures remote servers
+func (e *AgentEntrySpec) remote() string {
+	if e.Remote == "" {
+		return agentRemoteNative
+	}
+	return strings.ToLower(e.Remote)
+}
+
+// urlField returns the field holding the URL of a server using a transport
+func (e *AgentEntrySpec) urlField(transport string) string {
+	if name, ok := e.URLs[transport]; ok {
+		return name
+	}
+	return fieldName(e.URL, "url")
+}
+
+// urlFields returns every field the agent may hold a URL in, in order
+func (e *AgentEntrySpec) urlFields() []string {
+	names := []string{fieldName(e.URL, "url")}
+	transports := make([]string, 0, len(e.URLs))
+	for transport := range e.URLs {
+		transports = append(transports, transport)
+	}
+	sort.Strings(transports)
+	for _, transport := range transports {
+		name := e.URLs[transport]
+		if !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// containsString reports whether a string is in the list
+func containsString(values []string, value string) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldName returns name, or fallback when the agent does not rename the field
+func fieldName(name, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	return name
+}
@@ -0,0 +1,258 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// agentServersKey is the key agent configuration files keep MCP servers under
+const agentServersKey = "mcpServers"
+
+// agentConfigDocument edits the server entries of an agent configuration file.
+// Implementations apply edits to the file's text where they can so settings,
+// comments and formatting outside the touched entries are preserved.
+type agentConfigDocument interface {
+	// entries returns the decoded server entries by name
+	entries() (map[string]interface{}, error)
+	// setEntry sets fields of a server's entry, creating it if needed. Fields
+	// with a nil value are removed; other fields of the entry are kept.
+	setEntry(name string, fields orderedObject) error
+	// removeEntry deletes a server's entry, reporting whether it existed
+	removeEntry(name string) (bool, error)
+	// bytes returns the edited file contents
+	bytes() ([]byte, error)
+}
+
+// agentConfigFile is an agent configuration file loaded for editing
+type agentConfigFile struct {
+	path       string
+	definition *AgentDefinition
+	variables  *projectVariables
+	launcher   *serverLauncher
+	doc        agentConfigDocument
+	original   []byte
+	exists     bool
+	mode       os.FileMode
+	modified   bool
+
+	// filterLauncher runs servers whose tools filter the agent cannot apply
+	filterLauncher *serverLauncher
+
+	// written and removed name the server entries changed in this file
+	written []string
+	removed []string
+}
+
+// loadAgentConfigFile reads an agent configuration file laid out as the agent
+// definition describes. A missing file starts from the definition's template.
+func (m *Manager) loadAgentConfigFile(path string, definition *AgentDefinition) (*agentConfigFile, error) {
+	project, err := m.projectSettings()
+	if err != nil {
+		return nil, err
+	}
+	file := &agentConfigFile{path: path, definition: definition, variables: project.variables, launcher: project.launcher, mode: 0644}
+	if launcher, err := m.newServerLauncher(LauncherExec); err == nil {
+		file.filterLauncher = launcher
+	}
+	if m.userLevel(path, definition) {
+		// Agents start the servers of these files outside the project, where
+		// mcpv would not find its mcpv.json
+		file.launcher = file.launcher.withConfig(m.projectPath)
+		file.filterLauncher = file.filterLauncher.withConfig(m.projectPath)
+	}
+
+	data, err := m.readFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read agent config %s: %w", path, err)
+	}
+	if err == nil {
+		file.original = data
+		file.exists = true
+		if info, err := os.Stat(path); err == nil {
+			file.mode = info.Mode().Perm()
+		}
+	} else {
+		data = []byte(definition.Config.Template)
+	}
+
+	root := definition.Config.rootKey()
+	switch definition.Config.format() {
+	case agentFormatYAML:
+		nameKey := ""
+		if definition.Config.layout() == agentLayoutList {
+			nameKey = definition.Config.nameKey()
+		}
+		file.doc, err = newYAMLAgentDocument(data, root, nameKey)
+	case agentFormatTOML:
+		file.doc, err = newTOMLAgentDocument(data, root)
+	default:
+		file.doc, err = newJSONAgentDocument(data, root)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse agent config %s: %w", path, err)
+	}
+
+	return file, nil
+}
+
+// userLevel reports whether an agent configuration file is the agent's
+// user-level configuration or lies outside the selected project
+func (m *Manager) userLevel(path string, definition *AgentDefinition) bool {
+	if m.projectPath == "" {
+		return false
+	}
+	path = absPath(path)
+	if global, err := definition.configPath(false); err == nil && absPath(global) == path {
+		return true
+	}
+	rel, err := filepath.Rel(filepath.Dir(m.projectPath), path)
+	return err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// servers returns the MCP server entries of the configuration
+func (f *agentConfigFile) servers() (map[string]interface{}, error) {
+	servers, err := f.doc.entries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read servers from agent config %s: %w", f.path, err)
+	}
+	return servers, nil
+}
+
+// setServer adds or updates the entry for an MCP server. Fields of an existing
+// entry that mcpv does not manage are kept.
+func (f *agentConfigFile) setServer(server *MCPServer) error {
+	if f.launcher != nil && f.launcher.kind == LauncherServe {
+		server = f.launcher.gateway()
+	}
+
+	servers, err := f.servers()
+	if err != nil {
+		return err
+	}
+	_, exists := servers[server.Name]
+
+	if err := f.doc.setEntry(server.Name, f.entryFields(server, !exists)); err != nil {
+		return fmt.Errorf("failed to update agent config %s: %w", f.path, err)
+	}
+
+	f.modified = true
+	f.written = append(f.written, server.Name)
+	return nil
+}
+
+// entryFields returns the fields of a server's entry, with the variables its
+// settings reference resolved or, where the agent supports it, referenced.
+// Servers run through the project's launcher when it has one, and through
+// "mcpv exec" when they have a tools filter the agent cannot apply itself.
+func (f *agentConfigFile) entryFields(server *MCPServer, create bool) orderedObject {
+	if launcher := f.serverLauncher(server); launcher != nil {
+		return f.definition.entryFields(launcher.server(server), create)
+	}
+	if f.variables != nil {
+		server = f.variables.server(server, f.definition.Config.entry().EnvReference)
+	}
+	return f.definition.entryFields(server, create)
+}
+
+// serverLauncher returns the launcher a server's entry runs through, if any
+func (f *agentConfigFile) serverLauncher(server *MCPServer) *serverLauncher {
+	if f.launcher != nil && f.launcher.launches(server) {
+		return f.launcher
+	}
+	if server.Tools != nil && !f.definition.Config.entry().filtersTools(server.Tools) {
+		return f.filterLauncher
+	}
+	return nil
+}
+
+// removeServer deletes the entry for an MCP server, reporting whether it existed
+func (f *agentConfigFile) removeServer(name string) (bool, error) {
+	removed, err := f.doc.removeEntry(name)
+	if err != nil {
+		return false, fmt.Errorf("failed to update agent config %s: %w", f.path, err)
+	}
+	if removed {
+		f.modified = true
+		f.removed = append(f.removed, name)
+	}
+	return removed, nil
+}
+
+// render returns the edited configuration
+func (f *agentConfigFile) render() ([]byte, error) {
+	data, err := f.doc.bytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to render agent config %s: %w", f.path, err)
+	}
+	return data, nil
+}
+
+// jsonAgentDocument is an agent configuration in JSON, which may contain
+// comments and trailing commas
+type jsonAgentDocument struct {
+	editor *jsoncEditor
+	root   []string
+}
+
+func newJSONAgentDocument(data []byte, root []string) (*jsonAgentDocument, error) {
+	editor, err := newJSONCEditor(data)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonAgentDocument{editor: editor, root: root}, nil
+}
+
+func (d *jsonAgentDocument) entries() (map[string]interface{}, error) {
+	node := d.editor.lookup(d.root)
+	if node == nil || node.kind != jsoncObject {
+		return map[string]interface{}{}, nil
+	}
+
+	entries, err := node.decode(d.editor.src)
+	if err != nil {
+		return nil, err
+	}
+	return entries.(map[string]interface{}), nil
+}
+
+func (d *jsonAgentDocument) setEntry(name string, fields orderedObject) error {
+	if _, err := d.editor.ensureObject(d.root); err != nil {
+		return err
+	}
+	return d.editor.setObjectFields(d.entryPath(name), fields)
+}
+
+func (d *jsonAgentDocument) removeEntry(name string) (bool, error) {
+	return d.editor.removeMember(d.root, name)
+}
+
+func (d *jsonAgentDocument) bytes() ([]byte, error) {
+	return d.editor.src, nil
+}
+
+// entryPath returns the path of keys leading to a server's entry
+func (d *jsonAgentDocument) entryPath(name string) []string {
+	path := make([]string, 0, len(d.root)+1)
+	path = append(path, d.root...)
+	return append(path, name)
+}
+
+// addServerToAgentConfig writes an MCP server into an agent's configuration file
+func (m *Manager) addServerToAgentConfig(agentType AgentType, server *MCPServer, useLocal bool) error {
+	tx := m.newAgentConfigTransaction()
+	if err := tx.addServer(agentType, server, useLocal); err != nil {
+		return err
+	}
+	return tx.commit(fmt.Sprintf("add %s to %s", server.Name, agentType))
+}
+
+// removeServerFromAgentConfig deletes an MCP server from an agent's configuration file
+func (m *Manager) removeServerFromAgentConfig(agentType AgentType, serverName string, useLocal bool) error {
+	tx := m.newAgentConfigTransaction()
+	if _, err := tx.removeServer(agentType, serverName, useLocal); err != nil {
+		return err
+	}
+	return tx.commit(fmt.Sprintf("remove %s from %s", serverName, agentType))
+}
@@ -0,0 +1,482 @@
+package manager
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// agentFileCase edits testdata/agentfile/<name>.before.<ext> with the
+// definition of an agent and compares the result with <name>.after.<ext>
+type agentFileCase struct {
+	name   string
+	agent  string
+	set    []*MCPServer
+	remove []string
+	// add is set after the removals
+	add []*MCPServer
+}
+
+// testAgentsYAML defines agents for layouts no embedded agent uses
+const testAgentsYAML = `
+agents:
+  yaml-map:
+    config:
+      path: "config.yaml"
+      format: "yaml"
+      root_key: "servers"
+`
+
+func runAgentFileCases(t *testing.T, cases []agentFileCase) {
+	t.Helper()
+
+	definitions, err := parseAgentDefinitions([]byte(getEmbeddedAgentsYAML()), AgentSourceEmbedded, "agents.yaml")
+	if err != nil {
+		t.Fatalf("failed to parse embedded agent definitions: %v", err)
+	}
+	if err := mergeAgentDefinitions(definitions, []byte(testAgentsYAML), AgentSourceUser, "test"); err != nil {
+		t.Fatalf("failed to parse test agent definitions: %v", err)
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			definition, ok := definitions[tc.agent]
+			if !ok {
+				t.Fatalf("unknown agent %s", tc.agent)
+			}
+			ext := "." + definition.Config.format()
+			before := filepath.Join("testdata", "agentfile", tc.name+".before"+ext)
+			after := filepath.Join("testdata", "agentfile", tc.name+".after"+ext)
+
+			data, err := os.ReadFile(before)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "config"+ext)
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			m := &Manager{dataDir: t.TempDir(), out: io.Discard, project: &projectSettings{}}
+			file, err := m.loadAgentConfigFile(path, definition)
+			if err != nil {
+				t.Fatalf("failed to load %s: %v", before, err)
+			}
+			for _, server := range tc.set {
+				if err := file.setServer(server); err != nil {
+					t.Fatalf("failed to set %s: %v", server.Name, err)
+				}
+			}
+			for _, name := range tc.remove {
+				removed, err := file.removeServer(name)
+				if err != nil {
+					t.Fatalf("failed to remove %s: %v", name, err)
+				}
+				if !removed {
+					t.Fatalf("%s was not found in %s", name, before)
+				}
+			}
+			for _, server := range tc.add {
+				if err := file.setServer(server); err != nil {
+					t.Fatalf("failed to add %s: %v", server.Name, err)
+				}
+			}
+
+			got, err := file.render()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *updateGolden {
+				if err := os.WriteFile(after, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s does not match %s\n--- got\n%s\n--- want\n%s", before, after, got, want)
+			}
+
+			// The edited file must read back with the servers that were set
+			if err := os.WriteFile(path, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+			reloaded, err := m.loadAgentConfigFile(path, definition)
+			if err != nil {
+				t.Fatalf("failed to reload %s: %v", after, err)
+			}
+			servers, err := reloaded.servers()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, server := range append(tc.set, tc.add...) {
+				if _, ok := servers[server.Name]; !ok {
+					t.Errorf("%s is missing from the edited file", server.Name)
+				}
+			}
+			for _, name := range tc.remove {
+				if _, ok := servers[name]; ok {
+					t.Errorf("%s is still in the edited file", name)
+				}
+			}
+		})
+	}
+}
+
+func TestAgentFileFormats(t *testing.T) {
+	runAgentFileCases(t, []agentFileCase{
+		{
+			// Comments, trailing commas, key order and unknown fields of the
+			// file and of the edited entry are kept
+			name:  "json-comments",
+			agent: "cursor",
+			set: []*MCPServer{
+				{Name: "github", Command: "node", Args: []string{"dist/index.js", "--stdio"}},
+				{Name: "search", Command: "python3", Args: []string{"server.py"}, Env: map[string]string{"API_KEY": "k"}},
+			},
+			remove: []string{"legacy"},
+		},
+		{
+			name:   "json-remove-trailing-comment",
+			agent:  "cursor",
+			remove: []string{"first", "last"},
+		},
+		{
+			name:   "json-remove-only",
+			agent:  "cursor",
+			remove: []string{"only"},
+		},
+		{
+			// Comments left in an emptied object stay when an entry is added
+			name:   "json-remove-then-add",
+			agent:  "cursor",
+			remove: []string{"old"},
+			add: []*MCPServer{
+				{Name: "new", Command: "node", Args: []string{"index.js"}},
+			},
+		},
+		{
+			name:   "json-remove-block-comment",
+			agent:  "cursor",
+			remove: []string{"a", "c"},
+		},
+		{
+			name:  "json-compact",
+			agent: "cursor",
+			set: []*MCPServer{
+				{Name: "b", Command: "b"},
+			},
+			remove: []string{"a"},
+		},
+		{
+			name:  "json-empty",
+			agent: "cursor",
+			set: []*MCPServer{
+				{Name: "a", Command: "a", Args: []string{"--stdio"}},
+			},
+		},
+		{
+			name:  "toml-comments",
+			agent: "codex",
+			set: []*MCPServer{
+				{Name: "github", Command: "node", Args: []string{"dist/index.js"}},
+				{Name: "search", Command: "python3", Args: []string{"server.py"}},
+			},
+			remove: []string{"legacy"},
+		},
+		{
+			// Servers defined with dotted keys are edited in place
+			name:  "toml-dotted",
+			agent: "codex",
+			set: []*MCPServer{
+				{Name: "a", Command: "x", Args: []string{"two"}},
+				{Name: "c", Command: "z"},
+			},
+			remove: []string{"b"},
+		},
+		{
+			name:  "toml-root-dotted",
+			agent: "codex",
+			set: []*MCPServer{
+				{Name: "a", Command: "x2", Env: map[string]string{"TOKEN": "u"}},
+				{Name: "b", Command: "y"},
+			},
+		},
+		{
+			name:  "toml-inline",
+			agent: "codex",
+			set: []*MCPServer{
+				{Name: "a", Command: "x", Args: []string{"--stdio"}},
+			},
+		},
+		{
+			// Entries of an inline table cannot be given tables of their own
+			name:  "toml-parent-inline",
+			agent: "codex",
+			set: []*MCPServer{
+				{Name: "a", Command: "x", Args: []string{"--stdio"}},
+				{Name: "b", Command: "y"},
+			},
+			remove: []string{"legacy"},
+		},
+		{
+			name:  "toml-subtable",
+			agent: "codex",
+			set: []*MCPServer{
+				{Name: "a", Command: "x", Env: map[string]string{"TOKEN": "u"}},
+			},
+		},
+		{
+			name:  "yaml-list",
+			agent: "continue",
+			set: []*MCPServer{
+				{Name: "github", Command: "node", Args: []string{"dist/index.js"}},
+				{Name: "search", Command: "python3", Args: []string{"server.py"}},
+			},
+			remove: []string{"legacy"},
+		},
+		{
+			// Indentation, blank lines and comments outside the edited
+			// entries are kept
+			name:  "yaml-indent",
+			agent: "continue",
+			set: []*MCPServer{
+				{Name: "github", Command: "node", Args: []string{"dist/index.js"}},
+				{Name: "search", Command: "python3", Args: []string{"server.py"}},
+			},
+			remove: []string{"legacy"},
+		},
+		{
+			name:  "yaml-comments-only",
+			agent: "continue",
+			set: []*MCPServer{
+				{Name: "github", Command: "node"},
+			},
+		},
+		{
+			name:  "yaml-flow-list",
+			agent: "continue",
+			set: []*MCPServer{
+				{Name: "github", Command: "node"},
+			},
+		},
+		{
+			// Lists whose dashes sit at their key's column
+			name:  "yaml-zero-indent",
+			agent: "continue",
+			set: []*MCPServer{
+				{Name: "b", Command: "y", Args: []string{"--stdio"}},
+				{Name: "c", Command: "z"},
+			},
+			remove: []string{"a"},
+		},
+		{
+			name:   "yaml-zero-indent-remove-last",
+			agent:  "continue",
+			remove: []string{"z"},
+		},
+		{
+			name:   "yaml-remove-last",
+			agent:  "continue",
+			remove: []string{"only"},
+		},
+		{
+			name:  "yaml-map",
+			agent: "yaml-map",
+			set: []*MCPServer{
+				{Name: "github", Command: "node", Args: []string{"dist/index.js"}},
+				{Name: "search", Command: "python3"},
+			},
+			remove: []string{"legacy"},
+		},
+		{
+			// Merge keys are written back as they were, and values the
+			// entry gets from the anchor are not repeated
+			name:  "yaml-merge",
+			agent: "yaml-map",
+			set: []*MCPServer{
+				{Name: "github", Command: "node", Args: []string{"dist/index.js"}},
+			},
+		},
+	})
+}
+
+// TestAgentFileAgents checks the entry layout of each embedded agent that
+// differs from the default
+func TestAgentFileAgents(t *testing.T) {
+	runAgentFileCases(t, []agentFileCase{
+		{
+			// Remote servers run through mcp-remote
+			name:  "agent-claude",
+			agent: "claude",
+			set: []*MCPServer{
+				{Name: "github", Command: "node", Args: []string{"dist/index.js"}},
+				{Name: "docs", URL: "https://example.com/mcp", Headers: map[string]string{"Authorization": "Bearer t"}},
+				{Name: "events", URL: "https://example.com/sse", Transport: TransportSSE},
+			},
+			remove: []string{"legacy"},
+		},
+		{
+			// New entries start enabled with nothing always allowed; the
+			// user's settings of existing entries are kept
+			name:  "agent-roocode",
+			agent: "roocode",
+			set: []*MCPServer{
+				{Name: "github", Command: "node", Args: []string{"dist/index.js"}},
+				{Name: "search", Command: "python3", Args: []string{"server.py"}},
+				{Name: "docs", URL: "https://example.com/mcp"},
+			},
+			remove: []string{"legacy"},
+		},
+		{
+			// Remote servers use serverUrl
+			name:  "agent-windsurf",
+			agent: "windsurf",
+			set: []*MCPServer{
+				{Name: "github", Command: "node", Args: []string{"dist/index.js"}},
+				{Name: "docs", URL: "https://example.com/mcp", Headers: map[string]string{"Authorization": "Bearer t"}},
+			},
+			remove: []string{"legacy"},
+		},
+		{
+			name:  "agent-aider",
+			agent: "aider",
+			set: []*MCPServer{
+				{Name: "github", Command: "node", Args: []string{"dist/index.js"}, Env: map[string]string{"TOKEN": "t"}},
+				{Name: "docs", URL: "https://example.com/mcp"},
+			},
+			remove: []string{"legacy"},
+		},
+		{
+			// Only remote servers name their transport
+			name:  "agent-claude_code",
+			agent: "claude_code",
+			set: []*MCPServer{
+				{Name: "github", Command: "node", Args: []string{"dist/index.js"}},
+				{Name: "docs", URL: "https://example.com/mcp"},
+				{Name: "events", URL: "https://example.com/sse", Transport: TransportSSE},
+			},
+			remove: []string{"legacy"},
+		},
+		{
+			// Servers live under servers with the transport in type
+			name:  "agent-vscode",
+			agent: "vscode",
+			set: []*MCPServer{
+				{Name: "github", Command: "node", Args: []string{"dist/index.js"}, Env: map[string]string{"TOKEN": "t"}},
+				{Name: "docs", URL: "https://example.com/mcp", Headers: map[string]string{"Authorization": "Bearer t"}},
+			},
+			remove: []string{"legacy"},
+		},
+		{
+			// Servers live under context_servers and are marked as custom
+			name:  "agent-zed",
+			agent: "zed",
+			set: []*MCPServer{
+				{Name: "github", Command: "node", Args: []string{"dist/index.js"}},
+			},
+			remove: []string{"legacy"},
+		},
+		{
+			// Servers are a list of entries named by their name field
+			name:  "agent-continue",
+			agent: "continue",
+			set: []*MCPServer{
+				{Name: "github", Command: "node", Args: []string{"dist/index.js"}},
+				{Name: "docs", URL: "https://example.com/mcp"},
+			},
+			remove: []string{"legacy"},
+		},
+		{
+			// Remote servers run through mcp-remote and tools filters are
+			// written as enabled_tools and disabled_tools
+			name:  "agent-codex",
+			agent: "codex",
+			set: []*MCPServer{
+				{Name: "github", Command: "node", Args: []string{"dist/index.js"}, Tools: &ToolFilter{Allow: []string{"search_issues"}}},
+				{Name: "docs", URL: "https://example.com/mcp", Headers: map[string]string{"Authorization": "Bearer t"}},
+			},
+			remove: []string{"legacy"},
+		},
+		{
+			// Streamable HTTP servers use httpUrl, SSE servers url, and
+			// tools filters are written as includeTools and excludeTools
+			name:  "agent-gemini",
+			agent: "gemini",
+			set: []*MCPServer{
+				{Name: "docs", URL: "https://example.com/mcp", Tools: &ToolFilter{Allow: []string{"search"}, Deny: []string{"delete"}}},
+				{Name: "events", URL: "https://example.com/sse", Transport: TransportSSE},
+			},
+			remove: []string{"legacy"},
+		},
+	})
+}
+
+func TestEnsureObjectKeepsOtherValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    string
+		wantErr string
+	}{
+		{"missing", `{"other": 1}`, `{"other": 1, "mcpServers": {}}`, ""},
+		{"null", `{"mcpServers": null}`, `{"mcpServers": {}}`, ""},
+		{"object", `{"mcpServers": {"a": {}}}`, `{"mcpServers": {"a": {}}}`, ""},
+		{"array", `{"mcpServers": []}`, "", "mcpServers is not an object"},
+		{"string", `{"mcpServers": "none"}`, "", "mcpServers is not an object"},
+		{"number", `{"mcpServers": 0}`, "", "mcpServers is not an object"},
+		{"false", `{"mcpServers": false}`, "", "mcpServers is not an object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			editor, err := newJSONCEditor([]byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = editor.ensureObject([]string{"mcpServers"})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				if string(editor.src) != tt.src {
+					t.Errorf("the document was changed to %s", editor.src)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(string(editor.src)); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContinueWorkspaceConfig(t *testing.T) {
+	m, project := newTestManager(t)
+	if err := m.AddServerToAgent("continue", &MCPServer{Name: "github", Command: "node"}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(project, ".continue", "mcpServers", "mcpv.yaml"))
+	if err != nil {
+		t.Fatalf("the server was not written to the workspace: %v", err)
+	}
+	want := "name: mcpv\nversion: 0.0.1\nschema: v1\nmcpServers:\n  - name: github\n    command: node\n    args: []\n"
+	if string(data) != want {
+		t.Errorf("got\n%s\nwant\n%s", data, want)
+	}
+	if _, err := os.Stat(filepath.Join(os.Getenv("HOME"), ".continue", "config.yaml")); !os.IsNotExist(err) {
+		t.Errorf("a project install wrote the user-level Continue config: %v", err)
+	}
+}
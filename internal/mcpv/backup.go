@@ -0,0 +1,194 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// backupManifestFileName describes the files saved in a backup directory
+	backupManifestFileName = "manifest.json"
+
+	// maxBackups is the number of agent configuration backups kept
+	maxBackups = 20
+)
+
+// Backup is a snapshot of agent configuration files taken before they were modified
+type Backup struct {
+	ID          string        `json:"id" yaml:"id"`
+	Created     time.Time     `json:"created" yaml:"created"`
+	Description string        `json:"description,omitempty" yaml:"description,omitempty"`
+	Files       []BackupEntry `json:"files" yaml:"files"`
+}
+
+// BackupEntry records the state of one file in a backup. Files that did not
+// exist before the change are removed again on restore.
+type BackupEntry struct {
+	Path    string      `json:"path" yaml:"path"`
+	Existed bool        `json:"existed" yaml:"existed"`
+	Mode    os.FileMode `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Copy    string      `json:"copy,omitempty" yaml:"copy,omitempty"`
+}
+
+// getStateDir returns the XDG_STATE_HOME directory or default
+func getStateDir() (string, error) {
+	if xdgStateHome := os.Getenv("XDG_STATE_HOME"); xdgStateHome != "" {
+		return filepath.Join(xdgStateHome, "mcpv"), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homeDir, ".local", "state", "mcpv"), nil
+}
+
+// backupsDir returns the directory agent configuration backups are stored in
+func (m *Manager) backupsDir() string {
+	return filepath.Join(m.stateDir, "backups")
+}
+
+// createBackup saves the current contents of agent configuration files
+func (m *Manager) createBackup(description string, files []*agentConfigFile) (*Backup, error) {
+	now := time.Now().UTC()
+	backup := &Backup{
+		ID:          now.Format("20060102T150405.000000000Z"),
+		Created:     now,
+		Description: description,
+	}
+
+	dir := filepath.Join(m.backupsDir(), backup.ID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	for i, file := range files {
+		entry := BackupEntry{Path: absPath(file.path), Existed: file.exists, Mode: file.mode}
+		if file.exists {
+			entry.Copy = fmt.Sprintf("%d%s", i, filepath.Ext(file.path))
+			if err := os.WriteFile(filepath.Join(dir, entry.Copy), file.original, 0600); err != nil {
+				os.RemoveAll(dir)
+				return nil, err
+			}
+		}
+		backup.Files = append(backup.Files, entry)
+	}
+
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, backupManifestFileName), data, 0600); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	return backup, nil
+}
+
+// ListBackups returns the agent configuration backups, newest first
+func (m *Manager) ListBackups() ([]*Backup, error) {
+	entries, err := os.ReadDir(m.backupsDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Backup{}, nil
+		}
+		return nil, err
+	}
+
+	backups := []*Backup{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(m.backupsDir(), entry.Name(), backupManifestFileName))
+		if err != nil {
+			continue
+		}
+
+		var backup Backup
+		if err := json.Unmarshal(data, &backup); err != nil {
+			continue
+		}
+		backups = append(backups, &backup)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ID > backups[j].ID
+	})
+
+	return backups, nil
+}
+
+// RestoreLatestBackup restores the agent configuration files saved by the most
+// recent backup and then discards it, so repeated restores step further back
+func (m *Manager) RestoreLatestBackup() (*Backup, error) {
+	backups, err := m.ListBackups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	if len(backups) == 0 {
+		return nil, fmt.Errorf("no agent configuration backups found")
+	}
+
+	backup := backups[0]
+	dir := filepath.Join(m.backupsDir(), backup.ID)
+
+	if m.plan != nil {
+		for _, entry := range backup.Files {
+			m.plan.addStep("restore %s", entry.Path)
+		}
+		return backup, nil
+	}
+
+	for _, entry := range backup.Files {
+		if !entry.Existed {
+			if err := os.Remove(entry.Path); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+			}
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Copy))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup of %s: %w", entry.Path, err)
+		}
+
+		mode := entry.Mode
+		if mode == 0 {
+			mode = 0644
+		}
+		if err := writeFileAtomic(entry.Path, data, mode); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", entry.Path, err)
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to discard restored backup: %w", err)
+	}
+
+	return backup, nil
+}
+
+// pruneBackups deletes all but the newest maxBackups backups
+func (m *Manager) pruneBackups() error {
+	backups, err := m.ListBackups()
+	if err != nil {
+		return err
+	}
+
+	for _, backup := range backups[min(len(backups), maxBackups):] {
+		if err := os.RemoveAll(filepath.Join(m.backupsDir(), backup.ID)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPruneBackupsKeepsNewest(t *testing.T) {
+	m, _ := newTestManager(t)
+
+	var ids []string
+	for i := 0; i < maxBackups+3; i++ {
+		backup, err := m.createBackup("change", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, backup.ID)
+	}
+
+	if err := m.pruneBackups(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := m.ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != maxBackups {
+		t.Fatalf("expected %d backups after pruning, got %d", maxBackups, len(backups))
+	}
+	for i, backup := range backups {
+		if want := ids[len(ids)-1-i]; backup.ID != want {
+			t.Errorf("backup %d is %s, want %s", i, backup.ID, want)
+		}
+	}
+}
+
+func TestRestoreLatestBackup(t *testing.T) {
+	m, project := newTestManager(t)
+
+	original := []byte("{\n  \"mcpServers\": {}\n}\n")
+	existing := filepath.Join(project, "existing", "mcp.json")
+	if err := os.MkdirAll(filepath.Dir(existing), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(existing, original, 0600); err != nil {
+		t.Fatal(err)
+	}
+	created := filepath.Join(project, "created", "mcp.json")
+
+	tx := m.newAgentConfigTransaction()
+	stageServer(t, tx, existing, "github")
+	stageServer(t, tx, created, "github")
+	if err := tx.commit("add github"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(existing); string(data) == string(original) {
+		t.Fatalf("%s was not changed by the commit", existing)
+	}
+
+	backup, err := m.RestoreLatestBackup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup.Description != "add github" {
+		t.Errorf("restored backup %q, want the one of the commit", backup.Description)
+	}
+
+	data, err := os.ReadFile(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(original) {
+		t.Errorf("%s was not restored:\n%s", existing, data)
+	}
+	if info, err := os.Stat(existing); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("the mode of %s was not restored: %v %v", existing, info, err)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("%s did not exist before the commit and was not removed: %v", created, err)
+	}
+
+	// The restored backup is discarded
+	if backups, err := m.ListBackups(); err != nil || len(backups) != 0 {
+		t.Errorf("expected no backups left, got %d (%v)", len(backups), err)
+	}
+}
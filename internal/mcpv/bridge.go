@@ -0,0 +1,788 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/socialviolation/mcpv/internal/mcp"
+)
+
+// maxBridgeMessageSize bounds the size of a message a client may POST
+const maxBridgeMessageSize = 4 << 20
+
+// DefaultBridgeIdleTimeout is how long a client session may go without
+// requests before the bridge ends it
+const DefaultBridgeIdleTimeout = 30 * time.Minute
+
+// bridgeStreamBuffer is how many messages are queued for a client's event
+// stream before further ones are dropped
+const bridgeStreamBuffer = 64
+
+// bridgeBroadcasts are the notifications of a shared server passed on to
+// every client
+var bridgeBroadcasts = map[string]bool{
+	"notifications/tools/list_changed":     true,
+	"notifications/prompts/list_changed":   true,
+	"notifications/resources/list_changed": true,
+	"notifications/message":                true,
+}
+
+// Bridge exposes an installed stdio server over the MCP Streamable HTTP
+// transport. Each client session gets its own server process, or with shared
+// set, all sessions use one process that the bridge initializes itself.
+// Requests are passed on under IDs of the bridge's own, so clients of a
+// shared process cannot see or answer each other's messages. The shared
+// process is restarted as the server's restart policy says. Sessions that
+// go without requests for the idle timeout are ended.
+type Bridge struct {
+	manager *Manager
+	server  *MCPServer
+	shared  bool
+	timeout time.Duration
+	idle    time.Duration
+	log     io.Writer
+	nextID  atomic.Int64
+
+	// supervisor restarts the shared process, if any; exited is closed when
+	// it stops for good, and done when the bridge is closed
+	supervisor *supervisor
+	exited     chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+
+	// draining is closed to end the event streams of all sessions
+	draining  chan struct{}
+	drainOnce sync.Once
+
+	mu       sync.Mutex
+	process  *bridgeProcess
+	sessions map[string]*bridgeSession
+}
+
+// bridgeSession is a client session of a bridge
+type bridgeSession struct {
+	id string
+	// process is the session's own process; nil with a shared process
+	process *bridgeProcess
+
+	mu     sync.Mutex
+	stream chan *mcp.Message
+	// timer ends the session once it is idle; nil without an idle timeout
+	timer   *time.Timer
+	done    chan struct{}
+	endOnce sync.Once
+}
+
+// bridgeProcess is a server process of a bridge
+type bridgeProcess struct {
+	bridge *Bridge
+	stdio  *mcp.StdioTransport
+	// owner is the session the process serves; nil for the shared process
+	owner *bridgeSession
+	// supervisor logs a session's process; nil for the shared process
+	supervisor *supervisor
+	// initialized is the result of the shared process's initialize
+	initialized json.RawMessage
+
+	mu      sync.Mutex
+	pending map[string]*bridgeRequest
+}
+
+// bridgeRequest is a client request waiting for the server's response
+type bridgeRequest struct {
+	// session made the request; nil for the bridge's own requests
+	session *bridgeSession
+	id      json.RawMessage
+	reply   chan *mcp.Message
+}
+
+// NewBridge resolves an installed server to expose over HTTP as 'mcpv run'
+// would. With shared set, the server is started and initialized right away,
+// waiting at most timeout. Sessions without requests for idle are ended; an
+// idle of 0 keeps them until the client ends them. Servers' standard error is
+// written to log and to the server's log file.
+func (m *Manager) NewBridge(name, version, configPath string, shared bool, timeout, idle time.Duration, log io.Writer) (*Bridge, error) {
+	server, err := m.ResolveServer(name, version, configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bridge{
+		manager:  m,
+		server:   server,
+		shared:   shared,
+		timeout:  timeout,
+		idle:     idle,
+		log:      log,
+		exited:   make(chan struct{}),
+		done:     make(chan struct{}),
+		draining: make(chan struct{}),
+		sessions: map[string]*bridgeSession{},
+	}
+	if !shared {
+		return b, nil
+	}
+
+	b.supervisor = m.newSupervisor(server, "bridge", true, log)
+	if b.process, err = b.startShared(); err != nil {
+		b.supervisor.close()
+		return nil, err
+	}
+	b.supervisor.started(b.process.stdio)
+	go b.supervise()
+	return b, nil
+}
+
+// startShared starts and initializes the shared process
+func (b *Bridge) startShared() (*bridgeProcess, error) {
+	process, err := b.startProcess(nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := process.initialize(b.timeout); err != nil {
+		process.close()
+		return nil, fmt.Errorf("failed to initialize %s@%s: %w", b.server.Name, b.server.Version, err)
+	}
+	return process, nil
+}
+
+// supervise restarts the shared process as the server's restart policy says,
+// telling clients the server's tools, prompts and resources may have
+// changed, and closes exited once it gives up
+func (b *Bridge) supervise() {
+	start := func() (*mcp.StdioTransport, error) {
+		process, err := b.startShared()
+		if err != nil {
+			return nil, err
+		}
+		b.mu.Lock()
+		b.process = process
+		b.mu.Unlock()
+		for _, method := range []string{"notifications/tools/list_changed", "notifications/prompts/list_changed", "notifications/resources/list_changed"} {
+			b.broadcast(&mcp.Message{JSONRPC: "2.0", Method: method})
+		}
+		return process.stdio, nil
+	}
+	b.supervisor.supervise(b.sharedProcess().stdio, start, b.done)
+
+	select {
+	case <-b.done:
+	default:
+		close(b.exited)
+	}
+}
+
+// Server returns the server the bridge exposes
+func (b *Bridge) Server() *MCPServer {
+	return b.server
+}
+
+// Exited is closed if the shared process exits and is not restarted
+func (b *Bridge) Exited() <-chan struct{} {
+	return b.exited
+}
+
+// CloseStreams ends the event streams of all sessions, which otherwise stay
+// open until their session ends, so that an HTTP server can shut down while
+// requests in progress are still answered
+func (b *Bridge) CloseStreams() {
+	b.drainOnce.Do(func() { close(b.draining) })
+}
+
+// Close ends every session and stops the server processes
+func (b *Bridge) Close() {
+	b.closeOnce.Do(func() { close(b.done) })
+	b.mu.Lock()
+	processes := []*bridgeProcess{}
+	if b.process != nil {
+		processes = append(processes, b.process)
+	}
+	for _, session := range b.sessions {
+		session.end()
+		if session.process != nil {
+			processes = append(processes, session.process)
+		}
+	}
+	b.sessions = map[string]*bridgeSession{}
+	b.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, process := range processes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			process.close()
+		}()
+	}
+	wg.Wait()
+	if b.supervisor != nil {
+		b.supervisor.close()
+	}
+}
+
+// ServeHTTP implements the Streamable HTTP transport: clients POST messages,
+// GET an event stream of messages the server sends on its own, and DELETE
+// their session when they are done
+func (b *Bridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !allowedOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		b.post(w, r)
+	case http.MethodGet:
+		b.stream(w, r)
+	case http.MethodDelete:
+		session, status := b.session(r)
+		if session == nil {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		b.endSession(session)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// post passes a message from a client to its server, answering a request
+// with the server's response
+func (b *Bridge) post(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBridgeMessageSize))
+	var msg mcp.Message
+	if err == nil {
+		err = json.Unmarshal(body, &msg)
+	}
+	if err != nil || msg.JSONRPC == "" {
+		writeBridgeMessage(w, http.StatusBadRequest, mcp.NewErrorResponse(nil, mcp.CodeParseError, "request body is not a JSON-RPC message"))
+		return
+	}
+
+	if msg.IsRequest() && msg.Method == "initialize" {
+		b.initialize(w, r, &msg)
+		return
+	}
+
+	session, status := b.session(r)
+	if session == nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	process := b.processOf(session)
+	if !msg.IsRequest() {
+		process.forward(session, &msg)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	response, err := process.call(r.Context(), session, &msg)
+	if err != nil {
+		if r.Context().Err() == nil {
+			writeBridgeMessage(w, http.StatusOK, mcp.NewErrorResponse(msg.ID, mcp.CodeInternalError, "%v", err))
+		}
+		return
+	}
+	writeBridgeMessage(w, http.StatusOK, response)
+}
+
+// initialize opens a session: a new process initialized by the client, or
+// the shared process, whose initialize result the client gets
+func (b *Bridge) initialize(w http.ResponseWriter, r *http.Request, msg *mcp.Message) {
+	session := &bridgeSession{id: newSessionID(), done: make(chan struct{})}
+
+	if b.shared {
+		b.addSession(session)
+		w.Header().Set(mcp.SessionIDHeader, session.id)
+		writeBridgeMessage(w, http.StatusOK, &mcp.Message{JSONRPC: "2.0", ID: msg.ID, Result: b.sharedProcess().initialized})
+		return
+	}
+
+	process, err := b.startProcess(session)
+	if err != nil {
+		writeBridgeMessage(w, http.StatusOK, mcp.NewErrorResponse(msg.ID, mcp.CodeInternalError, "%v", err))
+		return
+	}
+	session.process = process
+
+	response, err := process.call(r.Context(), session, msg)
+	if err != nil || response.Error != nil {
+		process.close()
+		if err != nil {
+			response = mcp.NewErrorResponse(msg.ID, mcp.CodeInternalError, "%v", err)
+		}
+		writeBridgeMessage(w, http.StatusOK, response)
+		return
+	}
+
+	select {
+	case <-session.done:
+		// The process exited right after answering
+		writeBridgeMessage(w, http.StatusOK, mcp.NewErrorResponse(msg.ID, mcp.CodeInternalError, "%v", process.exitError()))
+		return
+	default:
+	}
+
+	b.addSession(session)
+	w.Header().Set(mcp.SessionIDHeader, session.id)
+	writeBridgeMessage(w, http.StatusOK, response)
+}
+
+// stream sends a client the messages its server sends on its own, as server
+// sent events, until the client disconnects or the session ends
+func (b *Bridge) stream(w http.ResponseWriter, r *http.Request) {
+	session, status := b.session(r)
+	if session == nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		http.Error(w, "Accept must include text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	stream := make(chan *mcp.Message, bridgeStreamBuffer)
+	session.mu.Lock()
+	if session.stream != nil {
+		session.mu.Unlock()
+		http.Error(w, "the session already has an event stream", http.StatusConflict)
+		return
+	}
+	session.stream = stream
+	session.mu.Unlock()
+	defer func() {
+		session.mu.Lock()
+		session.stream = nil
+		session.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case msg := <-stream:
+			data, err := json.Marshal(msg)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-session.done:
+			return
+		case <-b.draining:
+			return
+		}
+	}
+}
+
+// session returns the session a request belongs to, or the HTTP status to
+// answer with: 400 without a session ID, 404 for an unknown or ended one
+func (b *Bridge) session(r *http.Request) (*bridgeSession, int) {
+	id := r.Header.Get(mcp.SessionIDHeader)
+	if id == "" {
+		return nil, http.StatusBadRequest
+	}
+
+	b.mu.Lock()
+	session, ok := b.sessions[id]
+	b.mu.Unlock()
+	if !ok {
+		return nil, http.StatusNotFound
+	}
+	session.touch(b.idle)
+	return session, 0
+}
+
+// processOf returns the process serving a session
+func (b *Bridge) processOf(session *bridgeSession) *bridgeProcess {
+	if session.process != nil {
+		return session.process
+	}
+	return b.sharedProcess()
+}
+
+// sharedProcess returns the current shared process
+func (b *Bridge) sharedProcess() *bridgeProcess {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.process
+}
+
+// addSession registers a session once it is initialized and starts its idle
+// timer
+func (b *Bridge) addSession(session *bridgeSession) {
+	if b.idle > 0 {
+		session.mu.Lock()
+		session.timer = time.AfterFunc(b.idle, func() { b.expire(session) })
+		session.mu.Unlock()
+	}
+
+	b.mu.Lock()
+	b.sessions[session.id] = session
+	b.mu.Unlock()
+}
+
+// expire ends a session that went without requests for the idle timeout,
+// unless it has an event stream open or requests in progress
+func (b *Bridge) expire(session *bridgeSession) {
+	session.mu.Lock()
+	streaming := session.stream != nil
+	session.mu.Unlock()
+	if streaming || b.processOf(session).waiting(session) {
+		session.touch(b.idle)
+		return
+	}
+	b.endSession(session)
+}
+
+// endSession forgets a session, stopping its process unless it is shared
+func (b *Bridge) endSession(session *bridgeSession) {
+	b.mu.Lock()
+	delete(b.sessions, session.id)
+	b.mu.Unlock()
+
+	session.end()
+	if session.process != nil {
+		session.process.close()
+	}
+}
+
+// broadcast sends a notification of the shared process to every session
+func (b *Bridge) broadcast(msg *mcp.Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, session := range b.sessions {
+		session.send(msg)
+	}
+}
+
+// startProcess starts a server process for a session, or the shared process
+// when owner is nil
+func (b *Bridge) startProcess(owner *bridgeSession) (*bridgeProcess, error) {
+	supervisor := b.supervisor
+	if owner != nil {
+		supervisor = b.manager.newSupervisor(b.server, "bridge", false, b.log)
+	}
+
+	cmd := ServerCommand(b.server)
+	cmd.Stderr = supervisor.stderr
+	stdio, err := mcp.StartStdio(cmd)
+	if err != nil {
+		if owner != nil {
+			supervisor.close()
+		}
+		return nil, fmt.Errorf("failed to start %s: %w", b.server.Command, err)
+	}
+
+	p := &bridgeProcess{bridge: b, stdio: stdio, owner: owner, pending: map[string]*bridgeRequest{}}
+	if owner != nil {
+		p.supervisor = supervisor
+		supervisor.started(stdio)
+	}
+	go p.receive()
+	return p, nil
+}
+
+// end closes the session's event stream and stops its idle timer
+func (s *bridgeSession) end() {
+	s.endOnce.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+		s.mu.Unlock()
+	})
+}
+
+// touch restarts the session's idle timer
+func (s *bridgeSession) touch(idle time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.timer != nil {
+		s.timer.Reset(idle)
+	}
+}
+
+// send queues a message for the session's event stream, reporting whether
+// the client has a stream open to receive it
+func (s *bridgeSession) send(msg *mcp.Message) bool {
+	s.mu.Lock()
+	stream := s.stream
+	s.mu.Unlock()
+	if stream == nil {
+		return false
+	}
+
+	select {
+	case stream <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// initialize performs the handshake with the shared process on behalf of all
+// clients and keeps its result
+func (p *bridgeProcess) initialize(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	request, err := mcp.NewRequest(json.RawMessage("0"), "initialize", mcp.InitializeParams{
+		ProtocolVersion: mcp.ProtocolVersion,
+		Capabilities:    map[string]json.RawMessage{},
+		ClientInfo:      ClientInfo,
+	})
+	if err != nil {
+		return err
+	}
+	response, err := p.call(ctx, nil, request)
+	if err != nil {
+		return err
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	p.initialized = response.Result
+
+	initialized, _ := mcp.NewRequest(nil, "notifications/initialized", nil)
+	return p.stdio.Send(ctx, initialized)
+}
+
+// call passes a session's request on to the server under an ID of the
+// bridge's and waits for the response, which is returned with the request's
+// own ID
+func (p *bridgeProcess) call(ctx context.Context, session *bridgeSession, msg *mcp.Message) (*mcp.Message, error) {
+	id := json.RawMessage(strconv.FormatInt(p.bridge.nextID.Add(1), 10))
+	request := &bridgeRequest{session: session, id: msg.ID, reply: make(chan *mcp.Message, 1)}
+
+	p.mu.Lock()
+	if p.pending == nil {
+		p.mu.Unlock()
+		return nil, p.exitError()
+	}
+	p.pending[string(id)] = request
+	p.mu.Unlock()
+
+	forwarded := *msg
+	forwarded.ID = id
+	if err := p.stdio.Send(ctx, &forwarded); err != nil {
+		p.forget(id)
+		return nil, fmt.Errorf("failed to send %s: %w", msg.Method, err)
+	}
+
+	select {
+	case response, ok := <-request.reply:
+		if !ok {
+			return nil, p.exitError()
+		}
+		response.ID = msg.ID
+		return response, nil
+	case <-ctx.Done():
+		p.forget(id)
+		cancelled, _ := mcp.NewRequest(nil, "notifications/cancelled", map[string]interface{}{"requestId": id, "reason": ctx.Err().Error()})
+		go p.stdio.Send(context.Background(), cancelled)
+		return nil, ctx.Err()
+	}
+}
+
+// forward passes on a session's notification, or a response to a request the
+// server made of its client. Clients of the shared process cannot answer the
+// server, and the bridge has initialized it already.
+func (p *bridgeProcess) forward(session *bridgeSession, msg *mcp.Message) {
+	if p.owner == nil && (msg.IsResponse() || msg.Method == "notifications/initialized") {
+		return
+	}
+	if msg.Method == "notifications/cancelled" {
+		if msg = p.cancellation(session, msg); msg == nil {
+			return
+		}
+	}
+	p.stdio.Send(context.Background(), msg)
+}
+
+// cancellation returns a session's cancellation of a request with the ID the
+// request was passed on under, or nil if the request is not waiting for a
+// response
+func (p *bridgeProcess) cancellation(session *bridgeSession, msg *mcp.Message) *mcp.Message {
+	var params map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return nil
+	}
+	requestID := bytes.TrimSpace(params["requestId"])
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, request := range p.pending {
+		if request.session != session || !bytes.Equal(bytes.TrimSpace(request.id), requestID) {
+			continue
+		}
+		params["requestId"] = json.RawMessage(id)
+		cancelled := *msg
+		cancelled.Params, _ = json.Marshal(params)
+		return &cancelled
+	}
+	return nil
+}
+
+// receive dispatches the messages of the server until it exits
+func (p *bridgeProcess) receive() {
+	for {
+		msg, err := p.stdio.Receive()
+		if err != nil {
+			p.exit()
+			return
+		}
+
+		switch {
+		case msg.IsResponse():
+			p.mu.Lock()
+			request := p.pending[string(msg.ID)]
+			delete(p.pending, string(msg.ID))
+			p.mu.Unlock()
+			if request != nil {
+				request.reply <- msg
+			}
+		case msg.IsRequest():
+			if msg.Method != "ping" && p.owner != nil && p.owner.send(msg) {
+				continue
+			}
+			reply := mcp.NewErrorResponse(msg.ID, mcp.CodeMethodNotFound, "method %s is not supported by the client", msg.Method)
+			if msg.Method == "ping" {
+				reply, _ = mcp.NewResponse(msg.ID, struct{}{})
+			}
+			p.stdio.Send(context.Background(), reply)
+		case p.owner != nil:
+			p.owner.send(msg)
+		case bridgeBroadcasts[msg.Method]:
+			p.bridge.broadcast(msg)
+		}
+	}
+}
+
+// exit fails the requests waiting for the server and ends the session of a
+// session's process, reporting the exit unless the session had ended. The
+// shared process is left to the bridge's supervisor.
+func (p *bridgeProcess) exit() {
+	p.mu.Lock()
+	for _, request := range p.pending {
+		close(request.reply)
+	}
+	p.pending = nil
+	p.mu.Unlock()
+
+	if p.owner == nil {
+		return
+	}
+
+	<-p.stdio.Exited()
+	b := p.bridge
+	b.mu.Lock()
+	_, open := b.sessions[p.owner.id]
+	delete(b.sessions, p.owner.id)
+	b.mu.Unlock()
+	p.owner.end()
+
+	if open {
+		p.supervisor.exited(exitStatus(p.stdio))
+	}
+	p.supervisor.close()
+}
+
+// exitError explains why a request got no response
+func (p *bridgeProcess) exitError() error {
+	select {
+	case <-p.stdio.Exited():
+		return p.stdio.ExitError()
+	default:
+		return fmt.Errorf("server closed its output")
+	}
+}
+
+// waiting reports whether a session has requests waiting for the server's
+// response
+func (p *bridgeProcess) waiting(session *bridgeSession) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, request := range p.pending {
+		if request.session == session {
+			return true
+		}
+	}
+	return false
+}
+
+// forget stops waiting for the response to a request
+func (p *bridgeProcess) forget(id json.RawMessage) {
+	p.mu.Lock()
+	delete(p.pending, string(id))
+	p.mu.Unlock()
+}
+
+// close stops the process
+func (p *bridgeProcess) close() {
+	p.stdio.Close()
+}
+
+// newSessionID returns a random session ID
+func newSessionID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// writeBridgeMessage answers an HTTP request with a JSON-RPC message
+func writeBridgeMessage(w http.ResponseWriter, status int, msg *mcp.Message) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(msg)
+}
+
+// allowedOrigin reports whether a browser request comes from a page on a
+// loopback address, guarding local servers against DNS rebinding. Requests
+// without an Origin header are not from browsers.
+func allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,272 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/socialviolation/mcpv/internal/mcp"
+)
+
+func TestBridgeCancellation(t *testing.T) {
+	first, second := &bridgeSession{id: "first"}, &bridgeSession{id: "second"}
+	p := &bridgeProcess{pending: map[string]*bridgeRequest{
+		"7": {session: first, id: json.RawMessage(`1`)},
+		"8": {session: second, id: json.RawMessage(`1`)},
+	}}
+
+	cancel := func(session *bridgeSession, id string) *mcp.Message {
+		msg, _ := mcp.NewRequest(nil, "notifications/cancelled", map[string]interface{}{"requestId": json.RawMessage(id), "reason": "stop"})
+		return p.cancellation(session, msg)
+	}
+
+	msg := cancel(second, `1`)
+	if msg == nil {
+		t.Fatal("cancellation of a pending request was dropped")
+	}
+	var params map[string]interface{}
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		t.Fatal(err)
+	}
+	if params["requestId"] != float64(8) || params["reason"] != "stop" {
+		t.Errorf("got params %v, want requestId 8 and the reason kept", params)
+	}
+
+	if cancel(first, `2`) != nil {
+		t.Error("cancellation of an unknown request was passed on")
+	}
+	if cancel(&bridgeSession{id: "third"}, `1`) != nil {
+		t.Error("cancellation of another session's request was passed on")
+	}
+}
+
+// bridgeClient is a client session of a bridge under test
+type bridgeClient struct {
+	t       *testing.T
+	url     string
+	session string
+	nextID  int
+}
+
+// newBridgeClient initializes a session with the bridge at url
+func newBridgeClient(t *testing.T, url string) *bridgeClient {
+	t.Helper()
+
+	c := &bridgeClient{t: t, url: url}
+	response, status := c.post("initialize", map[string]interface{}{"protocolVersion": mcp.ProtocolVersion, "capabilities": map[string]interface{}{}})
+	if status != http.StatusOK || response.Error != nil || c.session == "" {
+		t.Fatalf("initialize answered %d %+v with session %q", status, response, c.session)
+	}
+	return c
+}
+
+// post sends a request and returns the response and HTTP status
+func (c *bridgeClient) post(method string, params interface{}) (*mcp.Message, int) {
+	c.t.Helper()
+
+	c.nextID++
+	request, err := mcp.NewRequest(json.RawMessage(fmt.Sprint(c.nextID)), method, params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	r, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if c.session != "" {
+		r.Header.Set(mcp.SessionIDHeader, c.session)
+	}
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if id := resp.Header.Get(mcp.SessionIDHeader); id != "" {
+		c.session = id
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode
+	}
+
+	var response mcp.Message
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		c.t.Fatal(err)
+	}
+	return &response, resp.StatusCode
+}
+
+// echo calls the fake server's echo tool and returns the text it answered
+func (c *bridgeClient) echo(text string) string {
+	c.t.Helper()
+
+	response, status := c.post("tools/call", map[string]interface{}{"name": "echo", "arguments": map[string]interface{}{"text": text}})
+	if status != http.StatusOK || response.Error != nil {
+		c.t.Fatalf("tools/call answered %d %+v", status, response)
+	}
+	var result struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil || len(result.Content) != 1 {
+		c.t.Fatalf("unexpected tools/call result %s: %v", response.Result, err)
+	}
+	return result.Content[0].Text
+}
+
+// delete ends the session and returns the HTTP status
+func (c *bridgeClient) delete() int {
+	c.t.Helper()
+
+	r, err := http.NewRequest(http.MethodDelete, c.url, nil)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	r.Header.Set(mcp.SessionIDHeader, c.session)
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// newTestBridge serves the fake server through a bridge
+func newTestBridge(t *testing.T, shared bool, idle time.Duration) (*Bridge, string) {
+	t.Helper()
+
+	m, project := newTestManager(t)
+	configPath := writeProjectConfig(t, project, &ProjectConfig{Servers: []MCPServer{fakeServer(nil)}})
+	bridge, err := m.NewBridge("fake", "", configPath, shared, 5*time.Second, idle, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(bridge)
+	t.Cleanup(func() {
+		bridge.CloseStreams()
+		server.Close()
+		bridge.Close()
+	})
+	return bridge, server.URL
+}
+
+// testSession returns a session of a bridge
+func testSession(b *Bridge, id string) *bridgeSession {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sessions[id]
+}
+
+// waitExited waits for a session's process to exit
+func waitExited(t *testing.T, process *bridgeProcess) {
+	t.Helper()
+
+	select {
+	case <-process.stdio.Exited():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the session's process was not stopped")
+	}
+}
+
+func TestBridgeSessions(t *testing.T) {
+	for _, shared := range []bool{false, true} {
+		t.Run(fmt.Sprintf("shared=%v", shared), func(t *testing.T) {
+			bridge, url := newTestBridge(t, shared, 0)
+
+			clients := []*bridgeClient{newBridgeClient(t, url), newBridgeClient(t, url)}
+			if clients[0].session == clients[1].session {
+				t.Fatal("the clients got the same session")
+			}
+			first, second := testSession(bridge, clients[0].session), testSession(bridge, clients[1].session)
+			if shared && (first.process != nil || second.process != nil) {
+				t.Error("a session of a shared bridge has a process of its own")
+			}
+			if !shared && (first.process == nil || first.process == second.process) {
+				t.Error("the sessions do not have processes of their own")
+			}
+
+			// Concurrent calls of both clients get their own responses
+			var wg sync.WaitGroup
+			for i, client := range clients {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 5; j++ {
+						text := fmt.Sprintf("client %d call %d", i, j)
+						if got := client.echo(text); got != text {
+							t.Errorf("%s got %q", text, got)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			if status := clients[0].delete(); status != http.StatusNoContent {
+				t.Fatalf("DELETE answered %d", status)
+			}
+			if _, status := clients[0].post("tools/list", nil); status != http.StatusNotFound {
+				t.Errorf("a request of an ended session answered %d, want 404", status)
+			}
+			if !shared {
+				waitExited(t, first.process)
+			}
+			if got := clients[1].echo("still there"); got != "still there" {
+				t.Errorf("the other session got %q after the first ended", got)
+			}
+		})
+	}
+}
+
+func TestBridgeIdleSessions(t *testing.T) {
+	bridge, url := newTestBridge(t, false, 500*time.Millisecond)
+
+	idle := newBridgeClient(t, url)
+	streaming := newBridgeClient(t, url)
+	idleProcess := testSession(bridge, idle.session).process
+	streamingProcess := testSession(bridge, streaming.session).process
+
+	// An open event stream keeps its session
+	r, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set(mcp.SessionIDHeader, streaming.session)
+	r.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	waitExited(t, idleProcess)
+	if _, status := idle.post("tools/list", nil); status != http.StatusNotFound {
+		t.Errorf("a request of an idle session answered %d, want 404", status)
+	}
+	if got := streaming.echo("streaming"); got != "streaming" {
+		t.Errorf("the streaming session got %q", got)
+	}
+
+	// Shutting down closes the stream, after which the session goes idle
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, resp.Body)
+		close(closed)
+	}()
+	bridge.CloseStreams()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the event stream was not closed")
+	}
+	waitExited(t, streamingProcess)
+}
@@ -0,0 +1,114 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/socialviolation/mcpv/internal/mcp"
+)
+
+// DefaultCheckTimeout bounds how long a server may take to start and answer
+// the MCP handshake
+const DefaultCheckTimeout = 30 * time.Second
+
+// ClientInfo identifies mcpv to the servers it connects to
+var ClientInfo = mcp.Implementation{Name: "mcpv", Version: "dev"}
+
+// CheckResult is the outcome of an MCP handshake with a server
+type CheckResult struct {
+	Server          string              `json:"server" yaml:"server"`
+	Version         string              `json:"version,omitempty" yaml:"version,omitempty"`
+	OK              bool                `json:"ok" yaml:"ok"`
+	ProtocolVersion string              `json:"protocol_version,omitempty" yaml:"protocol_version,omitempty"`
+	ServerInfo      *mcp.Implementation `json:"server_info,omitempty" yaml:"server_info,omitempty"`
+	Capabilities    []string            `json:"capabilities,omitempty" yaml:"capabilities,omitempty"`
+	Error           string              `json:"error,omitempty" yaml:"error,omitempty"`
+	// Stderr is the end of what the server wrote to standard error when the
+	// check failed
+	Stderr string `json:"stderr,omitempty" yaml:"stderr,omitempty"`
+}
+
+// CheckServer performs the MCP initialize handshake with a server: an
+// installed server started as 'mcpv run' would, or a remote server from
+// mcpv.json
+func (m *Manager) CheckServer(name, version, configPath string, timeout time.Duration) *CheckResult {
+	result := &CheckResult{Server: name, Version: version}
+
+	server, err := m.ConnectableServer(name, version, configPath)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Version = server.Version
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	session, err := openSession(ctx, server, nil)
+	if err != nil {
+		result.Error = err.Error()
+		var sessionErr *sessionError
+		if errors.As(err, &sessionErr) {
+			result.Stderr = sessionErr.stderr
+		}
+		return result
+	}
+	session.close()
+
+	result.OK = true
+	result.ProtocolVersion = session.initialized.ProtocolVersion
+	result.ServerInfo = &session.initialized.ServerInfo
+	result.Capabilities = session.initialized.CapabilityNames()
+	return result
+}
+
+// CheckServers checks every server of mcpv.json
+func (m *Manager) CheckServers(configPath string, timeout time.Duration) ([]*CheckResult, error) {
+	config, err := m.LoadProjectConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*CheckResult
+	for _, server := range config.Servers {
+		results = append(results, m.CheckServer(server.Name, "", configPath, timeout))
+	}
+	return results, nil
+}
+
+// checkInstall checks a server just installed when install checks are
+// enabled, calling rollback to undo the install if the check fails
+func (m *Manager) checkInstall(server *MCPServer, rollback func() error) error {
+	if m.installCheck == nil {
+		return nil
+	}
+
+	result := m.CheckServer(server.Name, server.Version, m.installCheck.configPath, m.installCheck.timeout)
+	if result.OK {
+		return nil
+	}
+
+	if err := rollback(); err != nil {
+		return fmt.Errorf("server %s@%s failed its health check: %s (rolling it back failed: %v)", server.Name, server.Version, result.Error, err)
+	}
+	message := fmt.Sprintf("server %s@%s failed its health check and was rolled back: %s", server.Name, server.Version, result.Error)
+	if result.Stderr != "" {
+		message += "\nServer output:\n" + result.Stderr
+	}
+	return fmt.Errorf("%s", message)
+}
+
+// installCheck configures the health check run after each install
+type installCheck struct {
+	configPath string
+	timeout    time.Duration
+}
+
+// EnableInstallCheck makes installs run the MCP handshake with each server
+// they install, with settings from the given mcpv.json, and roll the install
+// back if it fails
+func (m *Manager) EnableInstallCheck(configPath string, timeout time.Duration) {
+	m.installCheck = &installCheck{configPath: configPath, timeout: timeout}
+}
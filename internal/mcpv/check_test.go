@@ -0,0 +1,52 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckServer(t *testing.T) {
+	m, project := newTestManager(t)
+	failing := fakeServer(map[string]string{"FAKE_EXIT": "3"})
+	failing.Name = "failing"
+	configPath := writeProjectConfig(t, project, &ProjectConfig{Servers: []MCPServer{fakeServer(nil), failing}})
+
+	result := m.CheckServer("fake", "", configPath, 5*time.Second)
+	if !result.OK || result.Error != "" {
+		t.Fatalf("the check of a working server failed: %+v", result)
+	}
+	if result.ProtocolVersion != "2025-03-26" || result.ServerInfo == nil || result.ServerInfo.Name != "fake" {
+		t.Errorf("got protocol %q and server info %+v", result.ProtocolVersion, result.ServerInfo)
+	}
+	if want := []string{"tools"}; !reflect.DeepEqual(result.Capabilities, want) {
+		t.Errorf("got capabilities %v, want %v", result.Capabilities, want)
+	}
+
+	result = m.CheckServer("failing", "", configPath, 5*time.Second)
+	if result.OK || result.Error == "" {
+		t.Fatalf("the check of a server that exits passed: %+v", result)
+	}
+	if !strings.Contains(result.Stderr, "fake server exiting") {
+		t.Errorf("the server's standard error was not captured: %+v", result)
+	}
+}
+
+func TestInstallCheckRollsBackFreshInstall(t *testing.T) {
+	m, project := newTestManager(t)
+	repository := newTaggedRepo(t, "v1.0.0")
+	server := fakeServer(map[string]string{"FAKE_EXIT": "3"})
+	server.Repository = repository
+	m.EnableInstallCheck(writeProjectConfig(t, project, &ProjectConfig{Servers: []MCPServer{server}}), 5*time.Second)
+
+	_, err := m.InstallServer("fake", "v1.0.0", repository)
+	if err == nil || !strings.Contains(err.Error(), "rolled back") || !strings.Contains(err.Error(), "fake server exiting") {
+		t.Fatalf("expected the install to be rolled back with the server's output, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(m.dataDir, "fake", "v1.0.0")); !os.IsNotExist(err) {
+		t.Errorf("the failed install was kept: %v", err)
+	}
+}
@@ -0,0 +1,385 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/socialviolation/mcpv/internal/mcp"
+)
+
+// Kinds of items in a server's surface
+const (
+	SurfaceTool             = "tool"
+	SurfacePrompt           = "prompt"
+	SurfaceResource         = "resource"
+	SurfaceResourceTemplate = "resource_template"
+)
+
+// Changes to an item in a server's surface
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// SurfaceChange is one difference between what two servers expose
+type SurfaceChange struct {
+	Kind     string `json:"kind" yaml:"kind"`
+	Name     string `json:"name" yaml:"name"`
+	Change   string `json:"change" yaml:"change"`
+	Detail   string `json:"detail,omitempty" yaml:"detail,omitempty"`
+	Breaking bool   `json:"breaking" yaml:"breaking"`
+}
+
+// SurfaceDiff lists the differences between what two servers, usually two
+// versions of one server, expose
+type SurfaceDiff struct {
+	From    string          `json:"from" yaml:"from"`
+	To      string          `json:"to" yaml:"to"`
+	Changes []SurfaceChange `json:"changes" yaml:"changes"`
+}
+
+// BreakingChanges returns the number of changes that can break clients of
+// the older server: removed items, removed or newly required parameters and
+// arguments, and changed parameter types
+func (d *SurfaceDiff) BreakingChanges() int {
+	count := 0
+	for _, change := range d.Changes {
+		if change.Breaking {
+			count++
+		}
+	}
+	return count
+}
+
+// surfaceDiffer collects changes into a diff
+type surfaceDiffer struct {
+	diff *SurfaceDiff
+}
+
+func (d *surfaceDiffer) add(kind, name, change string, breaking bool, format string, args ...interface{}) {
+	d.diff.Changes = append(d.diff.Changes, SurfaceChange{
+		Kind:     kind,
+		Name:     name,
+		Change:   change,
+		Detail:   fmt.Sprintf(format, args...),
+		Breaking: breaking,
+	})
+}
+
+// CompareInspections compares what two servers expose
+func CompareInspections(from, to *Inspection) *SurfaceDiff {
+	d := &surfaceDiffer{diff: &SurfaceDiff{From: inspectionLabel(from), To: inspectionLabel(to), Changes: []SurfaceChange{}}}
+	d.compareTools(from.Tools, to.Tools)
+	d.comparePrompts(from.Prompts, to.Prompts)
+	d.compareResources(from.Resources, to.Resources)
+	d.compareResourceTemplates(from.ResourceTemplates, to.ResourceTemplates)
+	return d.diff
+}
+
+// inspectionLabel names the inspected server and version
+func inspectionLabel(inspection *Inspection) string {
+	if inspection.Version == "" {
+		return inspection.Server
+	}
+	return inspection.Server + "@" + inspection.Version
+}
+
+func (d *surfaceDiffer) compareTools(from, to []mcp.Tool) {
+	fromTools := map[string]mcp.Tool{}
+	for _, tool := range from {
+		fromTools[tool.Name] = tool
+	}
+	toTools := map[string]mcp.Tool{}
+	for _, tool := range to {
+		toTools[tool.Name] = tool
+	}
+
+	for _, name := range unionKeys(fromTools, toTools) {
+		old, inFrom := fromTools[name]
+		tool, inTo := toTools[name]
+		switch {
+		case !inTo:
+			d.add(SurfaceTool, name, ChangeRemoved, true, "tool removed")
+		case !inFrom:
+			d.add(SurfaceTool, name, ChangeAdded, false, "tool added")
+		default:
+			compareSchemas("", old.InputSchema, tool.InputSchema, func(breaking bool, format string, args ...interface{}) {
+				d.add(SurfaceTool, name, ChangeChanged, breaking, format, args...)
+			})
+			if old.Description != tool.Description {
+				d.add(SurfaceTool, name, ChangeChanged, false, "description changed")
+			}
+			if !jsonEqual(old.OutputSchema, tool.OutputSchema) {
+				d.add(SurfaceTool, name, ChangeChanged, false, "output schema changed")
+			}
+		}
+	}
+}
+
+func (d *surfaceDiffer) comparePrompts(from, to []mcp.Prompt) {
+	fromPrompts := map[string]mcp.Prompt{}
+	for _, prompt := range from {
+		fromPrompts[prompt.Name] = prompt
+	}
+	toPrompts := map[string]mcp.Prompt{}
+	for _, prompt := range to {
+		toPrompts[prompt.Name] = prompt
+	}
+
+	for _, name := range unionKeys(fromPrompts, toPrompts) {
+		old, inFrom := fromPrompts[name]
+		prompt, inTo := toPrompts[name]
+		switch {
+		case !inTo:
+			d.add(SurfacePrompt, name, ChangeRemoved, true, "prompt removed")
+		case !inFrom:
+			d.add(SurfacePrompt, name, ChangeAdded, false, "prompt added")
+		default:
+			oldArguments := map[string]mcp.PromptArgument{}
+			for _, argument := range old.Arguments {
+				oldArguments[argument.Name] = argument
+			}
+			arguments := map[string]mcp.PromptArgument{}
+			for _, argument := range prompt.Arguments {
+				arguments[argument.Name] = argument
+			}
+
+			for _, argument := range unionKeys(oldArguments, arguments) {
+				before, inBefore := oldArguments[argument]
+				after, inAfter := arguments[argument]
+				switch {
+				case !inAfter:
+					d.add(SurfacePrompt, name, ChangeChanged, true, "argument %s removed", argument)
+				case !inBefore && after.Required:
+					d.add(SurfacePrompt, name, ChangeChanged, true, "required argument %s added", argument)
+				case !inBefore:
+					d.add(SurfacePrompt, name, ChangeChanged, false, "optional argument %s added", argument)
+				case !before.Required && after.Required:
+					d.add(SurfacePrompt, name, ChangeChanged, true, "argument %s is now required", argument)
+				case before.Required && !after.Required:
+					d.add(SurfacePrompt, name, ChangeChanged, false, "argument %s is no longer required", argument)
+				}
+			}
+			if old.Description != prompt.Description {
+				d.add(SurfacePrompt, name, ChangeChanged, false, "description changed")
+			}
+		}
+	}
+}
+
+func (d *surfaceDiffer) compareResources(from, to []mcp.Resource) {
+	fromResources := map[string]mcp.Resource{}
+	for _, resource := range from {
+		fromResources[resource.URI] = resource
+	}
+	toResources := map[string]mcp.Resource{}
+	for _, resource := range to {
+		toResources[resource.URI] = resource
+	}
+
+	for _, uri := range unionKeys(fromResources, toResources) {
+		old, inFrom := fromResources[uri]
+		resource, inTo := toResources[uri]
+		switch {
+		case !inTo:
+			d.add(SurfaceResource, uri, ChangeRemoved, true, "resource removed")
+		case !inFrom:
+			d.add(SurfaceResource, uri, ChangeAdded, false, "resource added")
+		case old.MimeType != resource.MimeType:
+			d.add(SurfaceResource, uri, ChangeChanged, false, "MIME type changed from %q to %q", old.MimeType, resource.MimeType)
+		}
+	}
+}
+
+func (d *surfaceDiffer) compareResourceTemplates(from, to []mcp.ResourceTemplate) {
+	fromTemplates := map[string]mcp.ResourceTemplate{}
+	for _, template := range from {
+		fromTemplates[template.URITemplate] = template
+	}
+	toTemplates := map[string]mcp.ResourceTemplate{}
+	for _, template := range to {
+		toTemplates[template.URITemplate] = template
+	}
+
+	for _, uri := range unionKeys(fromTemplates, toTemplates) {
+		_, inFrom := fromTemplates[uri]
+		_, inTo := toTemplates[uri]
+		switch {
+		case !inTo:
+			d.add(SurfaceResourceTemplate, uri, ChangeRemoved, true, "resource template removed")
+		case !inFrom:
+			d.add(SurfaceResourceTemplate, uri, ChangeAdded, false, "resource template added")
+		}
+	}
+}
+
+// compareSchemas reports the differences between two versions of a JSON
+// Schema describing input. path names the schema being compared, such as a
+// parameter; an empty path is the whole input. Changes that reject input the
+// old schema accepted are breaking.
+func compareSchemas(path string, from, to map[string]interface{}, report func(breaking bool, format string, args ...interface{})) {
+	subject := "input"
+	if path != "" {
+		subject = "parameter " + path
+	}
+
+	if fromType, toType := schemaType(from), schemaType(to); fromType != toType {
+		if !acceptsTypes(schemaTypes(to), schemaTypes(from)) {
+			report(true, "type of %s changed from %s to %s", subject, orAny(fromType), orAny(toType))
+			return
+		}
+		report(false, "type of %s widened from %s to %s", subject, orAny(fromType), orAny(toType))
+	}
+
+	if fromEnum, ok := from["enum"].([]interface{}); ok {
+		toEnum, _ := to["enum"].([]interface{})
+		if _, constrained := to["enum"]; constrained {
+			for _, value := range fromEnum {
+				if !containsValue(toEnum, value) {
+					report(true, "%s no longer accepts %v", subject, value)
+				}
+			}
+		}
+	} else if _, constrained := to["enum"]; constrained {
+		report(true, "%s is now limited to %v", subject, to["enum"])
+	}
+
+	fromProperties, _ := from["properties"].(map[string]interface{})
+	toProperties, _ := to["properties"].(map[string]interface{})
+	fromRequired := requiredProperties(from)
+	toRequired := requiredProperties(to)
+
+	for _, name := range unionKeys(fromProperties, toProperties) {
+		property := joinSchemaPath(path, name)
+		before, inBefore := fromProperties[name].(map[string]interface{})
+		after, inAfter := toProperties[name].(map[string]interface{})
+		_, wasDeclared := fromProperties[name]
+		_, isDeclared := toProperties[name]
+
+		switch {
+		case wasDeclared && !isDeclared:
+			report(true, "parameter %s removed", property)
+			continue
+		case !wasDeclared && toRequired[name]:
+			report(true, "required parameter %s added", property)
+			continue
+		case !wasDeclared:
+			report(false, "optional parameter %s added", property)
+			continue
+		case !fromRequired[name] && toRequired[name]:
+			report(true, "parameter %s is now required", property)
+		case fromRequired[name] && !toRequired[name]:
+			report(false, "parameter %s is no longer required", property)
+		}
+
+		if inBefore && inAfter {
+			compareSchemas(property, before, after, report)
+			if before["description"] != after["description"] {
+				report(false, "description of parameter %s changed", property)
+			}
+		}
+	}
+
+	if fromItems, ok := from["items"].(map[string]interface{}); ok {
+		if toItems, ok := to["items"].(map[string]interface{}); ok {
+			compareSchemas(path+"[]", fromItems, toItems, report)
+		}
+	}
+}
+
+// schemaType returns the type a schema declares, with multiple types joined by |
+func schemaType(schema map[string]interface{}) string {
+	return strings.Join(schemaTypes(schema), "|")
+}
+
+// schemaTypes returns the types a schema declares in order, none for a schema
+// that accepts any type
+func schemaTypes(schema map[string]interface{}) []string {
+	switch value := schema["type"].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		types := make([]string, 0, len(value))
+		for _, kind := range value {
+			types = append(types, fmt.Sprint(kind))
+		}
+		sort.Strings(types)
+		return types
+	}
+	return nil
+}
+
+// acceptsTypes reports whether a schema declaring the types accepted takes
+// every value of one declaring the types given. No types accept any value,
+// and number accepts integers.
+func acceptsTypes(accepted, given []string) bool {
+	if len(accepted) == 0 {
+		return true
+	}
+	if len(given) == 0 {
+		return false
+	}
+	for _, kind := range given {
+		if !containsString(accepted, kind) && !(kind == "integer" && containsString(accepted, "number")) {
+			return false
+		}
+	}
+	return true
+}
+
+// orAny names an undeclared schema type
+func orAny(kind string) string {
+	if kind == "" {
+		return "any"
+	}
+	return kind
+}
+
+// requiredProperties returns the properties a schema requires
+func requiredProperties(schema map[string]interface{}) map[string]bool {
+	required := map[string]bool{}
+	names, _ := schema["required"].([]interface{})
+	for _, name := range names {
+		if name, ok := name.(string); ok {
+			required[name] = true
+		}
+	}
+	return required
+}
+
+// joinSchemaPath returns the path of a property within a parameter
+func joinSchemaPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// containsValue reports whether a JSON value is among values
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, candidate := range values {
+		if jsonEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// unionKeys returns the keys of both maps in sorted order
+func unionKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var keys []string
+	for key := range a {
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
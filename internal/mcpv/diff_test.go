@@ -0,0 +1,35 @@
+package manager
+
+import "testing"
+
+func TestCompareSchemasTypes(t *testing.T) {
+	for _, tc := range []struct {
+		from, to interface{}
+		breaking bool
+	}{
+		{from: "integer", to: "number", breaking: false},
+		{from: "string", to: []interface{}{"string", "null"}, breaking: false},
+		{from: "string", to: nil, breaking: false},
+		{from: "number", to: "integer", breaking: true},
+		{from: []interface{}{"string", "null"}, to: "string", breaking: true},
+		{from: nil, to: "string", breaking: true},
+		{from: "string", to: "boolean", breaking: true},
+	} {
+		from, to := map[string]interface{}{}, map[string]interface{}{}
+		if tc.from != nil {
+			from["type"] = tc.from
+		}
+		if tc.to != nil {
+			to["type"] = tc.to
+		}
+
+		changes, breaking := 0, false
+		compareSchemas("", from, to, func(b bool, format string, args ...interface{}) {
+			changes++
+			breaking = breaking || b
+		})
+		if changes != 1 || breaking != tc.breaking {
+			t.Errorf("%v to %v: got %d change(s), breaking %v; want 1, breaking %v", tc.from, tc.to, changes, breaking, tc.breaking)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package manager
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServerEnv makes the test binary run as a fake MCP server over stdio
+const fakeServerEnv = "MCPV_FAKE_SERVER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeServerEnv) != "" {
+		runFakeServer()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runFakeServer answers initialize, tools/list and tools/call until its input
+// ends. FAKE_DESCRIPTION sets the description of its echo tool; echo results
+// carry the time of the call in _meta.time, and calls of other tools fail.
+// FAKE_CAPABILITIES lists the capabilities it declares, tools by default;
+// with resources or prompts it also lists one resource or prompt. With FAKE_EXIT set, it exits with
+// that status right after starting instead.
+func runFakeServer() {
+	if status := os.Getenv("FAKE_EXIT"); status != "" {
+		code, _ := strconv.Atoi(status)
+		fmt.Fprintln(os.Stderr, "fake server exiting")
+		os.Exit(code)
+	}
+
+	description := os.Getenv("FAKE_DESCRIPTION")
+	if description == "" {
+		description = "Echo the text"
+	}
+
+	capabilities := map[string]interface{}{}
+	for _, capability := range strings.Split(os.Getenv("FAKE_CAPABILITIES"), ",") {
+		if capability == "" {
+			capability = "tools"
+		}
+		capabilities[capability] = map[string]interface{}{}
+	}
+
+	// Log output on standard output is not part of the protocol
+	fmt.Println("fake server ready")
+
+	out := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params struct {
+				Name      string                 `json:"name"`
+				Arguments map[string]interface{} `json:"arguments"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil || request.ID == nil {
+			continue
+		}
+
+		var result interface{}
+		switch request.Method {
+		case "initialize":
+			result = map[string]interface{}{
+				"protocolVersion": "2025-03-26",
+				"capabilities":    capabilities,
+				"serverInfo":      map[string]interface{}{"name": "fake", "version": "1.0.0"},
+			}
+		case "tools/list":
+			result = map[string]interface{}{"tools": []interface{}{
+				map[string]interface{}{"name": "echo", "description": description, "inputSchema": map[string]interface{}{"type": "object"}},
+			}}
+		case "tools/call":
+			if request.Params.Name != "echo" {
+				out.Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "error": map[string]interface{}{"code": -32602, "message": "unknown tool " + request.Params.Name}})
+				continue
+			}
+			result = map[string]interface{}{
+				"content": []interface{}{map[string]interface{}{"type": "text", "text": request.Params.Arguments["text"]}},
+				"_meta":   map[string]interface{}{"time": time.Now().UnixNano()},
+			}
+		case "resources/list":
+			result = map[string]interface{}{"resources": []interface{}{
+				map[string]interface{}{"uri": "file:///notes.txt", "name": "notes", "mimeType": "text/plain"},
+			}}
+		case "prompts/list":
+			result = map[string]interface{}{"prompts": []interface{}{
+				map[string]interface{}{"name": "greet", "arguments": []interface{}{map[string]interface{}{"name": "who", "required": true}}},
+			}}
+		default:
+			out.Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "error": map[string]interface{}{"code": -32601, "message": "method not found"}})
+			continue
+		}
+		out.Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": result})
+	}
+}
+
+// fakeServer returns a mcpv.json entry that runs the fake server
+func fakeServer(env map[string]string) MCPServer {
+	server := MCPServer{Name: "fake", Command: os.Args[0], Env: map[string]string{fakeServerEnv: "1"}}
+	for name, value := range env {
+		server.Env[name] = value
+	}
+	return server
+}
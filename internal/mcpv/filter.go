@@ -0,0 +1,236 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/socialviolation/mcpv/internal/mcp"
+)
+
+// ToolFilter limits the tools of a server agents may use. Patterns are
+// matched against tool names with path.Match, so * matches any run of
+// characters. A tool is allowed when it matches an allow pattern, or there
+// are none, and matches no deny pattern.
+type ToolFilter struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// Allows reports whether the filter lets agents use a tool. A nil filter
+// allows every tool.
+func (f *ToolFilter) Allows(name string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.Allow) > 0 && !matchesAny(f.Allow, name) {
+		return false
+	}
+	return !matchesAny(f.Deny, name)
+}
+
+// validate checks that the filter's patterns are well formed
+func (f *ToolFilter) validate() error {
+	if f == nil {
+		return nil
+	}
+	for _, pattern := range append(append([]string{}, f.Allow...), f.Deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("bad pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// exact reports whether the filter only names tools, without wildcards, so
+// that agents that match tool names exactly can apply it
+func (f *ToolFilter) exact() bool {
+	for _, pattern := range append(append([]string{}, f.Allow...), f.Deny...) {
+		if strings.ContainsAny(pattern, `*?[\`) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesAny reports whether a name matches one of the patterns
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ProxyServer connects to a resolved server, local or remote, and relays the
+// messages of a client on in and out to it, hiding the tools its filter does
+// not allow from tools/list and refusing calls to them. It returns when
+// either side ends the session, with a local server's exit code.
+func (m *Manager) ProxyServer(ctx context.Context, server *MCPServer, args []string, in io.Reader, out io.WriteCloser, stderr io.Writer) (int, error) {
+	transport, stdio, err := dialServer(ctx, server, args, stderr)
+	if err != nil {
+		return 0, err
+	}
+
+	proxy := &filterProxy{
+		client:   mcp.NewStreamTransport(in, out),
+		server:   transport,
+		filter:   server.Tools,
+		listings: map[string]bool{},
+	}
+	err = proxy.run(ctx)
+	transport.Close()
+
+	if stdio == nil {
+		return 0, err
+	}
+	var exitErr *exec.ExitError
+	if errors.As(stdio.ExitError(), &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	return 0, nil
+}
+
+// filterProxy relays messages between a client and a server, applying a
+// tools filter
+type filterProxy struct {
+	client mcp.Transport
+	server mcp.Transport
+	filter *ToolFilter
+
+	// listings holds the IDs of the client's tools/list requests awaiting
+	// a response
+	mu       sync.Mutex
+	listings map[string]bool
+}
+
+// run relays messages until either side ends the session or ctx is done. A
+// side closing its stream ends the session normally.
+func (p *filterProxy) run(ctx context.Context) error {
+	done := make(chan error, 2)
+	go func() {
+		done <- p.relay(ctx, p.client, p.server, p.fromClient)
+	}()
+	go func() {
+		done <- p.relay(ctx, p.server, p.client, p.fromServer)
+	}()
+
+	select {
+	case err := <-done:
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// relay passes the messages received from one side to the other, unless
+// handle answers them itself. A request the server cannot be sent, such as
+// one a remote server rejects, is answered with the error.
+func (p *filterProxy) relay(ctx context.Context, from, to mcp.Transport, handle func(context.Context, *mcp.Message) bool) error {
+	for {
+		msg, err := from.Receive()
+		if err != nil {
+			return err
+		}
+		if handle(ctx, msg) {
+			continue
+		}
+		if err := to.Send(ctx, msg); err != nil {
+			if to != p.server || !msg.IsRequest() {
+				return err
+			}
+			if err := p.client.Send(ctx, mcp.NewErrorResponse(msg.ID, mcp.CodeInternalError, "%v", err)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// fromClient refuses calls to tools the filter does not allow and notes
+// tools/list requests, reporting whether it answered the message itself
+func (p *filterProxy) fromClient(ctx context.Context, msg *mcp.Message) bool {
+	if !msg.IsRequest() {
+		return false
+	}
+
+	switch msg.Method {
+	case "tools/list":
+		p.mu.Lock()
+		p.listings[string(msg.ID)] = true
+		p.mu.Unlock()
+	case "tools/call":
+		var params struct {
+			Name string `json:"name"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		if !p.filter.Allows(params.Name) {
+			p.client.Send(ctx, mcp.NewErrorResponse(msg.ID, mcp.CodeInvalidParams, "tool %s is not allowed by mcpv.json", params.Name))
+			return true
+		}
+	}
+	return false
+}
+
+// fromServer removes the tools the filter does not allow from responses to
+// tools/list. A result that cannot be filtered is replaced with an error.
+func (p *filterProxy) fromServer(ctx context.Context, msg *mcp.Message) bool {
+	if !msg.IsResponse() || msg.Result == nil {
+		return false
+	}
+
+	p.mu.Lock()
+	listing := p.listings[string(msg.ID)]
+	delete(p.listings, string(msg.ID))
+	p.mu.Unlock()
+
+	if listing {
+		result, err := filterToolList(msg.Result, p.filter)
+		if err != nil {
+			// Relaying the list unfiltered would expose denied tools
+			*msg = *mcp.NewErrorResponse(msg.ID, mcp.CodeInternalError, "failed to filter the tools of the server: %v", err)
+			return false
+		}
+		msg.Result = result
+	}
+	return false
+}
+
+// filterToolList removes the tools a filter does not allow from a tools/list
+// result, keeping every other field as the server sent it
+func filterToolList(result json.RawMessage, filter *ToolFilter) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(result, &fields); err != nil {
+		return nil, err
+	}
+	var tools []json.RawMessage
+	if err := json.Unmarshal(fields["tools"], &tools); err != nil {
+		return nil, err
+	}
+
+	allowed := []json.RawMessage{}
+	for _, tool := range tools {
+		var named struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(tool, &named); err == nil && filter.Allows(named.Name) {
+			allowed = append(allowed, tool)
+		}
+	}
+
+	data, err := json.Marshal(allowed)
+	if err != nil {
+		return nil, err
+	}
+	fields["tools"] = data
+	return json.Marshal(fields)
+}
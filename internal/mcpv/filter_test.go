@@ -0,0 +1,35 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/socialviolation/mcpv/internal/mcp"
+)
+
+func TestFilterProxyToolList(t *testing.T) {
+	for _, tc := range []struct {
+		name, result, want string
+	}{
+		{name: "filtered", result: `{"tools":[{"name":"read"},{"name":"delete"}],"nextCursor":"c"}`, want: `{"nextCursor":"c","tools":[{"name":"read"}]}`},
+		{name: "invalid", result: `{"tools":{"name":"delete"}}`},
+		{name: "missing", result: `{}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &filterProxy{filter: &ToolFilter{Deny: []string{"delete"}}, listings: map[string]bool{"1": true}}
+			msg := &mcp.Message{JSONRPC: "2.0", ID: json.RawMessage(`1`), Result: json.RawMessage(tc.result)}
+			p.fromServer(context.Background(), msg)
+
+			if tc.want == "" {
+				if msg.Error == nil || msg.Result != nil {
+					t.Errorf("got result %s, want an error", msg.Result)
+				}
+				return
+			}
+			if msg.Error != nil || string(msg.Result) != tc.want {
+				t.Errorf("got result %s and error %v, want %s", msg.Result, msg.Error, tc.want)
+			}
+		})
+	}
+}
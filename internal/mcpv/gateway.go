@@ -0,0 +1,523 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/socialviolation/mcpv/internal/mcp"
+)
+
+// GatewaySeparator joins the name of a server to the names of the tools and
+// prompts the gateway exposes for it, as in github__create_issue
+const GatewaySeparator = "__"
+
+// gatewayNotifications are the notifications of servers the gateway passes on
+// to its client
+var gatewayNotifications = map[string]bool{
+	"notifications/tools/list_changed":     true,
+	"notifications/prompts/list_changed":   true,
+	"notifications/resources/list_changed": true,
+	"notifications/progress":               true,
+}
+
+// Gateway is an MCP server that runs the servers of mcpv.json and exposes
+// their tools, prompts and resources to one client. Tools and prompts are
+// named after their server, joined with GatewaySeparator; resources keep
+// their URIs.
+type Gateway struct {
+	servers []*gatewayServer
+	log     io.Writer
+	timeout time.Duration
+
+	// done is closed when the gateway is closed, to stop restarting servers
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu        sync.Mutex
+	client    *mcp.Server
+	resources map[string]*gatewayServer
+	templates map[string]*gatewayServer
+}
+
+// gatewayServer is a server the gateway runs
+type gatewayServer struct {
+	name string
+	// supervisor restarts a local server; nil for remote servers
+	supervisor *supervisor
+
+	mu      sync.Mutex
+	session *serverSession
+}
+
+// StartGateway starts every server of mcpv.json, local servers over stdio and
+// remote ones over HTTP, waiting at most timeout for each to answer the
+// handshake. Servers that fail to start are reported to log and left out, so
+// one broken server does not take the others away from agents. Local servers'
+// standard error is written to log with each line prefixed by their name and
+// to their log file, and they are restarted as their restart policy says.
+func (m *Manager) StartGateway(configPath string, timeout time.Duration, log io.Writer) (*Gateway, error) {
+	config, err := m.loadProject(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var resolved []*MCPServer
+	for _, configured := range config.Servers {
+		server, err := m.ConnectableServer(configured.Name, "", configPath)
+		if err != nil {
+			fmt.Fprintf(log, "Warning: skipping %s: %v\n", configured.Name, err)
+			continue
+		}
+		resolved = append(resolved, server)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	servers := make([]*gatewayServer, len(resolved))
+	var wg sync.WaitGroup
+	for i, server := range resolved {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var supervisor *supervisor
+			var stderr io.Writer
+			if !server.IsRemote() {
+				supervisor = m.newSupervisor(server, "serve", true, log)
+				stderr = supervisor.stderr
+			}
+			session, err := openSession(ctx, server, stderr)
+			if err != nil {
+				fmt.Fprintf(log, "Warning: skipping %s: %v\n", server.Name, err)
+				if supervisor != nil {
+					supervisor.close()
+				}
+				return
+			}
+			servers[i] = &gatewayServer{name: server.Name, supervisor: supervisor, session: session}
+		}()
+	}
+	wg.Wait()
+
+	g := &Gateway{log: log, timeout: timeout, done: make(chan struct{})}
+	for _, server := range servers {
+		if server == nil {
+			continue
+		}
+		g.servers = append(g.servers, server)
+		server.session.client.OnNotification(g.forward)
+		if server.supervisor != nil {
+			server.supervisor.started(server.session.stdio)
+			go g.supervise(server)
+		}
+	}
+	return g, nil
+}
+
+// supervise restarts a local server that exits as its restart policy says,
+// telling the client its tools, prompts and resources changed
+func (g *Gateway) supervise(server *gatewayServer) {
+	start := func() (*mcp.StdioTransport, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+		defer cancel()
+		session, err := openSession(ctx, server.current().server, server.supervisor.stderr)
+		if err != nil {
+			return nil, err
+		}
+		session.client.OnNotification(g.forward)
+
+		server.mu.Lock()
+		server.session = session
+		server.mu.Unlock()
+		g.listChanged()
+		return session.stdio, nil
+	}
+	server.supervisor.supervise(server.current().stdio, start, g.done)
+
+	select {
+	case <-g.done:
+	default:
+		g.listChanged()
+	}
+}
+
+// listChanged tells the client the tools, prompts and resources of the
+// servers changed, as they do when a server restarts or stops
+func (g *Gateway) listChanged() {
+	for _, method := range []string{"notifications/tools/list_changed", "notifications/prompts/list_changed", "notifications/resources/list_changed"} {
+		g.forward(&mcp.Message{JSONRPC: "2.0", Method: method})
+	}
+}
+
+// current returns the server's session
+func (s *gatewayServer) current() *serverSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.session
+}
+
+// Serve answers a client reading from in and writing to out, until the client
+// disconnects or ctx is done
+func (g *Gateway) Serve(ctx context.Context, in io.Reader, out io.WriteCloser) error {
+	server := mcp.NewServer(mcp.NewStreamTransport(in, out), g)
+	g.mu.Lock()
+	g.client = server
+	g.mu.Unlock()
+	return server.Serve(ctx)
+}
+
+// Close stops the servers
+func (g *Gateway) Close() {
+	g.closeOnce.Do(func() { close(g.done) })
+	var wg sync.WaitGroup
+	for _, server := range g.servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.current().close()
+			if server.supervisor != nil {
+				server.supervisor.close()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// forward passes a server's notification on to the client
+func (g *Gateway) forward(msg *mcp.Message) {
+	if !gatewayNotifications[msg.Method] {
+		return
+	}
+
+	g.mu.Lock()
+	client := g.client
+	if msg.Method == "notifications/resources/list_changed" {
+		g.resources, g.templates = nil, nil
+	}
+	g.mu.Unlock()
+
+	if client != nil {
+		client.Notify(context.Background(), msg.Method, msg.Params)
+	}
+}
+
+// HandleNotification ignores the client's notifications
+func (g *Gateway) HandleNotification(method string, params json.RawMessage) {}
+
+// HandleRequest answers a request of the client
+func (g *Gateway) HandleRequest(ctx context.Context, method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "initialize":
+		return g.initialize(params)
+	case "tools/list":
+		return g.listTools(ctx)
+	case "tools/call":
+		return g.call(ctx, method, params, "tools")
+	case "prompts/list":
+		return g.listPrompts(ctx)
+	case "prompts/get":
+		return g.call(ctx, method, params, "prompts")
+	case "resources/list":
+		return g.listResources(ctx)
+	case "resources/templates/list":
+		return g.listResourceTemplates(ctx)
+	case "resources/read":
+		return g.readResource(ctx, params)
+	}
+	return nil, mcp.Errorf(mcp.CodeMethodNotFound, "method %s is not supported by mcpv serve", method)
+}
+
+// initialize answers the client's handshake, offering the capabilities of
+// any of the servers
+func (g *Gateway) initialize(params json.RawMessage) (interface{}, error) {
+	var request mcp.InitializeParams
+	if err := json.Unmarshal(params, &request); err != nil {
+		return nil, mcp.Errorf(mcp.CodeInvalidParams, "invalid initialize params: %v", err)
+	}
+
+	listChanged := json.RawMessage(`{"listChanged":true}`)
+	result := mcp.InitializeResult{
+		ProtocolVersion: mcp.NegotiateProtocolVersion(request.ProtocolVersion),
+		Capabilities:    map[string]json.RawMessage{"tools": listChanged},
+		ServerInfo:      mcp.Implementation{Name: "mcpv", Version: ClientInfo.Version},
+	}
+
+	var instructions []string
+	for _, server := range g.servers {
+		session := server.current()
+		for _, capability := range []string{"prompts", "resources"} {
+			if session.hasCapability(capability) {
+				result.Capabilities[capability] = listChanged
+			}
+		}
+		if text := strings.TrimSpace(session.initialized.Instructions); text != "" {
+			instructions = append(instructions, fmt.Sprintf("Tools and prompts prefixed with %s%s:\n%s", server.name, GatewaySeparator, text))
+		}
+	}
+	result.Instructions = strings.Join(instructions, "\n\n")
+	return result, nil
+}
+
+// each calls list with every server that offers a capability and its index,
+// concurrently. Servers whose listing fails are reported to the log.
+func (g *Gateway) each(capability string, list func(i int, server *gatewayServer) error) {
+	errs := make([]error, len(g.servers))
+	var wg sync.WaitGroup
+	for i, server := range g.servers {
+		if !server.current().hasCapability(capability) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = list(i, server)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			fmt.Fprintf(g.log, "Warning: failed to list %s of %s: %v\n", capability, g.servers[i].name, err)
+		}
+	}
+}
+
+// listTools lists the tools of every server that its tools filter allows
+// under namespaced names
+func (g *Gateway) listTools(ctx context.Context) (interface{}, error) {
+	lists := make([][]mcp.Tool, len(g.servers))
+	g.each("tools", func(i int, server *gatewayServer) error {
+		session := server.current()
+		tools, err := session.client.ListTools(ctx)
+		filter := session.server.Tools
+		for _, tool := range tools {
+			if filter.Allows(tool.Name) {
+				tool.Name = server.name + GatewaySeparator + tool.Name
+				lists[i] = append(lists[i], tool)
+			}
+		}
+		return err
+	})
+
+	tools := []mcp.Tool{}
+	for _, list := range lists {
+		tools = append(tools, list...)
+	}
+	return map[string]interface{}{"tools": tools}, nil
+}
+
+// listPrompts lists the prompts of every server under namespaced names
+func (g *Gateway) listPrompts(ctx context.Context) (interface{}, error) {
+	lists := make([][]mcp.Prompt, len(g.servers))
+	g.each("prompts", func(i int, server *gatewayServer) error {
+		prompts, err := server.current().client.ListPrompts(ctx)
+		for j := range prompts {
+			prompts[j].Name = server.name + GatewaySeparator + prompts[j].Name
+		}
+		lists[i] = prompts
+		return err
+	})
+
+	prompts := []mcp.Prompt{}
+	for _, list := range lists {
+		prompts = append(prompts, list...)
+	}
+	return map[string]interface{}{"prompts": prompts}, nil
+}
+
+// listResources lists the resources of every server, remembering which
+// server offers each so reads can be routed to it
+func (g *Gateway) listResources(ctx context.Context) (interface{}, error) {
+	lists := make([][]mcp.Resource, len(g.servers))
+	g.each("resources", func(i int, server *gatewayServer) error {
+		resources, err := server.current().client.ListResources(ctx)
+		lists[i] = resources
+		return err
+	})
+
+	resources := []mcp.Resource{}
+	routes := map[string]*gatewayServer{}
+	for i, list := range lists {
+		for _, resource := range list {
+			if _, taken := routes[resource.URI]; !taken {
+				routes[resource.URI] = g.servers[i]
+				resources = append(resources, resource)
+			}
+		}
+	}
+
+	g.mu.Lock()
+	g.resources = routes
+	g.mu.Unlock()
+	return map[string]interface{}{"resources": resources}, nil
+}
+
+// listResourceTemplates lists the resource templates of every server,
+// remembering which server offers each
+func (g *Gateway) listResourceTemplates(ctx context.Context) (interface{}, error) {
+	lists := make([][]mcp.ResourceTemplate, len(g.servers))
+	g.each("resources", func(i int, server *gatewayServer) error {
+		templates, err := server.current().client.ListResourceTemplates(ctx)
+		var rpcErr *mcp.Error
+		if errors.As(err, &rpcErr) && rpcErr.Code == mcp.CodeMethodNotFound {
+			err = nil
+		}
+		lists[i] = templates
+		return err
+	})
+
+	templates := []mcp.ResourceTemplate{}
+	routes := map[string]*gatewayServer{}
+	for i, list := range lists {
+		for _, template := range list {
+			if _, taken := routes[template.URITemplate]; !taken {
+				routes[template.URITemplate] = g.servers[i]
+				templates = append(templates, template)
+			}
+		}
+	}
+
+	g.mu.Lock()
+	g.templates = routes
+	g.mu.Unlock()
+	return map[string]interface{}{"resourceTemplates": templates}, nil
+}
+
+// readResource reads a resource from the server that lists it, or whose
+// resource template its URI matches
+func (g *Gateway) readResource(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var request struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(params, &request); err != nil || request.URI == "" {
+		return nil, mcp.Errorf(mcp.CodeInvalidParams, "resources/read requires a uri")
+	}
+
+	g.mu.Lock()
+	listed := g.resources != nil && g.templates != nil
+	g.mu.Unlock()
+	if !listed {
+		g.listResources(ctx)
+		g.listResourceTemplates(ctx)
+	}
+
+	server := g.resourceServer(request.URI)
+	if server == nil {
+		return nil, mcp.Errorf(mcp.CodeInvalidParams, "no server offers resource %s", request.URI)
+	}
+	var result json.RawMessage
+	if err := server.current().client.Call(ctx, "resources/read", params, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// resourceServer returns the server that offers a resource: the one that
+// listed it, or else the one with the longest resource template prefix the
+// URI starts with
+func (g *Gateway) resourceServer(uri string) *gatewayServer {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if server, ok := g.resources[uri]; ok {
+		return server
+	}
+
+	var match *gatewayServer
+	longest := -1
+	for template, server := range g.templates {
+		prefix, _, _ := strings.Cut(template, "{")
+		if strings.HasPrefix(uri, prefix) && len(prefix) > longest {
+			match, longest = server, len(prefix)
+		}
+	}
+	return match
+}
+
+// call passes a tools/call or prompts/get request on to the server named by
+// the prefix of the tool or prompt, with the prefix removed
+func (g *Gateway) call(ctx context.Context, method string, params json.RawMessage, capability string) (interface{}, error) {
+	var request map[string]json.RawMessage
+	var name string
+	if err := json.Unmarshal(params, &request); err == nil {
+		json.Unmarshal(request["name"], &name)
+	}
+	if name == "" {
+		return nil, mcp.Errorf(mcp.CodeInvalidParams, "%s requires a name", method)
+	}
+
+	server, original := g.route(name)
+	if server == nil {
+		return nil, mcp.Errorf(mcp.CodeInvalidParams, "unknown %s %s", strings.TrimSuffix(capability, "s"), name)
+	}
+	session := server.current()
+	if !session.hasCapability(capability) {
+		return nil, mcp.Errorf(mcp.CodeInvalidParams, "unknown %s %s", strings.TrimSuffix(capability, "s"), name)
+	}
+	if capability == "tools" && !session.server.Tools.Allows(original) {
+		return nil, mcp.Errorf(mcp.CodeInvalidParams, "tool %s is not allowed by mcpv.json", name)
+	}
+
+	request["name"], _ = json.Marshal(original)
+	var result json.RawMessage
+	if err := session.client.Call(ctx, method, request, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// route returns the server a prefixed tool or prompt name belongs to and the
+// name without the prefix. Server names may contain GatewaySeparator
+// themselves, so the longest matching server name wins.
+func (g *Gateway) route(name string) (*gatewayServer, string) {
+	var match *gatewayServer
+	for _, server := range g.servers {
+		prefix := server.name + GatewaySeparator
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) && (match == nil || len(server.name) > len(match.name)) {
+			match = server
+		}
+	}
+	if match == nil {
+		return nil, ""
+	}
+	return match, strings.TrimPrefix(name, match.name+GatewaySeparator)
+}
+
+// prefixWriter writes each line written to it to w, prefixed, and with stamp
+// set, preceded by the time
+type prefixWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	prefix  string
+	stamp   bool
+	partial []byte
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.partial = append(p.partial, data...)
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			break
+		}
+		prefix := p.prefix
+		if p.stamp {
+			prefix = time.Now().UTC().Format(time.RFC3339) + " " + prefix
+		}
+		if _, err := fmt.Fprintf(p.w, "%s%s\n", prefix, p.partial[:i]); err != nil {
+			return 0, err
+		}
+		p.partial = p.partial[i+1:]
+	}
+	return len(data), nil
+}
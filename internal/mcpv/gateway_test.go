@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/socialviolation/mcpv/internal/mcp"
+)
+
+func TestGatewayRoute(t *testing.T) {
+	g := &Gateway{servers: []*gatewayServer{{name: "github"}, {name: "my__tools"}, {name: "my"}}}
+
+	for _, tc := range []struct {
+		name, server, original string
+	}{
+		{name: "github__search_issues", server: "github", original: "search_issues"},
+		{name: "github__a__b", server: "github", original: "a__b"},
+		{name: "my__tools__run", server: "my__tools", original: "run"},
+		{name: "my__run", server: "my", original: "run"},
+		{name: "github__", server: ""},
+		{name: "unknown__run", server: ""},
+		{name: "search", server: ""},
+	} {
+		server, original := g.route(tc.name)
+		name := ""
+		if server != nil {
+			name = server.name
+		}
+		if name != tc.server || original != tc.original {
+			t.Errorf("%s: routed to %q as %q, want %q as %q", tc.name, name, original, tc.server, tc.original)
+		}
+	}
+}
+
+func TestGatewayServe(t *testing.T) {
+	m, project := newTestManager(t)
+	alpha, beta, broken := fakeServer(nil), fakeServer(nil), fakeServer(map[string]string{"FAKE_EXIT": "3"})
+	alpha.Name, beta.Name, broken.Name = "alpha", "beta", "broken"
+	beta.Tools = &ToolFilter{Deny: []string{"echo"}}
+	configPath := writeProjectConfig(t, project, &ProjectConfig{Servers: []MCPServer{alpha, beta, broken}})
+
+	var log bytes.Buffer
+	g, err := m.StartGateway(configPath, 5*time.Second, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	if !strings.Contains(log.String(), "Warning: skipping broken") {
+		t.Errorf("the server that failed to start was not reported:\n%s", log.String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	clientIn, gatewayOut := io.Pipe()
+	gatewayIn, clientOut := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- g.Serve(ctx, gatewayIn, gatewayOut)
+	}()
+	client := mcp.NewClient(mcp.NewStreamTransport(clientIn, clientOut), ClientInfo)
+	defer client.Close()
+
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	if want := []string{"alpha__echo"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("listed tools %v, want %v", names, want)
+	}
+
+	call := func(name string) (string, error) {
+		var result struct {
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		}
+		params := map[string]interface{}{"name": name, "arguments": map[string]interface{}{"text": "hello"}}
+		if err := client.Call(ctx, "tools/call", params, &result); err != nil {
+			return "", err
+		}
+		if len(result.Content) != 1 {
+			return "", nil
+		}
+		return result.Content[0].Text, nil
+	}
+	// The fake server fails calls of tools other than echo
+	if text, err := call("alpha__echo"); err != nil || text != "hello" {
+		t.Errorf("alpha__echo answered %q, %v", text, err)
+	}
+	if _, err := call("beta__echo"); err == nil || !strings.Contains(err.Error(), "not allowed by mcpv.json") {
+		t.Errorf("expected the filtered tool to be refused, got %v", err)
+	}
+	if _, err := call("broken__echo"); err == nil || !strings.Contains(err.Error(), "unknown tool") {
+		t.Errorf("expected a tool of the skipped server to be unknown, got %v", err)
+	}
+
+	clientOut.Close()
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Error("Serve did not return when its input ended")
+	}
+}
@@ -0,0 +1,90 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/socialviolation/mcpv/internal/mcp"
+)
+
+// Inspection lists what a server exposes over MCP
+type Inspection struct {
+	Server            string                 `json:"server" yaml:"server"`
+	Version           string                 `json:"version,omitempty" yaml:"version,omitempty"`
+	URL               string                 `json:"url,omitempty" yaml:"url,omitempty"`
+	ProtocolVersion   string                 `json:"protocol_version" yaml:"protocol_version"`
+	ServerInfo        mcp.Implementation     `json:"server_info" yaml:"server_info"`
+	Tools             []mcp.Tool             `json:"tools" yaml:"tools"`
+	Resources         []mcp.Resource         `json:"resources" yaml:"resources"`
+	ResourceTemplates []mcp.ResourceTemplate `json:"resource_templates" yaml:"resource_templates"`
+	Prompts           []mcp.Prompt           `json:"prompts" yaml:"prompts"`
+}
+
+// InspectServer connects to a server, an installed one over stdio or a remote
+// one over HTTP, and lists the tools, resources, resource templates and
+// prompts it offers. Lists the server does not declare a capability for are
+// left empty.
+func (m *Manager) InspectServer(name, version, configPath string, timeout time.Duration) (*Inspection, error) {
+	server, err := m.ConnectableServer(name, version, configPath)
+	if err != nil {
+		return nil, err
+	}
+	return inspectServer(server, timeout)
+}
+
+// inspectServer lists what a resolved server offers
+func inspectServer(server *MCPServer, timeout time.Duration) (*Inspection, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	session, err := openSession(ctx, server, nil)
+	if err != nil {
+		return nil, withServerOutput(err)
+	}
+	defer session.close()
+
+	inspection := &Inspection{
+		Server:            server.Name,
+		Version:           server.Version,
+		URL:               server.URL,
+		ProtocolVersion:   session.initialized.ProtocolVersion,
+		ServerInfo:        session.initialized.ServerInfo,
+		Tools:             []mcp.Tool{},
+		Resources:         []mcp.Resource{},
+		ResourceTemplates: []mcp.ResourceTemplate{},
+		Prompts:           []mcp.Prompt{},
+	}
+
+	if session.hasCapability("tools") {
+		tools, err := session.client.ListTools(ctx)
+		if err != nil {
+			return nil, session.failure(err)
+		}
+		inspection.Tools = append(inspection.Tools, tools...)
+	}
+	if session.hasCapability("resources") {
+		resources, err := session.client.ListResources(ctx)
+		if err != nil {
+			return nil, session.failure(err)
+		}
+		inspection.Resources = append(inspection.Resources, resources...)
+
+		// Servers with only fixed resources may not implement templates
+		templates, err := session.client.ListResourceTemplates(ctx)
+		var rpcErr *mcp.Error
+		if err != nil && !(errors.As(err, &rpcErr) && rpcErr.Code == mcp.CodeMethodNotFound) {
+			return nil, session.failure(err)
+		}
+		inspection.ResourceTemplates = append(inspection.ResourceTemplates, templates...)
+	}
+	if session.hasCapability("prompts") {
+		prompts, err := session.client.ListPrompts(ctx)
+		if err != nil {
+			return nil, session.failure(err)
+		}
+		inspection.Prompts = append(inspection.Prompts, prompts...)
+	}
+
+	return inspection, nil
+}
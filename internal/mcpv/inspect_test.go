@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/socialviolation/mcpv/internal/mcp"
+)
+
+func TestInspectServer(t *testing.T) {
+	m, project := newTestManager(t)
+	configPath := writeProjectConfig(t, project, &ProjectConfig{Servers: []MCPServer{
+		fakeServer(map[string]string{"FAKE_CAPABILITIES": "tools,resources,prompts"}),
+	}})
+
+	inspection, err := m.InspectServer("fake", "", configPath, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Inspection{
+		Server:          "fake",
+		ProtocolVersion: "2025-03-26",
+		ServerInfo:      mcp.Implementation{Name: "fake", Version: "1.0.0"},
+		Tools: []mcp.Tool{
+			{Name: "echo", Description: "Echo the text", InputSchema: map[string]interface{}{"type": "object"}},
+		},
+		Resources: []mcp.Resource{{URI: "file:///notes.txt", Name: "notes", MimeType: "text/plain"}},
+		// The fake server does not implement resources/templates/list
+		ResourceTemplates: []mcp.ResourceTemplate{},
+		Prompts: []mcp.Prompt{
+			{Name: "greet", Arguments: []mcp.PromptArgument{{Name: "who", Required: true}}},
+		},
+	}
+	if !reflect.DeepEqual(inspection, want) {
+		t.Fatalf("got inspection %+v, want %+v", inspection, want)
+	}
+
+	// 'mcpv inspect -o json' output is read back by 'mcpv diff'
+	data, err := json.Marshal(inspection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"server", "protocol_version", "server_info", "tools", "resources", "resource_templates", "prompts"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("the JSON inspection has no %s: %s", key, data)
+		}
+	}
+	var read Inspection
+	if err := json.Unmarshal(data, &read); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&read, inspection) {
+		t.Errorf("read back %+v from JSON, want %+v", read, inspection)
+	}
+}
+
+func TestInspectServerWithoutCapabilities(t *testing.T) {
+	m, project := newTestManager(t)
+	configPath := writeProjectConfig(t, project, &ProjectConfig{Servers: []MCPServer{fakeServer(nil)}})
+
+	inspection, err := m.InspectServer("fake", "", configPath, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inspection.Tools) != 1 || inspection.Resources == nil || len(inspection.Resources) != 0 || inspection.Prompts == nil || len(inspection.Prompts) != 0 {
+		t.Errorf("lists without a capability are not empty: %+v", inspection)
+	}
+}
@@ -0,0 +1,286 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dotEnvFileName is the file next to mcpv.json that defines project variables
+const dotEnvFileName = ".env"
+
+// projectVariables resolves ${VAR} and ${VAR:-default} references in the env,
+// args and headers of servers. Variables come from the process environment,
+// then from the secrets stored for the server, then from the project's .env
+// file.
+type projectVariables struct {
+	dotEnv map[string]string
+
+	// references writes references to variables instead of their values
+	// into agents that can resolve them themselves
+	references bool
+
+	// secrets returns the secrets stored for a server; serverSecrets caches
+	// them by server
+	secrets       func(server string) (map[string]string, error)
+	serverSecrets map[string]map[string]string
+
+	// warned records undefined variables and unreadable secrets that were
+	// already reported to out
+	warned map[string]bool
+	out    io.Writer
+}
+
+// loadProjectVariables reads the .env file next to a mcpv.json config file
+func loadProjectVariables(configPath string, references bool) (*projectVariables, error) {
+	if configPath == "" {
+		configPath = "mcpv.json"
+	}
+	variables := &projectVariables{
+		dotEnv:        map[string]string{},
+		references:    references,
+		serverSecrets: map[string]map[string]string{},
+		warned:        map[string]bool{},
+		out:           io.Discard,
+	}
+
+	path := filepath.Join(filepath.Dir(configPath), dotEnvFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return variables, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if variables.dotEnv, err = parseDotEnv(data); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return variables, nil
+}
+
+// projectVariables sets up variable resolution for a project, including the
+// secrets stored for its servers
+func (m *Manager) projectVariables(configPath string, references bool) (*projectVariables, error) {
+	variables, err := loadProjectVariables(configPath, references)
+	if err != nil {
+		return nil, err
+	}
+	variables.secrets = m.serverSecrets
+	variables.out = m.out
+	return variables, nil
+}
+
+// serverExpansion expands the references in one server's settings
+type serverExpansion struct {
+	variables *projectVariables
+	server    string
+	secrets   map[string]string
+
+	// reference is the agent's format for referring to an environment
+	// variable, or empty to write values
+	reference string
+}
+
+// lookup returns the value of a variable, reporting whether it comes from the
+// process environment
+func (e *serverExpansion) lookup(name string) (value string, fromEnv, ok bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true, true
+	}
+	if value, ok := e.secrets[name]; ok {
+		return value, false, true
+	}
+	value, ok = e.variables.dotEnv[name]
+	return value, false, ok
+}
+
+// server returns a copy of the server with references in its env, args and
+// headers replaced. reference is the agent's format for referring to an
+// environment variable, with %s standing for the name; when references are
+// enabled and the agent has one, variables without a default that are set in
+// the process environment are written as references for the agent, which
+// inherits that environment, to resolve. Variables from secrets or .env are
+// always written as values, since agents cannot resolve them, and secrets are
+// added to the env of local servers.
+func (v *projectVariables) server(server *MCPServer, reference string) *MCPServer {
+	if !v.references {
+		reference = ""
+	}
+	e := &serverExpansion{variables: v, server: server.Name, secrets: v.secretsOf(server.Name), reference: reference}
+
+	expanded := *server
+	if server.Args != nil {
+		expanded.Args = make([]string, len(server.Args))
+		for i, arg := range server.Args {
+			expanded.Args[i] = e.expand(arg)
+		}
+	}
+	expanded.Env = e.expandMap(server.Env)
+	expanded.Headers = e.expandMap(server.Headers)
+
+	if !server.IsRemote() && len(e.secrets) > 0 {
+		env := make(map[string]string, len(expanded.Env)+len(e.secrets))
+		for name, value := range e.secrets {
+			env[name] = value
+		}
+		for name, value := range expanded.Env {
+			env[name] = value
+		}
+		expanded.Env = env
+	}
+	return &expanded
+}
+
+// secretsOf returns the secrets stored for a server, reading them once
+func (v *projectVariables) secretsOf(server string) map[string]string {
+	if v.secrets == nil {
+		return nil
+	}
+	if secrets, ok := v.serverSecrets[server]; ok {
+		return secrets
+	}
+
+	secrets, err := v.secrets(server)
+	if err != nil {
+		v.warn("secrets:"+server, "Warning: %v", err)
+	}
+	v.serverSecrets[server] = secrets
+	return secrets
+}
+
+// expandMap expands the values of a map
+func (e *serverExpansion) expandMap(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	expanded := make(map[string]string, len(values))
+	for key, value := range values {
+		expanded[key] = e.expand(value)
+	}
+	return expanded
+}
+
+// expand replaces ${VAR} and ${VAR:-default} references in a value. $${ is
+// written as a literal ${. Undefined variables without a default are replaced
+// by an empty string and reported once.
+func (e *serverExpansion) expand(value string) string {
+	var result strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			result.WriteString(value)
+			return result.String()
+		}
+		if start > 0 && value[start-1] == '$' {
+			result.WriteString(value[:start-1])
+			result.WriteString("${")
+			value = value[start+2:]
+			continue
+		}
+
+		end := strings.Index(value[start:], "}")
+		if end < 0 {
+			result.WriteString(value)
+			return result.String()
+		}
+		end += start
+
+		name, fallback, hasDefault := strings.Cut(value[start+2:end], ":-")
+		result.WriteString(value[:start])
+		if !isVariableName(name) {
+			result.WriteString(value[start : end+1])
+			value = value[end+1:]
+			continue
+		}
+
+		resolved, fromEnv, ok := e.lookup(name)
+		switch {
+		case e.reference != "" && !hasDefault && fromEnv:
+			result.WriteString(fmt.Sprintf(e.reference, name))
+		case !ok || (hasDefault && resolved == ""):
+			if !hasDefault {
+				e.variables.warn(name, "Warning: variable %s used by server %s is not set in the environment, its secrets or %s", name, e.server, dotEnvFileName)
+			}
+			result.WriteString(fallback)
+		default:
+			result.WriteString(resolved)
+		}
+		value = value[end+1:]
+	}
+}
+
+// warn prints a warning the first time it is raised for a key
+func (v *projectVariables) warn(key, format string, args ...interface{}) {
+	if v.warned[key] {
+		return
+	}
+	v.warned[key] = true
+	fmt.Fprintf(v.out, format+"\n", args...)
+}
+
+// isVariableName reports whether name is a valid environment variable name
+func isVariableName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// parseDotEnv parses KEY=value lines. Lines may start with export; values may
+// be single-quoted (literal), double-quoted (with \n, \", \\ escapes) or bare.
+// A # preceded by a space after the value starts a comment.
+func parseDotEnv(data []byte) (map[string]string, error) {
+	values := map[string]string{}
+	for number, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !isVariableName(key) {
+			return nil, fmt.Errorf("line %d: expected KEY=value", number+1)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quote", number+1)
+			}
+			value = value[1 : end+1]
+		case strings.HasPrefix(value, `"`):
+			end := 1
+			for end < len(value) && value[end] != '"' {
+				if value[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(value) {
+				return nil, fmt.Errorf("line %d: unterminated quote", number+1)
+			}
+			value = strings.NewReplacer(`\n`, "\n", `\"`, `"`, `\\`, `\`).Replace(value[1:end])
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		values[key] = value
+	}
+	return values, nil
+}
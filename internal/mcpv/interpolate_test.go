@@ -0,0 +1,150 @@
+package manager
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	t.Setenv("MCPV_TEST_SET", "from-env")
+	t.Setenv("MCPV_TEST_EMPTY", "")
+
+	tests := []struct {
+		value string
+		want  string
+		warns bool
+	}{
+		{"plain", "plain", false},
+		{"${MCPV_TEST_SET}", "from-env", false},
+		{"Bearer ${MCPV_TEST_SET}!", "Bearer from-env!", false},
+		{"${MCPV_TEST_UNSET:-fallback}", "fallback", false},
+		{"${MCPV_TEST_EMPTY:-fallback}", "fallback", false},
+		{"${MCPV_TEST_SET:-fallback}", "from-env", false},
+		{"${MCPV_TEST_UNSET:-}", "", false},
+		{"${MCPV_TEST_UNSET}", "", true},
+		{"$${MCPV_TEST_SET}", "${MCPV_TEST_SET}", false},
+		{"$$${MCPV_TEST_SET}", "$${MCPV_TEST_SET}", false},
+		{"${FROM_DOTENV}/${FROM_SECRETS}", "dotenv/secret", false},
+		{"${not a name}", "${not a name}", false},
+		{"${MCPV_TEST_SET", "${MCPV_TEST_SET", false},
+		{"$MCPV_TEST_SET", "$MCPV_TEST_SET", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var out bytes.Buffer
+			variables := &projectVariables{
+				dotEnv: map[string]string{"FROM_DOTENV": "dotenv", "FROM_SECRETS": "shadowed"},
+				warned: map[string]bool{},
+				out:    &out,
+			}
+			e := &serverExpansion{variables: variables, server: "srv", secrets: map[string]string{"FROM_SECRETS": "secret"}}
+
+			if got := e.expand(tt.value); got != tt.want {
+				t.Errorf("expand(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+			if warned := strings.Contains(out.String(), "is not set"); warned != tt.warns {
+				t.Errorf("expand(%q) warned %v, want %v: %q", tt.value, warned, tt.warns, out.String())
+			}
+		})
+	}
+}
+
+func TestEnvReferencesOnlyForEnvironmentVariables(t *testing.T) {
+	t.Setenv("FROM_ENV", "env")
+	variables := &projectVariables{
+		dotEnv:        map[string]string{"FROM_DOTENV": "dotenv"},
+		references:    true,
+		serverSecrets: map[string]map[string]string{},
+		secrets: func(string) (map[string]string, error) {
+			return map[string]string{"FROM_SECRETS": "secret"}, nil
+		},
+		warned: map[string]bool{},
+		out:    &bytes.Buffer{},
+	}
+	server := &MCPServer{Name: "srv", Command: "srv", Env: map[string]string{
+		"A": "${FROM_ENV}",
+		"B": "${FROM_DOTENV}",
+		"C": "${FROM_SECRETS}",
+		"D": "${MCPV_TEST_UNSET:-fallback}",
+		"E": "${MCPV_TEST_UNSET}",
+	}}
+
+	got := variables.server(server, "${env:%s}").Env
+	want := map[string]string{
+		"A":            "${env:FROM_ENV}",
+		"B":            "dotenv",
+		"C":            "secret",
+		"D":            "fallback",
+		"E":            "",
+		"FROM_SECRETS": "secret",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got env %q, want %q", got, want)
+	}
+}
+
+func TestUndefinedVariableWarnsOnce(t *testing.T) {
+	m, project := newTestManager(t)
+	var out bytes.Buffer
+	m.SetOutput(&out)
+	m.UseProject(writeProjectConfig(t, project, &ProjectConfig{}))
+
+	settings, err := m.projectSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &MCPServer{Name: "srv", Command: "srv", Args: []string{"${MCPV_TEST_UNSET}"}, Env: map[string]string{"TOKEN": "${MCPV_TEST_UNSET}"}}
+	settings.variables.server(server, "")
+	settings.variables.server(server, "")
+
+	if warnings := strings.Count(out.String(), "variable MCPV_TEST_UNSET used by server srv is not set"); warnings != 1 {
+		t.Errorf("expected one warning on the manager's output, got:\n%s", out.String())
+	}
+}
+
+func TestParseDotEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr string
+	}{
+		{"bare", "KEY=value", map[string]string{"KEY": "value"}, ""},
+		{"spaces", "  KEY = value  ", map[string]string{"KEY": "value"}, ""},
+		{"empty", "KEY=", map[string]string{"KEY": ""}, ""},
+		{"export", "export KEY=value", map[string]string{"KEY": "value"}, ""},
+		{"comments and blank lines", "# comment\n\nKEY=value\n  # indented comment\n", map[string]string{"KEY": "value"}, ""},
+		{"trailing comment", "KEY=value # comment", map[string]string{"KEY": "value"}, ""},
+		{"hash in value", "KEY=a#b", map[string]string{"KEY": "a#b"}, ""},
+		{"equals in value", "KEY=a=b", map[string]string{"KEY": "a=b"}, ""},
+		{"single quotes", `KEY='a \n "b" # c'`, map[string]string{"KEY": `a \n "b" # c`}, ""},
+		{"double quotes", `KEY="a\nb \"c\" \\ # d" # comment`, map[string]string{"KEY": "a\nb \"c\" \\ # d"}, ""},
+		{"CRLF", "A=1\r\nB='2'\r\n", map[string]string{"A": "1", "B": "2"}, ""},
+		{"later wins", "KEY=first\nKEY=second", map[string]string{"KEY": "second"}, ""},
+		{"missing equals", "KEY", nil, "line 1: expected KEY=value"},
+		{"invalid name", "A=1\n1KEY=value", nil, "line 2: expected KEY=value"},
+		{"unterminated single quote", "KEY='value", nil, "line 1: unterminated quote"},
+		{"unterminated double quote", `KEY="value\"`, nil, "line 1: unterminated quote"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDotEnv([]byte(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
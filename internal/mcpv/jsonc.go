@@ -0,0 +1,717 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// This file implements just enough of a JSON-with-comments parser to edit agent
+// configuration files in place. Values are located by byte offset so an edit
+// only rewrites the text of the member it touches, leaving key order,
+// indentation, comments and trailing commas elsewhere in the file untouched.
+
+// jsoncKind is the kind of a parsed JSON value
+type jsoncKind int
+
+const (
+	jsoncScalar jsoncKind = iota
+	jsoncObject
+	jsoncArray
+)
+
+// jsoncNode is a value in a JSON document together with the offsets it occupies
+type jsoncNode struct {
+	kind     jsoncKind
+	start    int
+	end      int
+	members  []*jsoncMember
+	elements []*jsoncNode
+}
+
+// jsoncMember is a key/value pair of an object. commaEnd is the offset just
+// past the comma following the value, or -1 when there is none.
+type jsoncMember struct {
+	key      string
+	keyStart int
+	value    *jsoncNode
+	commaEnd int
+}
+
+// jsoncField is a key/value pair of an object rendered in a fixed order
+type jsoncField struct {
+	Key   string
+	Value interface{}
+}
+
+// orderedObject is a JSON object whose keys are written in the given order
+type orderedObject []jsoncField
+
+// MarshalJSON renders the fields in order
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := marshalJSONValue(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalJSONValue(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsoncParser parses JSON that may contain comments and trailing commas
+type jsoncParser struct {
+	src []byte
+	pos int
+}
+
+// parseJSONC parses a document, returning nil for an empty one
+func parseJSONC(src []byte) (*jsoncNode, error) {
+	p := &jsoncParser{src: src}
+	if err := p.skipSpace(); err != nil {
+		return nil, err
+	}
+	if p.pos == len(src) {
+		return nil, nil
+	}
+
+	node, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.skipSpace(); err != nil {
+		return nil, err
+	}
+	if p.pos != len(src) {
+		return nil, p.errorf("unexpected %q after top-level value", src[p.pos])
+	}
+	return node, nil
+}
+
+func (p *jsoncParser) errorf(format string, args ...interface{}) error {
+	line := bytes.Count(p.src[:min(p.pos, len(p.src))], []byte("\n")) + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips whitespace and comments
+func (p *jsoncParser) skipSpace() error {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			p.pos++
+		case bytes.HasPrefix(p.src[p.pos:], []byte("//")):
+			end := bytes.IndexByte(p.src[p.pos:], '\n')
+			if end < 0 {
+				p.pos = len(p.src)
+			} else {
+				p.pos += end + 1
+			}
+		case bytes.HasPrefix(p.src[p.pos:], []byte("/*")):
+			end := bytes.Index(p.src[p.pos+2:], []byte("*/"))
+			if end < 0 {
+				return p.errorf("unterminated comment")
+			}
+			p.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *jsoncParser) parseValue() (*jsoncNode, error) {
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of input")
+	}
+
+	switch p.src[p.pos] {
+	case '{':
+		return p.parseObject()
+	case '[':
+		return p.parseArray()
+	case '"':
+		start := p.pos
+		if err := p.skipString(); err != nil {
+			return nil, err
+		}
+		return &jsoncNode{kind: jsoncScalar, start: start, end: p.pos}, nil
+	default:
+		start := p.pos
+		for p.pos < len(p.src) && !strings.ContainsRune(" \t\r\n,:]}/", rune(p.src[p.pos])) {
+			p.pos++
+		}
+		if p.pos == start {
+			return nil, p.errorf("unexpected %q", p.src[p.pos])
+		}
+		var value interface{}
+		if err := json.Unmarshal(p.src[start:p.pos], &value); err != nil {
+			return nil, p.errorf("invalid value %q", p.src[start:p.pos])
+		}
+		return &jsoncNode{kind: jsoncScalar, start: start, end: p.pos}, nil
+	}
+}
+
+func (p *jsoncParser) skipString() error {
+	p.pos++
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos += 2
+		case '"':
+			p.pos++
+			return nil
+		case '\n':
+			return p.errorf("unterminated string")
+		default:
+			p.pos++
+		}
+	}
+	return p.errorf("unterminated string")
+}
+
+func (p *jsoncParser) parseObject() (*jsoncNode, error) {
+	node := &jsoncNode{kind: jsoncObject, start: p.pos}
+	p.pos++
+
+	for {
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated object")
+		}
+		if p.src[p.pos] == '}' {
+			p.pos++
+			node.end = p.pos
+			return node, nil
+		}
+		if len(node.members) > 0 && node.members[len(node.members)-1].commaEnd < 0 {
+			return nil, p.errorf("expected ',' or '}'")
+		}
+		if p.src[p.pos] != '"' {
+			return nil, p.errorf("expected object key")
+		}
+
+		keyStart := p.pos
+		if err := p.skipString(); err != nil {
+			return nil, err
+		}
+		var key string
+		if err := json.Unmarshal(p.src[keyStart:p.pos], &key); err != nil {
+			return nil, p.errorf("invalid object key")
+		}
+
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.src) || p.src[p.pos] != ':' {
+			return nil, p.errorf("expected ':' after object key")
+		}
+		p.pos++
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		member := &jsoncMember{key: key, keyStart: keyStart, value: value, commaEnd: -1}
+		node.members = append(node.members, member)
+
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+			member.commaEnd = p.pos
+		}
+	}
+}
+
+func (p *jsoncParser) parseArray() (*jsoncNode, error) {
+	node := &jsoncNode{kind: jsoncArray, start: p.pos}
+	p.pos++
+	expectValue := true
+
+	for {
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.src[p.pos] == ']' {
+			p.pos++
+			node.end = p.pos
+			return node, nil
+		}
+		if !expectValue {
+			return nil, p.errorf("expected ',' or ']'")
+		}
+
+		element, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		node.elements = append(node.elements, element)
+
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		expectValue = p.pos < len(p.src) && p.src[p.pos] == ','
+		if expectValue {
+			p.pos++
+		}
+	}
+}
+
+// member returns the member of an object with the given key
+func (n *jsoncNode) member(key string) *jsoncMember {
+	if n == nil || n.kind != jsoncObject {
+		return nil
+	}
+	for _, member := range n.members {
+		if member.key == key {
+			return member
+		}
+	}
+	return nil
+}
+
+// decode converts the node into the value encoding/json would produce
+func (n *jsoncNode) decode(src []byte) (interface{}, error) {
+	switch n.kind {
+	case jsoncObject:
+		object := make(map[string]interface{}, len(n.members))
+		for _, member := range n.members {
+			value, err := member.value.decode(src)
+			if err != nil {
+				return nil, err
+			}
+			object[member.key] = value
+		}
+		return object, nil
+	case jsoncArray:
+		array := make([]interface{}, 0, len(n.elements))
+		for _, element := range n.elements {
+			value, err := element.decode(src)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	default:
+		var value interface{}
+		if err := json.Unmarshal(src[n.start:n.end], &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+}
+
+// jsoncEditor applies in-place edits to a JSON document
+type jsoncEditor struct {
+	src  []byte
+	root *jsoncNode
+}
+
+// newJSONCEditor parses a document for editing
+func newJSONCEditor(src []byte) (*jsoncEditor, error) {
+	root, err := parseJSONC(src)
+	if err != nil {
+		return nil, err
+	}
+	if root != nil && root.kind != jsoncObject {
+		return nil, fmt.Errorf("top-level value is not an object")
+	}
+	return &jsoncEditor{src: src, root: root}, nil
+}
+
+// replace substitutes src[start:end] with text and re-parses the document
+func (e *jsoncEditor) replace(start, end int, text string) error {
+	var buf bytes.Buffer
+	buf.Write(e.src[:start])
+	buf.WriteString(text)
+	buf.Write(e.src[end:])
+
+	root, err := parseJSONC(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("edit produced invalid JSON: %w", err)
+	}
+	e.src = buf.Bytes()
+	e.root = root
+	return nil
+}
+
+// lookup returns the node at a path of object keys, or nil
+func (e *jsoncEditor) lookup(path []string) *jsoncNode {
+	node := e.root
+	for _, key := range path {
+		member := node.member(key)
+		if member == nil {
+			return nil
+		}
+		node = member.value
+	}
+	return node
+}
+
+// indentUnit guesses the indentation step used by the document
+func (e *jsoncEditor) indentUnit() string {
+	if e.root != nil {
+		for _, member := range e.root.members {
+			if indent, ownLine := lineIndent(e.src, member.keyStart); ownLine && indent != "" {
+				return indent
+			}
+		}
+	}
+	return "  "
+}
+
+// lineIndent returns the whitespace that starts the line containing offset and
+// whether offset is the first non-whitespace character on that line
+func lineIndent(src []byte, offset int) (string, bool) {
+	lineStart := bytes.LastIndexByte(src[:offset], '\n') + 1
+	prefix := src[lineStart:offset]
+	trimmed := bytes.TrimLeft(prefix, " \t")
+	indentLen := len(prefix) - len(trimmed)
+	return string(prefix[:indentLen]), len(trimmed) == 0
+}
+
+// lineEnd returns the offset of the newline ending the line containing offset
+func lineEnd(src []byte, offset int) int {
+	if i := bytes.IndexByte(src[offset:], '\n'); i >= 0 {
+		return offset + i
+	}
+	return len(src)
+}
+
+// renderValue marshals a value for insertion at the given indentation
+func (e *jsoncEditor) renderValue(value interface{}, indent string) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent(indent, e.indentUnit())
+	if err := enc.Encode(value); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// memberIndent returns the indentation used for members of an object
+func (e *jsoncEditor) memberIndent(object *jsoncNode) string {
+	if len(object.members) > 0 {
+		if indent, ownLine := lineIndent(e.src, object.members[0].keyStart); ownLine {
+			return indent
+		}
+	}
+	indent, _ := lineIndent(e.src, object.start)
+	return indent + e.indentUnit()
+}
+
+// isMultiline reports whether a node spans more than one line
+func (e *jsoncEditor) isMultiline(node *jsoncNode) bool {
+	return bytes.IndexByte(e.src[node.start:node.end], '\n') >= 0
+}
+
+// ensureRoot gives an empty document a top-level object
+func (e *jsoncEditor) ensureRoot() error {
+	if e.root != nil {
+		return nil
+	}
+	return e.replace(0, len(e.src), strings.TrimRight(string(e.src), " \t\r\n")+"{}\n")
+}
+
+// ensureObject returns the object at a path of keys, creating missing objects.
+// A null along the path is replaced by an object; any other value is an error
+// rather than being overwritten.
+func (e *jsoncEditor) ensureObject(path []string) (*jsoncNode, error) {
+	if err := e.ensureRoot(); err != nil {
+		return nil, err
+	}
+
+	for i := range path {
+		node := e.lookup(path[:i+1])
+		if node != nil && node.kind == jsoncObject {
+			continue
+		}
+		if node != nil && (node.kind != jsoncScalar || string(e.src[node.start:node.end]) != "null") {
+			return nil, fmt.Errorf("%s is not an object", strings.Join(path[:i+1], "."))
+		}
+		if err := e.setMember(path[:i], path[i], orderedObject{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return e.lookup(path), nil
+}
+
+// setMember sets key in the object at path to value. An existing member has
+// only its value replaced; a new member is appended after the last one using
+// the object's indentation.
+func (e *jsoncEditor) setMember(path []string, key string, value interface{}) error {
+	object := e.lookup(path)
+	if object == nil || object.kind != jsoncObject {
+		return fmt.Errorf("%s is not an object", strings.Join(path, "."))
+	}
+
+	if member := object.member(key); member != nil {
+		indent, _ := lineIndent(e.src, member.keyStart)
+		if !e.isMultiline(object) {
+			indent = ""
+		}
+		text, err := e.renderValue(value, indent)
+		if err != nil {
+			return err
+		}
+		if !e.isMultiline(object) {
+			text = compactJSON(text)
+		}
+		return e.replace(member.value.start, member.value.end, text)
+	}
+
+	keyText, err := marshalJSONValue(key)
+	if err != nil {
+		return err
+	}
+
+	// Objects written on a single line stay on a single line
+	if len(object.members) > 0 && !e.isMultiline(object) {
+		valueText, err := e.renderValue(value, "")
+		if err != nil {
+			return err
+		}
+		last := object.members[len(object.members)-1]
+		memberText := string(keyText) + ": " + compactJSON(valueText)
+		if last.commaEnd >= 0 {
+			return e.replace(last.commaEnd, last.commaEnd, " "+memberText+",")
+		}
+		return e.replace(last.value.end, last.value.end, ", "+memberText)
+	}
+
+	indent := e.memberIndent(object)
+	valueText, err := e.renderValue(value, indent)
+	if err != nil {
+		return err
+	}
+	memberText := indent + string(keyText) + ": " + valueText
+
+	if len(object.members) == 0 {
+		// Insert below the line of the opening brace so comments left inside
+		// the object stay where they are
+		closing := object.end - 1
+		if insertAt := lineEnd(e.src, object.start); insertAt < closing {
+			return e.replace(insertAt, insertAt, "\n"+memberText)
+		}
+		insertAt := closing
+		for insertAt > object.start+1 && (e.src[insertAt-1] == ' ' || e.src[insertAt-1] == '\t') {
+			insertAt--
+		}
+		closeIndent, _ := lineIndent(e.src, object.start)
+		return e.replace(insertAt, closing, "\n"+memberText+"\n"+closeIndent)
+	}
+
+	// Insert after the last member, keeping any comment that trails it on its
+	// line attached to it, and keep a trailing comma style if the file uses one
+	last := object.members[len(object.members)-1]
+	insertAt := lineEnd(e.src, max(last.value.end, last.commaEnd))
+	closing := object.end - 1
+	if insertAt > closing {
+		insertAt = closing
+	}
+	// Step back over whitespace so the new member lands before the line break
+	for insertAt > 0 && (e.src[insertAt-1] == ' ' || e.src[insertAt-1] == '\t') && insertAt-1 >= max(last.value.end, last.commaEnd) {
+		insertAt--
+	}
+
+	if last.commaEnd >= 0 {
+		return e.replace(insertAt, insertAt, "\n"+memberText+",")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(e.src[:last.value.end])
+	buf.WriteByte(',')
+	buf.Write(e.src[last.value.end:insertAt])
+	buf.WriteString("\n" + memberText)
+	buf.Write(e.src[insertAt:])
+	return e.replace(0, len(e.src), buf.String())
+}
+
+// removeMember deletes key from the object at path, reporting whether it existed
+func (e *jsoncEditor) removeMember(path []string, key string) (bool, error) {
+	object := e.lookup(path)
+	if object == nil || object.kind != jsoncObject {
+		return false, nil
+	}
+
+	index := -1
+	for i, member := range object.members {
+		if member.key == key {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return false, nil
+	}
+
+	member := object.members[index]
+	start := member.keyStart
+	end := member.value.end
+	if member.commaEnd >= 0 {
+		end = member.commaEnd
+	}
+	text := ""
+
+	if _, ownLine := lineIndent(e.src, start); ownLine && e.isMultiline(object) {
+		indent, _ := lineIndent(e.src, start)
+		lineStart := bytes.LastIndexByte(e.src[:start], '\n') + 1
+		lineStop := lineEnd(e.src, end)
+		closing := object.end - 1
+		rest := bytes.TrimLeft(e.src[end:min(lineStop, closing)], " \t\r")
+		switch {
+		case lineStop >= closing:
+			start, end = lineStart, closing
+		case len(rest) == 0:
+			// Remove the whole line
+			start, end = lineStart, lineStop+1
+		case bytes.HasPrefix(rest, []byte("//")) || bytes.HasPrefix(rest, []byte("/*")):
+			// Keep a comment trailing the member on its own line
+			start, end = lineStart, lineStop-len(bytes.TrimLeft(e.src[end:lineStop], " \t\r"))
+			text = indent
+		default:
+			// Another member follows on the same line
+			for end < len(e.src) && (e.src[end] == ' ' || e.src[end] == '\t') {
+				end++
+			}
+		}
+	} else if member.commaEnd >= 0 {
+		// Remove the space separating this member from the next one
+		for end < len(e.src) && (e.src[end] == ' ' || e.src[end] == '\t') {
+			end++
+		}
+	}
+
+	// Drop the whitespace in front of the member when nothing but a closing
+	// brace or a comment follows it, so none is left trailing
+	last := index == len(object.members)-1 && member.commaEnd < 0
+	if text == "" && (last || end == len(e.src) || e.src[end] == '\n' || e.src[end] == '\r') {
+		for start > 0 && (e.src[start-1] == ' ' || e.src[start-1] == '\t') {
+			start--
+		}
+	}
+
+	var buf bytes.Buffer
+	if index > 0 && last {
+		// The last member without a trailing comma takes the previous comma with it
+		previous := object.members[index-1]
+		buf.Write(e.src[:previous.commaEnd-1])
+		buf.Write(e.src[previous.commaEnd:start])
+	} else {
+		buf.Write(e.src[:start])
+	}
+	buf.WriteString(text)
+	buf.Write(e.src[end:])
+	if err := e.replace(0, len(e.src), buf.String()); err != nil {
+		return true, err
+	}
+
+	// An object left with nothing but whitespace is written as {}
+	object = e.lookup(path)
+	if len(object.members) == 0 && len(bytes.TrimSpace(e.src[object.start+1:object.end-1])) == 0 {
+		return true, e.replace(object.start, object.end, "{}")
+	}
+	return true, nil
+}
+
+// setObjectFields updates the object at path so the given fields have the
+// given values, editing only fields whose values differ. Fields with a nil
+// value are removed. Other fields of the object are left untouched.
+func (e *jsoncEditor) setObjectFields(path []string, fields orderedObject) error {
+	object := e.lookup(path)
+	if object == nil || object.kind != jsoncObject {
+		parent, key := path[:len(path)-1], path[len(path)-1]
+		var present orderedObject
+		for _, field := range fields {
+			if field.Value != nil {
+				present = append(present, field)
+			}
+		}
+		return e.setMember(parent, key, present)
+	}
+
+	for _, field := range fields {
+		if field.Value == nil {
+			if _, err := e.removeMember(path, field.Key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if member := e.lookup(path).member(field.Key); member != nil {
+			existing, err := member.value.decode(e.src)
+			if err == nil && jsonEqual(existing, field.Value) {
+				continue
+			}
+		}
+		if err := e.setMember(path, field.Key, field.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// jsonEqual reports whether two values have the same JSON representation
+func jsonEqual(a, b interface{}) bool {
+	aData, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bData, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+
+	var aValue, bValue interface{}
+	if json.Unmarshal(aData, &aValue) != nil || json.Unmarshal(bData, &bValue) != nil {
+		return false
+	}
+	return reflect.DeepEqual(aValue, bValue)
+}
+
+// marshalJSONValue marshals a value without escaping HTML characters
+func marshalJSONValue(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// compactJSON removes insignificant whitespace from rendered JSON
+func compactJSON(text string) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(text)); err != nil {
+		return text
+	}
+	return buf.String()
+}
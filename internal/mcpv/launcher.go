@@ -0,0 +1,194 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Launchers for local servers in agent configurations. With a launcher, agents
+// run servers through mcpv, which resolves the version from the nearest
+// mcpv.json and mcpv.lock each time a server starts, so agent configurations
+// do not change when servers are upgraded.
+const (
+	// LauncherExec configures servers as "mcpv exec <name>"
+	LauncherExec = "exec"
+	// LauncherShim configures servers as a shim in the bin directory of the
+	// mcpv data directory that runs "mcpv exec <name>"
+	LauncherShim = "shim"
+	// LauncherServe replaces the entries of all servers, local and remote,
+	// with a single GatewayEntryName entry that runs "mcpv serve"
+	LauncherServe = "serve"
+)
+
+// GatewayEntryName is the name of the agent entry for "mcpv serve"
+const GatewayEntryName = "mcpv"
+
+// shimDirName is the directory of the data directory shims are written to
+const shimDirName = "bin"
+
+// serverLauncher rewrites the agent entries of local servers to run through mcpv
+type serverLauncher struct {
+	kind       string
+	executable string
+	shimDir    string
+
+	// shims names the servers whose shims entries refer to
+	shims map[string]bool
+
+	// config is the mcpv.json entries name, for agent configurations that
+	// are not read from within the project
+	config string
+}
+
+// newServerLauncher returns the launcher configured by mcpv.json, or nil when
+// agents should run servers directly
+func (m *Manager) newServerLauncher(kind string) (*serverLauncher, error) {
+	switch kind {
+	case "":
+		return nil, nil
+	case LauncherExec, LauncherShim, LauncherServe:
+	default:
+		return nil, fmt.Errorf("unsupported launcher %s; use %s, %s or %s", kind, LauncherExec, LauncherShim, LauncherServe)
+	}
+
+	executable, err := mcpvExecutable()
+	if err != nil {
+		return nil, err
+	}
+
+	return &serverLauncher{
+		kind:       kind,
+		executable: executable,
+		shimDir:    filepath.Join(m.dataDir, shimDirName),
+		shims:      map[string]bool{},
+	}, nil
+}
+
+// mcpvExecutable returns the path agents run mcpv by. The mcpv found on PATH is
+// preferred when it is the running executable: package managers link it to a
+// versioned install directory, and the link survives upgrades where the
+// target does not.
+func mcpvExecutable() (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the mcpv executable: %w", err)
+	}
+
+	if path, err := exec.LookPath("mcpv"); err == nil {
+		if path, err := filepath.Abs(path); err == nil {
+			running, errRunning := os.Stat(executable)
+			found, errFound := os.Stat(path)
+			if errRunning == nil && errFound == nil && os.SameFile(running, found) {
+				return path, nil
+			}
+		}
+	}
+	return executable, nil
+}
+
+// withConfig returns a launcher whose entries run mcpv with the given
+// mcpv.json instead of the one nearest to where the agent starts them
+func (l *serverLauncher) withConfig(configPath string) *serverLauncher {
+	if l == nil {
+		return nil
+	}
+	pinned := *l
+	pinned.config = configPath
+	return &pinned
+}
+
+// configArgs returns the arguments naming the launcher's mcpv.json, if any
+func (l *serverLauncher) configArgs() []string {
+	if l.config == "" {
+		return nil
+	}
+	return []string{"--config", l.config}
+}
+
+// launches reports whether the entry for a server runs through the launcher:
+// every server with LauncherServe, otherwise local servers
+func (l *serverLauncher) launches(server *MCPServer) bool {
+	return l.kind == LauncherServe || !server.IsRemote()
+}
+
+// server returns a copy of a server that runs through the launcher. Its args,
+// env and, for a remote server, URL and headers are left out, since mcpv
+// applies them when the server starts. With LauncherServe it returns the
+// gateway entry instead.
+func (l *serverLauncher) server(server *MCPServer) *MCPServer {
+	launched := *server
+	launched.Env = nil
+	launched.URL, launched.Transport, launched.Headers = "", "", nil
+
+	// Shims use the nearest mcpv.json, so entries naming one run mcpv exec
+	switch {
+	case l.kind == LauncherServe:
+		return l.gateway()
+	case l.kind == LauncherShim && l.config == "":
+		l.shims[server.Name] = true
+		launched.Command = l.shimPath(server.Name)
+		launched.Args = nil
+	default:
+		launched.Command = l.executable
+		launched.Args = append(append([]string{"exec"}, l.configArgs()...), server.Name)
+	}
+	return &launched
+}
+
+// gateway returns the entry that runs "mcpv serve"
+func (l *serverLauncher) gateway() *MCPServer {
+	return &MCPServer{Name: GatewayEntryName, Command: l.executable, Args: append([]string{"serve"}, l.configArgs()...)}
+}
+
+// shimPath returns the path of a server's shim
+func (l *serverLauncher) shimPath(name string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(l.shimDir, name+".cmd")
+	}
+	return filepath.Join(l.shimDir, name)
+}
+
+// shim returns the contents of a server's shim
+func (l *serverLauncher) shim(name string) []byte {
+	if runtime.GOOS == "windows" {
+		return []byte(fmt.Sprintf("@echo off\r\n\"%s\" exec %s %%*\r\n", l.executable, name))
+	}
+	return []byte(fmt.Sprintf("#!/bin/sh\nexec %s exec %s \"$@\"\n", shellQuote(l.executable), shellQuote(name)))
+}
+
+// shellQuote quotes a word for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// writeShims writes the shims agent entries refer to, leaving shims that are
+// already up to date alone
+func (m *Manager) writeShims(l *serverLauncher) error {
+	if l == nil || len(l.shims) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(l.shims))
+	for name := range l.shims {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := l.shimPath(name)
+		script := l.shim(name)
+		if existing, err := m.readFile(path); err == nil && bytes.Equal(existing, script) {
+			continue
+		}
+		if err := m.writeFile(path, script, 0755); err != nil {
+			return fmt.Errorf("failed to write shim for %s: %w", name, err)
+		}
+	}
+	return nil
+}
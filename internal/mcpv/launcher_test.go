@@ -0,0 +1,80 @@
+package manager
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUserLevelEntriesNameProjectConfig(t *testing.T) {
+	// configArg stands for the path of the project's mcpv.json
+	const configArg = "<config>"
+	tests := []struct {
+		launcher string
+		entry    string
+		local    []string
+		user     []string
+	}{
+		{LauncherExec, "github", []string{"exec", "github"}, []string{"exec", "--config", configArg, "github"}},
+		{LauncherShim, "github", nil, []string{"exec", "--config", configArg, "github"}},
+		{LauncherServe, GatewayEntryName, []string{"serve"}, []string{"serve", "--config", configArg}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.launcher, func(t *testing.T) {
+			m, project := newTestManager(t)
+			server := MCPServer{Name: "github", Version: "v1.0.0", Command: "node", Args: []string{"dist/index.js"}}
+			configPath := writeProjectConfig(t, project, &ProjectConfig{Launcher: tt.launcher, Servers: []MCPServer{server}})
+			m.UseProject(configPath)
+
+			definition, err := m.agentDefinition("cursor")
+			if err != nil {
+				t.Fatal(err)
+			}
+			local := filepath.Join(project, ".cursor", "mcp.json")
+			user, err := definition.configPath(false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tx := m.newAgentConfigTransaction()
+			for _, path := range []string{local, user} {
+				file, err := tx.file(path, definition)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := file.setServer(&server); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := tx.commit("add github"); err != nil {
+				t.Fatal(err)
+			}
+
+			var want []string
+			for _, arg := range tt.user {
+				if arg == configArg {
+					arg = configPath
+				}
+				want = append(want, arg)
+			}
+			if args := entryArgs(readAgentEntry(t, local, "mcpServers", tt.entry)); !reflect.DeepEqual(args, tt.local) {
+				t.Errorf("project entry has args %q, want %q", args, tt.local)
+			}
+			if args := entryArgs(readAgentEntry(t, user, "mcpServers", tt.entry)); !reflect.DeepEqual(args, want) {
+				t.Errorf("user-level entry has args %q, want %q", args, want)
+			}
+		})
+	}
+}
+
+// entryArgs returns the args of a decoded agent entry
+func entryArgs(entry map[string]interface{}) []string {
+	var args []string
+	values, _ := entry["args"].([]interface{})
+	for _, value := range values {
+		arg, _ := value.(string)
+		args = append(args, arg)
+	}
+	return args
+}
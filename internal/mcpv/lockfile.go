@@ -0,0 +1,178 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+)
+
+const (
+	// lockFileName is the lockfile written next to mcpv.json
+	lockFileName = "mcpv.lock"
+
+	// manifestFileName is the install manifest written into each installed server directory
+	manifestFileName = ".mcpv-install.json"
+)
+
+// LockedServer records the exact version of a server installed for a project
+type LockedServer struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Repository string `json:"repository"`
+	Commit     string `json:"commit,omitempty"`
+}
+
+// LockFile represents the mcpv.lock file
+type LockFile struct {
+	Servers []LockedServer `json:"servers"`
+}
+
+// LockFilePath returns the lockfile path that belongs to a mcpv.json config file
+func LockFilePath(configPath string) string {
+	if configPath == "" {
+		configPath = "mcpv.json"
+	}
+	return filepath.Join(filepath.Dir(configPath), lockFileName)
+}
+
+// LoadLockFile loads the lockfile belonging to a mcpv.json config file
+func (m *Manager) LoadLockFile(configPath string) (*LockFile, error) {
+	data, err := m.readFile(LockFilePath(configPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &LockFile{Servers: []LockedServer{}}, nil
+		}
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+
+	var lock LockFile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file: %w", err)
+	}
+
+	return &lock, nil
+}
+
+// SaveLockFile saves the lockfile belonging to a mcpv.json config file
+func (m *Manager) SaveLockFile(lock *LockFile, configPath string) error {
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal lock file: %w", err)
+	}
+
+	if err := m.writeFile(LockFilePath(configPath), data, 0644); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+
+	return nil
+}
+
+// Find returns the locked entry for a server, if any
+func (l *LockFile) Find(name string) *LockedServer {
+	for i := range l.Servers {
+		if l.Servers[i].Name == name {
+			return &l.Servers[i]
+		}
+	}
+	return nil
+}
+
+// lockServer records an installed server version in the project's lockfile,
+// replacing any previously locked version of the same server
+func (m *Manager) lockServer(configPath, name, version, repository string) error {
+	lock, err := m.LoadLockFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	entry := LockedServer{
+		Name:       name,
+		Version:    version,
+		Repository: repository,
+		Commit:     m.installedCommit(name, version),
+	}
+
+	if existing := lock.Find(name); existing != nil {
+		*existing = entry
+	} else {
+		lock.Servers = append(lock.Servers, entry)
+	}
+
+	return m.SaveLockFile(lock, configPath)
+}
+
+// UnlockServer removes a server from the project's lockfile
+func (m *Manager) UnlockServer(configPath, name string) error {
+	lock, err := m.LoadLockFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	servers := []LockedServer{}
+	for _, locked := range lock.Servers {
+		if locked.Name != name {
+			servers = append(servers, locked)
+		}
+	}
+
+	if len(servers) == len(lock.Servers) {
+		return nil
+	}
+
+	lock.Servers = servers
+	return m.SaveLockFile(lock, configPath)
+}
+
+// saveProjectConfigAndLock saves mcpv.json and records the installed server in its lockfile
+func (m *Manager) saveProjectConfigAndLock(config *ProjectConfig, configPath string, server *MCPServer) error {
+	if err := m.SaveProjectConfig(config, configPath); err != nil {
+		return err
+	}
+	return m.lockServer(configPath, server.Name, server.Version, server.Repository)
+}
+
+// installedCommit returns the commit hash checked out for an installed server
+func (m *Manager) installedCommit(name, version string) string {
+	repo, err := git.PlainOpen(filepath.Join(m.dataDir, name, version))
+	if err != nil {
+		return ""
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return ""
+	}
+	return head.Hash().String()
+}
+
+// writeInstallManifest records how a server was installed inside its install directory
+func writeInstallManifest(server *MCPServer) error {
+	data, err := json.MarshalIndent(server, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal install manifest: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(server.InstallPath, manifestFileName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write install manifest: %w", err)
+	}
+
+	return nil
+}
+
+// readInstallManifest loads the install manifest of an installed server, if present
+func (m *Manager) readInstallManifest(name, version string) (*MCPServer, error) {
+	data, err := os.ReadFile(filepath.Join(m.dataDir, name, version, manifestFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var server MCPServer
+	if err := json.Unmarshal(data, &server); err != nil {
+		return nil, fmt.Errorf("failed to parse install manifest: %w", err)
+	}
+
+	return &server, nil
+}
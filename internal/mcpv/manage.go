@@ -3,6 +3,7 @@ package manager
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -13,29 +14,156 @@ import (
 	"github.com/go-git/go-git/v5/plumbing"
 )
 
-// MCPServer represents an MCP server configuration
+// MCPServer represents an MCP server configuration. Local servers are
+// installed from a repository and run as a command over stdio; remote servers
+// are reached at a URL and have nothing to install. Env, args and headers may
+// reference variables as ${VAR} or ${VAR:-default}, so secrets need not be
+// stored in mcpv.json.
 type MCPServer struct {
 	Name        string            `json:"name"`
-	Version     string            `json:"version"`
-	Repository  string            `json:"repository"`
+	Version     string            `json:"version,omitempty"`
+	Repository  string            `json:"repository,omitempty"`
 	InstallPath string            `json:"install_path,omitempty"`
 	Installed   bool              `json:"installed,omitempty"`
 	Command     string            `json:"command,omitempty"`
 	Args        []string          `json:"args,omitempty"`
 	Env         map[string]string `json:"env,omitempty"`
+
+	// URL, Transport and Headers describe a remote server. Transport is
+	// http (Streamable HTTP, the default) or sse.
+	URL       string            `json:"url,omitempty"`
+	Transport string            `json:"transport,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+
+	// Tools limits the server's tools agents may use
+	Tools *ToolFilter `json:"tools,omitempty"`
+
+	// Restart says whether mcpv serve and mcpv bridge --shared restart the
+	// server when it exits
+	Restart *RestartPolicy `json:"restart,omitempty"`
+}
+
+// MCP transports
+const (
+	TransportStdio = "stdio"
+	TransportHTTP  = "http"
+	TransportSSE   = "sse"
+)
+
+// IsRemote reports whether the server is reached at a URL rather than installed
+func (s *MCPServer) IsRemote() bool {
+	return s.URL != ""
+}
+
+// keepConfigured completes an installed server with the settings of its
+// mcpv.json entry: a configured command replaces the determined one,
+// configured args follow the determined ones and configured env takes
+// precedence over the determined env
+func (s *MCPServer) keepConfigured(configured *MCPServer) *MCPServer {
+	if configured.Command != "" {
+		s.Command, s.Args = configured.Command, configured.Args
+	} else if len(configured.Args) > 0 {
+		s.Args = append(append([]string{}, s.Args...), configured.Args...)
+	}
+	if len(configured.Env) > 0 {
+		env := make(map[string]string, len(s.Env)+len(configured.Env))
+		for name, value := range s.Env {
+			env[name] = value
+		}
+		for name, value := range configured.Env {
+			env[name] = value
+		}
+		s.Env = env
+	}
+	s.Tools = configured.Tools
+	s.Restart = configured.Restart
+	return s
+}
+
+// transport returns the transport used to reach the server
+func (s *MCPServer) transport() string {
+	switch {
+	case !s.IsRemote():
+		return TransportStdio
+	case s.Transport == "":
+		return TransportHTTP
+	}
+	return s.Transport
+}
+
+// validate checks that a server from mcpv.json is either local or remote
+func (s *MCPServer) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("server without a name")
+	}
+	if err := s.Tools.validate(); err != nil {
+		return fmt.Errorf("server %s has an invalid tools filter: %w", s.Name, err)
+	}
+	if err := s.Restart.validate(); err != nil {
+		return fmt.Errorf("server %s has an invalid restart policy: %w", s.Name, err)
+	}
+	if !s.IsRemote() {
+		if s.Transport != "" && s.Transport != TransportStdio {
+			return fmt.Errorf("server %s uses transport %s but has no url", s.Name, s.Transport)
+		}
+		if len(s.Headers) > 0 {
+			return fmt.Errorf("server %s has headers but no url", s.Name)
+		}
+		return nil
+	}
+
+	switch s.transport() {
+	case TransportHTTP, TransportSSE:
+	default:
+		return fmt.Errorf("server %s uses unsupported transport %s; use http or sse", s.Name, s.Transport)
+	}
+	if s.Command != "" || s.Repository != "" {
+		return fmt.Errorf("server %s has a url and a command or repository; remote servers are not installed", s.Name)
+	}
+	if s.Restart != nil {
+		return fmt.Errorf("server %s has a url and a restart policy; remote servers are not run by mcpv", s.Name)
+	}
+	return nil
 }
 
 // ProjectConfig represents the mcpv.json configuration file
 type ProjectConfig struct {
-	Servers      []MCPServer           `json:"servers"`
-	DefaultAgent string                `json:"default_agent,omitempty"`
-	Agents       map[string]*AgentSpec `json:"agents,omitempty"`
+	Servers      []MCPServer                 `json:"servers"`
+	DefaultAgent string                      `json:"default_agent,omitempty"`
+	Agents       map[string]*AgentDefinition `json:"agents,omitempty"`
+
+	// EnvReferences writes ${VAR} references into agents that resolve
+	// environment variables themselves, instead of the variables' values
+	EnvReferences bool `json:"env_references,omitempty"`
+
+	// Launcher configures local servers in agents to run through mcpv
+	// ("exec" or "shim"), which resolves their version when they start,
+	// instead of by their install path
+	Launcher string `json:"launcher,omitempty"`
 }
 
 // Manager handles MCP server operations
 type Manager struct {
-	dataDir            string
-	agentConfigManager *DynamicAgentConfigManager
+	dataDir  string
+	stateDir string
+	// agentDefinitions are the embedded and user agent definitions
+	agentDefinitions map[string]*AgentDefinition
+	// projectPath is the mcpv.json whose settings apply to agent
+	// configurations; project holds them once they are first needed
+	projectPath   string
+	project       *projectSettings
+	projectErr    error
+	projectWarned bool
+	// secrets is the secret store, chosen when first needed;
+	// secretStoreWarned is set once secrets kept in another store than the
+	// one in use have been reported
+	secrets           secretStore
+	secretStoreWarned bool
+	// installCheck runs a health check after each install, if set
+	installCheck *installCheck
+	plan         *Plan
+	// out receives progress messages
+	out io.Writer
 }
 
 // NewManager creates a new manager instance
@@ -50,16 +178,31 @@ func NewManager() (*Manager, error) {
 		return nil, fmt.Errorf("failed to create data directory: %w", err)
 	}
 
-	// Initialize dynamic agent config manager
-	agentConfigManager, err := NewDynamicAgentConfigManager()
+	stateDir, err := getStateDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state directory: %w", err)
+	}
+
+	agentDefinitions, err := loadAgentDefinitions()
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize agent config manager: %w", err)
+		return nil, err
+	}
+
+	m := &Manager{
+		dataDir:          dataDir,
+		stateDir:         stateDir,
+		agentDefinitions: agentDefinitions,
+		out:              os.Stdout,
 	}
+	m.UseProject("")
 
-	return &Manager{
-		dataDir:            dataDir,
-		agentConfigManager: agentConfigManager,
-	}, nil
+	return m, nil
+}
+
+// SetOutput sets where progress messages are written, standard output by
+// default
+func (m *Manager) SetOutput(w io.Writer) {
+	m.out = w
 }
 
 // getDataDir returns the XDG_DATA_HOME directory or default
@@ -87,7 +230,7 @@ func (m *Manager) LoadProjectConfig(configPath string) (*ProjectConfig, error) {
 		configPath = "mcpv.json"
 	}
 
-	data, err := os.ReadFile(configPath)
+	data, err := m.readFile(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return &ProjectConfig{Servers: []MCPServer{}}, nil
@@ -100,9 +243,81 @@ func (m *Manager) LoadProjectConfig(configPath string) (*ProjectConfig, error) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	for i := range config.Servers {
+		if err := config.Servers[i].validate(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", configPath, err)
+		}
+	}
+
 	return &config, nil
 }
 
+// projectSettings are the settings of a mcpv.json that apply when its servers
+// are written to agents or run
+type projectSettings struct {
+	// agentDefinitions adds the project's agent definitions to the embedded
+	// and user definitions
+	agentDefinitions map[string]*AgentDefinition
+	// variables resolves references in server settings
+	variables *projectVariables
+	// launcher runs local servers through mcpv, if set
+	launcher *serverLauncher
+}
+
+// UseProject selects the mcpv.json whose agent definitions, variables and
+// launcher apply to agent configurations, the one in the current directory by
+// default. Its settings are read when they are first needed.
+func (m *Manager) UseProject(configPath string) {
+	if configPath == "" {
+		configPath = "mcpv.json"
+	}
+	configPath = absPath(configPath)
+	if configPath == m.projectPath {
+		return
+	}
+	m.projectPath = configPath
+	m.project, m.projectErr, m.projectWarned = nil, nil, false
+}
+
+// loadProject loads the mcpv.json an operation acts on and selects it as the
+// project whose settings apply to agent configurations
+func (m *Manager) loadProject(configPath string) (*ProjectConfig, error) {
+	m.UseProject(configPath)
+	return m.LoadProjectConfig(configPath)
+}
+
+// projectSettings returns the settings of the selected project, reading them
+// on first use
+func (m *Manager) projectSettings() (*projectSettings, error) {
+	if m.project == nil && m.projectErr == nil {
+		if m.projectPath == "" {
+			m.UseProject("")
+		}
+		m.project, m.projectErr = m.loadProjectSettings(m.projectPath)
+	}
+	return m.project, m.projectErr
+}
+
+// loadProjectSettings reads the settings of a project's mcpv.json
+func (m *Manager) loadProjectSettings(configPath string) (*projectSettings, error) {
+	config, err := m.LoadProjectConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &projectSettings{}
+	if settings.agentDefinitions, err = m.projectAgentDefinitions(config.Agents, configPath); err != nil {
+		return nil, err
+	}
+	if settings.variables, err = m.projectVariables(configPath, config.EnvReferences); err != nil {
+		return nil, err
+	}
+	if settings.launcher, err = m.newServerLauncher(config.Launcher); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configPath, err)
+	}
+	return settings, nil
+}
+
 // SaveProjectConfig saves the mcpv.json configuration file
 func (m *Manager) SaveProjectConfig(config *ProjectConfig, configPath string) error {
 	if configPath == "" {
@@ -114,7 +329,7 @@ func (m *Manager) SaveProjectConfig(config *ProjectConfig, configPath string) er
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
+	if err := m.writeFile(configPath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -130,6 +345,19 @@ func (m *Manager) InstallServer(name, version, repository string) (*MCPServer, e
 		return nil, fmt.Errorf("server %s@%s is already installed", name, version)
 	}
 
+	if m.plan != nil {
+		m.plan.addStep("clone %s at %s into %s", repository, version, serverDir)
+		m.plan.addStep("install dependencies and build %s@%s", name, version)
+		return &MCPServer{
+			Name:        name,
+			Version:     version,
+			Repository:  repository,
+			InstallPath: serverDir,
+			Installed:   true,
+			Command:     pendingBuildCommand,
+		}, nil
+	}
+
 	// Create server directory
 	if err := os.MkdirAll(serverDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create server directory: %w", err)
@@ -142,6 +370,15 @@ func (m *Manager) InstallServer(name, version, repository string) (*MCPServer, e
 		return nil, fmt.Errorf("failed to clone repository: %w", err)
 	}
 
+	return m.buildInstall(name, version, repository, serverDir, func() error {
+		return m.removeInstallDir(name, version)
+	})
+}
+
+// buildInstall installs the dependencies of a checked out server, builds it
+// and records how to run it. rollback undoes the install if the server then
+// fails its health check
+func (m *Manager) buildInstall(name, version, repository, serverDir string, rollback func() error) (*MCPServer, error) {
 	// Install dependencies if needed
 	if err := m.installDependencies(serverDir); err != nil {
 		return nil, fmt.Errorf("failed to install dependencies: %w", err)
@@ -169,6 +406,14 @@ func (m *Manager) InstallServer(name, version, repository string) (*MCPServer, e
 		Env:         env,
 	}
 
+	if err := writeInstallManifest(server); err != nil {
+		return nil, err
+	}
+
+	if err := m.checkInstall(server, rollback); err != nil {
+		return nil, err
+	}
+
 	return server, nil
 }
 
@@ -358,23 +603,75 @@ func (m *Manager) determineExecution(serverDir string) (string, []string, map[st
 	return "", nil, nil, fmt.Errorf("could not determine execution method for server")
 }
 
+// fillExecutionDetails determines how to run an installed server when its
+// command is not already known. Args and env already set on the server, such
+// as those from mcpv.json, are kept after the determined args and over the
+// determined env.
+func (m *Manager) fillExecutionDetails(server *MCPServer) {
+	if server.Command != "" {
+		return
+	}
+
+	serverDir := filepath.Join(m.dataDir, server.Name, server.Version)
+	if _, err := os.Stat(serverDir); os.IsNotExist(err) && m.plan != nil {
+		// The server has only been planned for installation
+		server.Command = pendingBuildCommand
+		return
+	}
+
+	command, args, env, err := m.determineExecution(serverDir)
+	if err == nil {
+		server.Command = command
+		server.Args = append(args, server.Args...)
+		for name, value := range server.Env {
+			env[name] = value
+		}
+		server.Env = env
+	}
+}
+
 // runCommand executes a command in a specific directory
 func (m *Manager) runCommand(dir, command string, args ...string) error {
 	cmd := exec.Command(command, args...)
 	cmd.Dir = dir
-	cmd.Stdout = os.Stdout
+	cmd.Stdout = m.out
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
 
 // RemoveServer removes a specific MCP server version
 func (m *Manager) RemoveServer(name, version string) error {
+	if err := m.RemoveServerFiles(name, version); err != nil {
+		return err
+	}
+
+	// Remove server from agent configurations
+	if err := m.RemoveServerFromAgentConfigs(name); err != nil {
+		fmt.Fprintf(m.out, "Warning: Failed to remove server from agent configurations: %v\n", err)
+	}
+
+	return nil
+}
+
+// RemoveServerFiles deletes the installed files of a server version without
+// changing any agent configuration
+func (m *Manager) RemoveServerFiles(name, version string) error {
+	return m.removeInstallDir(name, version)
+}
+
+// removeInstallDir deletes the files of an installed server version
+func (m *Manager) removeInstallDir(name, version string) error {
 	serverDir := filepath.Join(m.dataDir, name, version)
 
 	if _, err := os.Stat(serverDir); os.IsNotExist(err) {
 		return fmt.Errorf("server %s@%s is not installed", name, version)
 	}
 
+	if m.plan != nil {
+		m.plan.addStep("remove %s", serverDir)
+		return nil
+	}
+
 	if err := os.RemoveAll(serverDir); err != nil {
 		return fmt.Errorf("failed to remove server: %w", err)
 	}
@@ -385,11 +682,6 @@ func (m *Manager) RemoveServer(name, version string) error {
 		os.Remove(parentDir)
 	}
 
-	// Remove server from agent configurations
-	if err := m.RemoveServerFromAgentConfigs(name); err != nil {
-		fmt.Printf("Warning: Failed to remove server from agent configurations: %v\n", err)
-	}
-
 	return nil
 }
 
@@ -406,7 +698,7 @@ func (m *Manager) ListInstalledServers() ([]MCPServer, error) {
 	}
 
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if !entry.IsDir() || entry.Name() == shimDirName {
 			continue
 		}
 
@@ -419,16 +711,27 @@ func (m *Manager) ListInstalledServers() ([]MCPServer, error) {
 		}
 
 		for _, versionEntry := range versions {
-			if !versionEntry.IsDir() {
+			// Hidden directories hold builds kept while a server is updated
+			if !versionEntry.IsDir() || strings.HasPrefix(versionEntry.Name(), ".") {
 				continue
 			}
 
-			servers = append(servers, MCPServer{
+			server := MCPServer{
 				Name:        serverName,
 				Version:     versionEntry.Name(),
 				InstallPath: filepath.Join(serverDir, versionEntry.Name()),
 				Installed:   true,
-			})
+			}
+
+			// Fill in repository and execution details recorded at install time
+			if manifest, err := m.readInstallManifest(serverName, versionEntry.Name()); err == nil {
+				server.Repository = manifest.Repository
+				server.Command = manifest.Command
+				server.Args = manifest.Args
+				server.Env = manifest.Env
+			}
+
+			servers = append(servers, server)
 		}
 	}
 
@@ -448,66 +751,73 @@ func (m *Manager) UpdateServer(name, repository string) error {
 	return err
 }
 
-// getLatestVersion gets the latest version from a git repository
+// getLatestVersion gets the latest version from a git repository's tags.
+// Repositories without semantic version tags resolve to "latest".
 func (m *Manager) getLatestVersion(repoURL string) (string, error) {
-	// This is a simplified implementation
-	// In a real implementation, you'd query the git repository for tags
-	return "latest", nil
-	return "latest", nil
+	versions, err := m.ListRemoteVersions(repoURL)
+	if err != nil {
+		return "", err
+	}
+
+	latest, ok := latestRemoteVersion(versions)
+	if !ok {
+		return "latest", nil
+	}
+	return latest.Tag, nil
 }
 
 // InstallFromConfig installs all servers specified in the project config
 func (m *Manager) InstallFromConfig(configPath string) error {
-	config, err := m.LoadProjectConfig(configPath)
+	config, err := m.loadProject(configPath)
 	if err != nil {
 		return err
 	}
 
 	for _, server := range config.Servers {
+		if server.IsRemote() {
+			// Remote servers have nothing to install
+			if err := m.PatchAgentConfigs(&server); err != nil {
+				fmt.Fprintf(m.out, "Warning: Failed to configure server %s for agents: %v\n", server.Name, err)
+			}
+			continue
+		}
 		if server.Repository == "" {
 			return fmt.Errorf("repository not specified for server %s", server.Name)
 		}
 
-		version := server.Version
-		if version == "" {
-			version = "latest"
+		version, err := m.installVersion(configPath, &server)
+		if err != nil {
+			return err
 		}
 
-		fmt.Printf("Installing %s@%s...\n", server.Name, version)
+		fmt.Fprintf(m.out, "Installing %s@%s...\n", server.Name, version)
 		installedServer, err := m.InstallServer(server.Name, version, server.Repository)
 		if err != nil {
 			if strings.Contains(err.Error(), "already installed") {
-				fmt.Printf("Server %s@%s is already installed\n", server.Name, version)
+				fmt.Fprintf(m.out, "Server %s@%s is already installed\n", server.Name, version)
 				// Still need to patch agent configs for already installed servers
 				installedServer = &MCPServer{
 					Name:       server.Name,
 					Version:    version,
 					Repository: server.Repository,
-					Command:    server.Command,
-					Args:       server.Args,
-					Env:        server.Env,
 				}
 
-				// Try to determine execution details if not provided
-				if installedServer.Command == "" {
-					serverDir := filepath.Join(m.dataDir, server.Name, version)
-					command, args, env, execErr := m.determineExecution(serverDir)
-					if execErr == nil {
-						installedServer.Command = command
-						installedServer.Args = args
-						installedServer.Env = env
-					}
-				}
+				// Try to determine execution details
+				m.fillExecutionDetails(installedServer)
 			} else {
 				return err
 			}
 		} else {
-			fmt.Printf("Successfully installed %s@%s\n", server.Name, version)
+			m.done("Successfully installed %s@%s\n", server.Name, version)
+		}
+
+		if err := m.lockServer(configPath, server.Name, version, server.Repository); err != nil {
+			fmt.Fprintf(m.out, "Warning: Failed to record %s@%s in lock file: %v\n", server.Name, version, err)
 		}
 
 		// Patch agent configurations for all detected agents
-		if err := m.PatchAgentConfigs(installedServer); err != nil {
-			fmt.Printf("Warning: Failed to configure server %s for agents: %v\n", server.Name, err)
+		if err := m.PatchAgentConfigs(installedServer.keepConfigured(&server)); err != nil {
+			fmt.Fprintf(m.out, "Warning: Failed to configure server %s for agents: %v\n", server.Name, err)
 		}
 	}
 
@@ -516,32 +826,44 @@ func (m *Manager) InstallFromConfig(configPath string) error {
 
 // InstallFromConfigForAgent installs all servers specified in the project config for a specific agent
 func (m *Manager) InstallFromConfigForAgent(configPath string, agentType AgentType) error {
-	config, err := m.LoadProjectConfig(configPath)
+	config, err := m.loadProject(configPath)
 	if err != nil {
 		return err
 	}
 
 	for _, server := range config.Servers {
+		if server.IsRemote() {
+			// Remote servers have nothing to install
+			if err := m.AddServerToAgent(agentType, &server); err != nil {
+				return fmt.Errorf("failed to configure server %s for %s: %w", server.Name, agentType, err)
+			}
+			m.done("✓ Configured %s for %s agent\n", server.Name, agentType)
+			continue
+		}
 		if server.Repository == "" {
 			return fmt.Errorf("repository not specified for server %s", server.Name)
 		}
 
-		version := server.Version
-		if version == "" {
-			version = "latest"
+		version, err := m.installVersion(configPath, &server)
+		if err != nil {
+			return err
 		}
 
-		fmt.Printf("Installing %s@%s for %s agent...\n", server.Name, version, agentType)
-		_, err := m.InstallServer(server.Name, version, server.Repository)
+		fmt.Fprintf(m.out, "Installing %s@%s for %s agent...\n", server.Name, version, agentType)
+		_, err = m.InstallServer(server.Name, version, server.Repository)
 		if err != nil {
 			if strings.Contains(err.Error(), "already installed") {
-				fmt.Printf("Server %s@%s is already installed\n", server.Name, version)
+				fmt.Fprintf(m.out, "Server %s@%s is already installed\n", server.Name, version)
 				// Still need to configure for the specific agent
 			} else {
 				return err
 			}
 		} else {
-			fmt.Printf("Successfully installed %s@%s\n", server.Name, version)
+			m.done("Successfully installed %s@%s\n", server.Name, version)
+		}
+
+		if err := m.lockServer(configPath, server.Name, version, server.Repository); err != nil {
+			fmt.Fprintf(m.out, "Warning: Failed to record %s@%s in lock file: %v\n", server.Name, version, err)
 		}
 
 		// Configure for specific agent only
@@ -549,26 +871,16 @@ func (m *Manager) InstallFromConfigForAgent(configPath string, agentType AgentTy
 			Name:       server.Name,
 			Version:    version,
 			Repository: server.Repository,
-			Command:    server.Command,
-			Args:       server.Args,
-			Env:        server.Env,
-		}
-
-		// If we don't have execution details, try to determine them
-		if installedServer.Command == "" {
-			serverDir := filepath.Join(m.dataDir, server.Name, version)
-			command, args, env, err := m.determineExecution(serverDir)
-			if err == nil {
-				installedServer.Command = command
-				installedServer.Args = args
-				installedServer.Env = env
-			}
 		}
 
-		if err := m.agentConfigManager.AddServerToAgent(agentType, installedServer); err != nil {
+		// Try to determine execution details
+		m.fillExecutionDetails(installedServer)
+		installedServer.keepConfigured(&server)
+
+		if err := m.AddServerToAgent(agentType, installedServer); err != nil {
 			return fmt.Errorf("failed to configure server %s for %s: %w", server.Name, agentType, err)
 		}
-		fmt.Printf("✓ Configured %s for %s agent\n", server.Name, agentType)
+		m.done("✓ Configured %s for %s agent\n", server.Name, agentType)
 	}
 
 	return nil
@@ -582,7 +894,7 @@ func (m *Manager) InstallServerAndAddToConfig(name, version, repository, configP
 	}
 
 	// Load existing config
-	config, err := m.LoadProjectConfig(configPath)
+	config, err := m.loadProject(configPath)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -591,8 +903,8 @@ func (m *Manager) InstallServerAndAddToConfig(name, version, repository, configP
 	for i, existingServer := range config.Servers {
 		if existingServer.Name == name && existingServer.Version == version {
 			// Update existing server with execution details
-			config.Servers[i] = *server
-			if err := m.SaveProjectConfig(config, configPath); err != nil {
+			config.Servers[i] = *server.keepConfigured(&existingServer)
+			if err := m.saveProjectConfigAndLock(config, configPath, server); err != nil {
 				return err
 			}
 			// Patch agent configurations
@@ -602,7 +914,7 @@ func (m *Manager) InstallServerAndAddToConfig(name, version, repository, configP
 
 	// Add new server to config
 	config.Servers = append(config.Servers, *server)
-	if err := m.SaveProjectConfig(config, configPath); err != nil {
+	if err := m.saveProjectConfigAndLock(config, configPath, server); err != nil {
 		return err
 	}
 
@@ -619,7 +931,7 @@ func (m *Manager) InstallServerAndAddToConfigForAgent(name, version, repository,
 	}
 
 	// Load existing config
-	config, err := m.LoadProjectConfig(configPath)
+	config, err := m.loadProject(configPath)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -628,23 +940,23 @@ func (m *Manager) InstallServerAndAddToConfigForAgent(name, version, repository,
 	for i, existingServer := range config.Servers {
 		if existingServer.Name == name && existingServer.Version == version {
 			// Update existing server with execution details
-			config.Servers[i] = *server
-			if err := m.SaveProjectConfig(config, configPath); err != nil {
+			config.Servers[i] = *server.keepConfigured(&existingServer)
+			if err := m.saveProjectConfigAndLock(config, configPath, server); err != nil {
 				return err
 			}
 			// Configure for specific agent only
-			return m.agentConfigManager.AddServerToAgent(agentType, server)
+			return m.AddServerToAgent(agentType, server)
 		}
 	}
 
 	// Add new server to config
 	config.Servers = append(config.Servers, *server)
-	if err := m.SaveProjectConfig(config, configPath); err != nil {
+	if err := m.saveProjectConfigAndLock(config, configPath, server); err != nil {
 		return err
 	}
 
 	// Configure for specific agent only
-	return m.agentConfigManager.AddServerToAgent(agentType, server)
+	return m.AddServerToAgent(agentType, server)
 }
 
 // isDirEmpty checks if a directory is empty
@@ -677,93 +989,159 @@ func ValidateVersion(version string) error {
 
 // PatchAgentConfigs patches agent configurations with the installed MCP server
 func (m *Manager) PatchAgentConfigs(server *MCPServer) error {
-	if m.agentConfigManager == nil {
-		return fmt.Errorf("agent config manager not initialized")
-	}
-
 	// Detect available agents
-	availableAgents := m.agentConfigManager.DetectAvailableAgents()
+	availableAgents := m.DetectAgents()
 	if len(availableAgents) == 0 {
-		fmt.Printf("No supported AI agents detected. Server %s installed but not configured for any agents.\n", server.Name)
+		fmt.Fprintf(m.out, "No supported AI agents detected. Server %s installed but not configured for any agents.\n", server.Name)
 		return nil
 	}
 
-	fmt.Printf("Configuring server %s for detected agents...\n", server.Name)
+	fmt.Fprintf(m.out, "Configuring server %s for detected agents...\n", server.Name)
 
-	// Add server to all available agents
-	var errors []string
+	// Stage the change for all available agents, then write them together
+	tx := m.newAgentConfigTransaction()
 	for _, agentType := range availableAgents {
-		if err := m.agentConfigManager.AddServerToAgent(agentType, server); err != nil {
-			errors = append(errors, fmt.Sprintf("%s: %v", agentType, err))
-		} else {
-			fmt.Printf("✓ Added %s to %s configuration\n", server.Name, agentType)
+		if err := tx.addServer(agentType, server, true); err != nil {
+			return fmt.Errorf("failed to configure server for %s, no agent configurations were changed: %w", agentType, err)
 		}
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("failed to configure server for some agents: %v", errors)
+	if err := tx.commit(fmt.Sprintf("add %s", server.Name)); err != nil {
+		return err
+	}
+
+	for _, agentType := range availableAgents {
+		m.done("✓ Added %s to %s configuration\n", server.Name, agentType)
 	}
 
 	return nil
 }
 
-// RemoveServerFromAgentConfigs removes an MCP server from agent configurations
+// RemoveServerFromAgentConfigs removes an MCP server from the local
+// configuration of all detected agents
 func (m *Manager) RemoveServerFromAgentConfigs(serverName string) error {
-	if m.agentConfigManager == nil {
-		return fmt.Errorf("agent config manager not initialized")
-	}
+	return m.RemoveServerFromAgents(serverName, m.DetectAgents(), true)
+}
 
-	// Detect available agents
-	availableAgents := m.agentConfigManager.DetectAvailableAgents()
-	if len(availableAgents) == 0 {
+// RemoveServerFromAgents removes an MCP server from the local or global
+// configuration of the given agents
+func (m *Manager) RemoveServerFromAgents(serverName string, agentTypes []AgentType, useLocal bool) error {
+	if len(agentTypes) == 0 {
 		return nil // No agents to remove from
 	}
 
-	fmt.Printf("Removing server %s from agent configurations...\n", serverName)
+	fmt.Fprintf(m.out, "Removing server %s from agent configurations...\n", serverName)
 
-	// Remove server from all available agents
-	var errors []string
-	for _, agentType := range availableAgents {
-		if err := m.agentConfigManager.RemoveServerFromAgent(agentType, serverName); err != nil {
-			errors = append(errors, fmt.Sprintf("%s: %v", agentType, err))
-		} else {
-			fmt.Printf("✓ Removed %s from %s configuration\n", serverName, agentType)
+	// Stage the removal for all target agents, then write them together
+	tx := m.newAgentConfigTransaction()
+	var removedFrom []AgentType
+	for _, agentType := range agentTypes {
+		removed, err := tx.removeServer(agentType, serverName, useLocal)
+		if err != nil {
+			return fmt.Errorf("failed to remove server from %s, no agent configurations were changed: %w", agentType, err)
+		}
+		if removed {
+			removedFrom = append(removedFrom, agentType)
 		}
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("failed to remove server from some agents: %v", errors)
+	if err := tx.commit(fmt.Sprintf("remove %s", serverName)); err != nil {
+		return err
+	}
+
+	for _, agentType := range removedFrom {
+		m.done("✓ Removed %s from %s configuration\n", serverName, agentType)
 	}
 
 	return nil
 }
 
-// ListAgentConfigurations lists the current agent configurations
-func (m *Manager) ListAgentConfigurations() error {
-	if m.agentConfigManager == nil {
-		return fmt.Errorf("agent config manager not initialized")
+// AgentConfiguration describes an agent, where its configuration lives and
+// where its definition came from
+type AgentConfiguration struct {
+	Type       AgentType `json:"type" yaml:"type"`
+	Path       string    `json:"path,omitempty" yaml:"path,omitempty"`
+	Detected   bool      `json:"detected" yaml:"detected"`
+	Source     string    `json:"source" yaml:"source"`
+	SourcePath string    `json:"source_path,omitempty" yaml:"source_path,omitempty"`
+	Error      string    `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// DefinitionSource describes where the agent's definition came from
+func (a AgentConfiguration) DefinitionSource() string {
+	if a.SourcePath == "" {
+		return a.Source
 	}
+	return fmt.Sprintf("%s: %s", a.Source, a.SourcePath)
+}
 
-	availableAgents := m.agentConfigManager.DetectAvailableAgents()
-	if len(availableAgents) == 0 {
-		fmt.Println("No supported AI agents detected.")
-		return nil
+// AgentConfigurations returns the detected agents and their configuration
+// paths. With all set, agents that were not detected are included too.
+func (m *Manager) AgentConfigurations(all bool) ([]AgentConfiguration, error) {
+	detected := map[AgentType]bool{}
+	for _, agentType := range m.DetectAgents() {
+		detected[agentType] = true
 	}
 
-	fmt.Println("Detected AI agents:")
-	for _, agentType := range availableAgents {
-		config, exists := m.agentConfigManager.configs[string(agentType)]
-		if !exists {
+	definitions := m.definitions()
+	agents := []AgentConfiguration{}
+	for _, key := range m.AgentTypes() {
+		agentType := AgentType(key)
+		if !all && !detected[agentType] {
 			continue
 		}
 
-		configPath, err := config.GetConfigPath()
+		definition := definitions[key]
+		agent := AgentConfiguration{
+			Type:     agentType,
+			Detected: detected[agentType],
+			Source:   definition.Source,
+		}
+		if definition.Source != AgentSourceEmbedded {
+			agent.SourcePath = definition.SourcePath
+		}
+
+		configPath, err := definition.configPath(true)
 		if err != nil {
-			fmt.Printf("- %s: Error getting config path: %v\n", agentType, err)
+			agent.Error = err.Error()
+		} else {
+			agent.Path = configPath
+		}
+		agents = append(agents, agent)
+	}
+
+	return agents, nil
+}
+
+// ListAgentConfigurations lists the current agent configurations. With all
+// set, agents that were not detected are listed too.
+func (m *Manager) ListAgentConfigurations(all bool) error {
+	agents, err := m.AgentConfigurations(all)
+	if err != nil {
+		return err
+	}
+
+	if len(agents) == 0 {
+		fmt.Fprintln(m.out, "No supported AI agents detected.")
+		return nil
+	}
+
+	if all {
+		fmt.Fprintln(m.out, "AI agents:")
+	} else {
+		fmt.Fprintln(m.out, "Detected AI agents:")
+	}
+	for _, agent := range agents {
+		if agent.Error != "" {
+			fmt.Fprintf(m.out, "- %s: Error getting config path: %s\n", agent.Type, agent.Error)
 			continue
 		}
 
-		fmt.Printf("- %s: %s\n", agentType, configPath)
+		status := ""
+		if !agent.Detected {
+			status = ", not detected"
+		}
+		fmt.Fprintf(m.out, "- %s: %s (%s%s)\n", agent.Type, agent.Path, agent.DefinitionSource(), status)
 	}
 
 	return nil
@@ -771,20 +1149,53 @@ func (m *Manager) ListAgentConfigurations() error {
 
 // AddServerToAgent adds an MCP server to a specific agent's configuration
 func (m *Manager) AddServerToAgent(agentType AgentType, server *MCPServer) error {
-	if m.agentConfigManager == nil {
-		return fmt.Errorf("agent config manager not initialized")
-	}
-
-	return m.agentConfigManager.AddServerToAgent(agentType, server)
+	return m.addServerToAgentConfig(agentType, server, true)
 }
 
 // AddServerToAgentWithLocal adds an MCP server to a specific agent's configuration with local preference
 func (m *Manager) AddServerToAgentWithLocal(agentType AgentType, server *MCPServer, useLocal bool) error {
-	if m.agentConfigManager == nil {
-		return fmt.Errorf("agent config manager not initialized")
+	return m.addServerToAgentConfig(agentType, server, useLocal)
+}
+
+// AddRemoteServer adds a remote server to mcpv.json, replacing any server of
+// the same name, and configures it for an agent or, without one, for all
+// detected agents
+func (m *Manager) AddRemoteServer(server *MCPServer, configPath string, agentType AgentType, useLocal bool) error {
+	if err := server.validate(); err != nil {
+		return err
+	}
+	if !server.IsRemote() {
+		return fmt.Errorf("server %s has no url", server.Name)
 	}
 
-	return m.agentConfigManager.AddServerToAgentWithLocal(agentType, server, useLocal)
+	config, err := m.loadProject(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	servers := []MCPServer{}
+	for _, existing := range config.Servers {
+		if existing.Name != server.Name {
+			servers = append(servers, existing)
+		}
+	}
+	config.Servers = append(servers, *server)
+
+	if err := m.SaveProjectConfig(config, configPath); err != nil {
+		return err
+	}
+	// A server replaced by a remote one is no longer locked to a version
+	if err := m.UnlockServer(configPath, server.Name); err != nil {
+		return err
+	}
+
+	if agentType == "" {
+		return m.PatchAgentConfigs(server)
+	}
+	if !m.IsAgentType(string(agentType)) {
+		return fmt.Errorf("unsupported agent type: %s. Supported types: %v", agentType, m.AgentTypes())
+	}
+	return m.AddServerToAgentWithLocal(agentType, server, useLocal)
 }
 
 // InstallServerAndAddToConfigForAgentWithLocal installs a server and adds it to the mcpv.json configuration for a specific agent with local preference
@@ -796,7 +1207,7 @@ func (m *Manager) InstallServerAndAddToConfigForAgentWithLocal(name, version, re
 	}
 
 	// Load existing config
-	config, err := m.LoadProjectConfig(configPath)
+	config, err := m.loadProject(configPath)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -805,8 +1216,8 @@ func (m *Manager) InstallServerAndAddToConfigForAgentWithLocal(name, version, re
 	for i, existingServer := range config.Servers {
 		if existingServer.Name == name && existingServer.Version == version {
 			// Update existing server with execution details
-			config.Servers[i] = *server
-			if err := m.SaveProjectConfig(config, configPath); err != nil {
+			config.Servers[i] = *server.keepConfigured(&existingServer)
+			if err := m.saveProjectConfigAndLock(config, configPath, server); err != nil {
 				return err
 			}
 			// Configure for specific agent with local preference
@@ -816,7 +1227,7 @@ func (m *Manager) InstallServerAndAddToConfigForAgentWithLocal(name, version, re
 
 	// Add new server to config
 	config.Servers = append(config.Servers, *server)
-	if err := m.SaveProjectConfig(config, configPath); err != nil {
+	if err := m.saveProjectConfigAndLock(config, configPath, server); err != nil {
 		return err
 	}
 
@@ -826,36 +1237,49 @@ func (m *Manager) InstallServerAndAddToConfigForAgentWithLocal(name, version, re
 
 // InstallFromConfigForAgentWithLocal installs all servers specified in the project config for a specific agent with local preference
 func (m *Manager) InstallFromConfigForAgentWithLocal(configPath string, agentType AgentType, useLocal bool) error {
-	config, err := m.LoadProjectConfig(configPath)
+	config, err := m.loadProject(configPath)
 	if err != nil {
 		return err
 	}
 
+	configType := "local"
+	if !useLocal {
+		configType = "global"
+	}
+
 	for _, server := range config.Servers {
+		if server.IsRemote() {
+			// Remote servers have nothing to install
+			if err := m.AddServerToAgentWithLocal(agentType, &server, useLocal); err != nil {
+				return fmt.Errorf("failed to configure server %s for %s: %w", server.Name, agentType, err)
+			}
+			m.done("✓ Configured %s for %s agent (%s config)\n", server.Name, agentType, configType)
+			continue
+		}
 		if server.Repository == "" {
 			return fmt.Errorf("repository not specified for server %s", server.Name)
 		}
 
-		version := server.Version
-		if version == "" {
-			version = "latest"
+		version, err := m.installVersion(configPath, &server)
+		if err != nil {
+			return err
 		}
 
-		configType := "local"
-		if !useLocal {
-			configType = "global"
-		}
-		fmt.Printf("Installing %s@%s for %s agent (%s config)...\n", server.Name, version, agentType, configType)
-		_, err := m.InstallServer(server.Name, version, server.Repository)
+		fmt.Fprintf(m.out, "Installing %s@%s for %s agent (%s config)...\n", server.Name, version, agentType, configType)
+		_, err = m.InstallServer(server.Name, version, server.Repository)
 		if err != nil {
 			if strings.Contains(err.Error(), "already installed") {
-				fmt.Printf("Server %s@%s is already installed\n", server.Name, version)
+				fmt.Fprintf(m.out, "Server %s@%s is already installed\n", server.Name, version)
 				// Still need to configure for the specific agent
 			} else {
 				return err
 			}
 		} else {
-			fmt.Printf("Successfully installed %s@%s\n", server.Name, version)
+			m.done("Successfully installed %s@%s\n", server.Name, version)
+		}
+
+		if err := m.lockServer(configPath, server.Name, version, server.Repository); err != nil {
+			fmt.Fprintf(m.out, "Warning: Failed to record %s@%s in lock file: %v\n", server.Name, version, err)
 		}
 
 		// Configure for specific agent with local preference
@@ -863,32 +1287,17 @@ func (m *Manager) InstallFromConfigForAgentWithLocal(configPath string, agentTyp
 			Name:       server.Name,
 			Version:    version,
 			Repository: server.Repository,
-			Command:    server.Command,
-			Args:       server.Args,
-			Env:        server.Env,
-		}
-
-		// If we don't have execution details, try to determine them
-		if installedServer.Command == "" {
-			serverDir := filepath.Join(m.dataDir, server.Name, version)
-			command, args, env, err := m.determineExecution(serverDir)
-			if err == nil {
-				installedServer.Command = command
-				installedServer.Args = args
-				installedServer.Env = env
-			}
 		}
 
+		// Try to determine execution details
+		m.fillExecutionDetails(installedServer)
+		installedServer.keepConfigured(&server)
+
 		if err := m.AddServerToAgentWithLocal(agentType, installedServer, useLocal); err != nil {
 			return fmt.Errorf("failed to configure server %s for %s: %w", server.Name, agentType, err)
 		}
-		fmt.Printf("✓ Configured %s for %s agent (%s config)\n", server.Name, agentType, configType)
+		m.done("✓ Configured %s for %s agent (%s config)\n", server.Name, agentType, configType)
 	}
 
 	return nil
 }
-
-// GetAgentConfigManager returns the agent configuration manager
-func (m *Manager) GetAgentConfigManager() *DynamicAgentConfigManager {
-	return m.agentConfigManager
-}
@@ -0,0 +1,226 @@
+package manager
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// newTestManager returns a manager with its data and state in temporary
+// directories, working in an empty project directory it returns too
+func newTestManager(t *testing.T) (*Manager, string) {
+	t.Helper()
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	definitions, err := loadAgentDefinitions()
+	if err != nil {
+		t.Fatalf("failed to load agent definitions: %v", err)
+	}
+
+	project := t.TempDir()
+	t.Chdir(project)
+	m := &Manager{
+		dataDir:          t.TempDir(),
+		stateDir:         t.TempDir(),
+		agentDefinitions: definitions,
+		out:              io.Discard,
+	}
+	m.UseProject("")
+	return m, project
+}
+
+// writeProjectConfig writes a project's mcpv.json
+func writeProjectConfig(t *testing.T, dir string, config *ProjectConfig) string {
+	t.Helper()
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "mcpv.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readAgentEntry reads a server's entry from a JSON agent configuration
+func readAgentEntry(t *testing.T, path, rootKey, name string) map[string]interface{} {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config map[string]map[string]map[string]interface{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatalf("failed to parse %s: %v", path, err)
+	}
+	entry, ok := config[rootKey][name]
+	if !ok {
+		t.Fatalf("%s has no entry for %s:\n%s", path, name, data)
+	}
+	return entry
+}
+
+// newTaggedRepo creates a repository of a Go server that does nothing, with a
+// commit tagged for each of the given tags, and returns its path
+func newTaggedRepo(t *testing.T, tags ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"go.mod":  "module example.com/server\n\ngo 1.21\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := worktree.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	signature := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	for _, tag := range tags {
+		if err := os.WriteFile(filepath.Join(dir, "VERSION"), []byte(tag), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := worktree.Add("VERSION"); err != nil {
+			t.Fatal(err)
+		}
+		hash, err := worktree.Commit("release "+tag, &git.CommitOptions{Author: signature})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.CreateTag(tag, hash, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestInstallFromConfigResolvesRange(t *testing.T) {
+	m, project := newTestManager(t)
+	repository := newTaggedRepo(t, "v1.0.0", "v1.2.0", "v2.0.0")
+	configPath := writeProjectConfig(t, project, &ProjectConfig{Servers: []MCPServer{
+		{Name: "ranged", Version: "^1.0.0", Repository: repository},
+	}})
+
+	if err := m.InstallFromConfig(configPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(m.dataDir, "ranged", "v1.2.0", "server")); err != nil {
+		t.Errorf("the highest version matching ^1.0.0 was not installed: %v", err)
+	}
+	lock, err := m.LoadLockFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locked := lock.Find("ranged"); locked == nil || locked.Version != "v1.2.0" {
+		t.Fatalf("mcpv.lock records %+v, want v1.2.0", locked)
+	}
+
+	// Once locked, installs keep the locked version as long as the range allows it
+	lock.Servers[0].Version = "v1.0.0"
+	if err := m.SaveLockFile(lock, configPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.InstallFromConfigForAgent(configPath, "cursor"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(m.dataDir, "ranged", "v1.0.0")); err != nil {
+		t.Errorf("the locked version was not installed: %v", err)
+	}
+}
+
+func TestInstallFromConfigWritesConfiguredEnv(t *testing.T) {
+	m, project := newTestManager(t)
+	t.Setenv("API_KEY", "secret")
+	configPath := writeProjectConfig(t, project, &ProjectConfig{Servers: []MCPServer{{
+		Name:       "envy",
+		Version:    "v1.0.0",
+		Repository: newTaggedRepo(t, "v1.0.0"),
+		Args:       []string{"--verbose"},
+		Env:        map[string]string{"API_KEY": "${API_KEY}"},
+	}}})
+	if err := os.MkdirAll(filepath.Join(project, ".cursor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.InstallFromConfig(configPath); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := readAgentEntry(t, filepath.Join(project, ".cursor", "mcp.json"), "mcpServers", "envy")
+	if env, _ := entry["env"].(map[string]interface{}); env["API_KEY"] != "secret" {
+		t.Errorf("cursor entry has env %v, want API_KEY=secret", entry["env"])
+	}
+	want := []string{filepath.Join(m.dataDir, "envy", "v1.0.0", "server"), "--verbose"}
+	if entry["command"] != want[0] || !reflect.DeepEqual(entryArgs(entry), want[1:]) {
+		t.Errorf("cursor entry runs %v %v, want %q", entry["command"], entry["args"], want)
+	}
+
+	statuses, err := m.AgentStatus(configPath, []AgentType{"cursor"}, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Status != EntryMatches {
+		t.Errorf("the entry written by a fresh install drifted from mcpv.json: %+v", statuses)
+	}
+}
+
+func TestInstallFromConfigKeepsToolsFilter(t *testing.T) {
+	m, project := newTestManager(t)
+
+	server := MCPServer{
+		Name:       "github",
+		Version:    "v1.0.0",
+		Repository: "https://example.com/github.git",
+		Command:    "node",
+		Args:       []string{"dist/index.js"},
+		Tools:      &ToolFilter{Allow: []string{"search_issues"}},
+	}
+	configPath := writeProjectConfig(t, project, &ProjectConfig{Servers: []MCPServer{server}})
+
+	// The server counts as installed already, so nothing is cloned
+	if err := os.MkdirAll(filepath.Join(m.dataDir, server.Name, server.Version), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Gemini applies the filter itself
+	if err := m.InstallFromConfigForAgentWithLocal(configPath, "gemini", true); err != nil {
+		t.Fatal(err)
+	}
+	entry := readAgentEntry(t, filepath.Join(project, ".gemini", "settings.json"), "mcpServers", "github")
+	if tools, _ := entry["includeTools"].([]interface{}); len(tools) != 1 || tools[0] != "search_issues" {
+		t.Errorf("gemini entry has includeTools %v, want [search_issues]", entry["includeTools"])
+	}
+
+	// Cursor cannot, so the server runs through mcpv exec, which applies it
+	if err := m.InstallFromConfigForAgent(configPath, "cursor"); err != nil {
+		t.Fatal(err)
+	}
+	entry = readAgentEntry(t, filepath.Join(project, ".cursor", "mcp.json"), "mcpServers", "github")
+	if args, _ := entry["args"].([]interface{}); len(args) < 2 || args[0] != "exec" || args[1] != "github" {
+		t.Errorf("cursor entry runs %v %v, want mcpv exec github", entry["command"], entry["args"])
+	}
+}
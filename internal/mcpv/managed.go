@@ -0,0 +1,112 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// managedEntriesFileName records which agent configuration entries mcpv wrote
+const managedEntriesFileName = "managed.json"
+
+// managedEntries lists, per agent configuration file, the server entries mcpv
+// wrote and the mcpv.json each was written for, so they can be told apart from
+// entries users or other projects added
+type managedEntries struct {
+	Files map[string]map[string]string `json:"files"`
+}
+
+// managedEntriesPath returns the location of the managed entries manifest
+func (m *Manager) managedEntriesPath() string {
+	return filepath.Join(m.stateDir, managedEntriesFileName)
+}
+
+// loadManagedEntries reads the managed entries manifest
+func (m *Manager) loadManagedEntries() (*managedEntries, error) {
+	entries := &managedEntries{Files: map[string]map[string]string{}}
+
+	data, err := os.ReadFile(m.managedEntriesPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, fmt.Errorf("failed to read managed entries: %w", err)
+	}
+
+	if err := json.Unmarshal(data, entries); err != nil {
+		return nil, fmt.Errorf("failed to parse managed entries: %w", err)
+	}
+	if entries.Files == nil {
+		entries.Files = map[string]map[string]string{}
+	}
+	return entries, nil
+}
+
+// saveManagedEntries writes the managed entries manifest
+func (m *Manager) saveManagedEntries(entries *managedEntries) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal managed entries: %w", err)
+	}
+	return writeFileAtomic(m.managedEntriesPath(), data, 0644)
+}
+
+// owner returns the mcpv.json a managed entry in a configuration file was
+// written for, or "" if mcpv did not write the entry
+func (e *managedEntries) owner(path, name string) string {
+	return e.Files[absPath(path)][name]
+}
+
+// set records the mcpv.json a server's entry in a configuration file was
+// written for, or with an empty owner, that mcpv no longer manages it
+func (e *managedEntries) set(path, name, owner string) {
+	path = absPath(path)
+
+	names := e.Files[path]
+	if owner != "" {
+		if names == nil {
+			names = map[string]string{}
+			e.Files[path] = names
+		}
+		names[name] = owner
+		return
+	}
+
+	delete(names, name)
+	if len(names) == 0 {
+		delete(e.Files, path)
+	}
+}
+
+// recordManagedEntries updates the manifest with the entries written to and
+// removed from agent configuration files, owned by the selected project
+func (m *Manager) recordManagedEntries(files []*agentConfigFile) error {
+	changed := false
+	for _, file := range files {
+		changed = changed || len(file.written) > 0 || len(file.removed) > 0
+	}
+	if !changed {
+		return nil
+	}
+
+	entries, err := m.loadManagedEntries()
+	if err != nil {
+		return err
+	}
+
+	owner := m.projectPath
+	if owner == "" {
+		owner = absPath("mcpv.json")
+	}
+	for _, file := range files {
+		for _, name := range file.written {
+			entries.set(file.path, name, owner)
+		}
+		for _, name := range file.removed {
+			entries.set(file.path, name, "")
+		}
+	}
+
+	return m.saveManagedEntries(entries)
+}
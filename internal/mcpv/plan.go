@@ -0,0 +1,190 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// pendingBuildCommand stands in for the command of a server that a dry run
+// would install, since it is only known after building
+const pendingBuildCommand = "(determined after build)"
+
+// Plan collects the actions a command would take when running in dry-run mode
+type Plan struct {
+	Steps []string      `json:"steps" yaml:"steps"`
+	Files []*FileChange `json:"files" yaml:"files"`
+}
+
+// FileChange is a pending write to a file recorded by a dry run
+type FileChange struct {
+	Path   string `json:"path" yaml:"path"`
+	Diff   string `json:"diff" yaml:"diff"`
+	before []byte
+	after  []byte
+	exists bool
+}
+
+// EnableDryRun makes the manager record its actions in a plan instead of
+// performing them
+func (m *Manager) EnableDryRun() {
+	m.plan = &Plan{Steps: []string{}, Files: []*FileChange{}}
+}
+
+// IsDryRun reports whether the manager only records a plan
+func (m *Manager) IsDryRun() bool {
+	return m.plan != nil
+}
+
+// Plan returns the recorded plan, or nil when not running in dry-run mode
+func (m *Manager) Plan() *Plan {
+	return m.plan
+}
+
+// done writes a message reporting a completed change. Dry runs leave it out,
+// since their plan lists the changes that were not made.
+func (m *Manager) done(format string, args ...interface{}) {
+	if m.plan != nil {
+		return
+	}
+	fmt.Fprintf(m.out, format, args...)
+}
+
+// addStep records a planned action
+func (p *Plan) addStep(format string, args ...interface{}) {
+	p.Steps = append(p.Steps, fmt.Sprintf(format, args...))
+}
+
+// file returns the pending change to a path, if any
+func (p *Plan) file(path string) *FileChange {
+	for _, change := range p.Files {
+		if change.Path == path {
+			return change
+		}
+	}
+	return nil
+}
+
+// readFile reads a file, returning pending contents when running in dry-run mode
+func (m *Manager) readFile(path string) ([]byte, error) {
+	if m.plan != nil {
+		if change := m.plan.file(absPath(path)); change != nil {
+			return change.after, nil
+		}
+	}
+	return os.ReadFile(path)
+}
+
+// writeFile atomically writes a file, creating its parent directory. In dry-run
+// mode the write is recorded in the plan instead.
+func (m *Manager) writeFile(path string, data []byte, perm os.FileMode) error {
+	if m.plan == nil {
+		return writeFileAtomic(path, data, perm)
+	}
+
+	path = absPath(path)
+	change := m.plan.file(path)
+	if change == nil {
+		change = &FileChange{Path: path}
+		if before, err := os.ReadFile(path); err == nil {
+			change.before = before
+			change.exists = true
+		}
+		m.plan.Files = append(m.plan.Files, change)
+	}
+	change.after = data
+
+	diff, err := change.unifiedDiff()
+	if err != nil {
+		return err
+	}
+	change.Diff = diff
+	return nil
+}
+
+// absPath returns an absolute form of path, or path itself when that fails
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// unifiedDiff renders the change as a unified diff
+func (c *FileChange) unifiedDiff() (string, error) {
+	if c.exists && bytes.Equal(c.before, c.after) {
+		return "", nil
+	}
+
+	fromFile := c.Path
+	if !c.exists {
+		fromFile = "/dev/null"
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(withTrailingNewline(c.before)),
+		B:        difflib.SplitLines(withTrailingNewline(c.after)),
+		FromFile: fromFile,
+		ToFile:   c.Path,
+		Context:  3,
+	})
+}
+
+// pendingBuild reports whether any planned file change holds the placeholder
+// for the command of a server that is not built yet
+func (p *Plan) pendingBuild() bool {
+	for _, change := range p.Files {
+		if strings.Contains(change.Diff, pendingBuildCommand) {
+			return true
+		}
+	}
+	return false
+}
+
+// withTrailingNewline terminates content with a newline so the last line diffs cleanly
+func withTrailingNewline(data []byte) string {
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return string(data)
+	}
+	return string(data) + "\n"
+}
+
+// Print writes the plan in human readable form
+func (p *Plan) Print(w io.Writer) error {
+	fmt.Fprintln(w, "\nDry run: no changes were made.")
+
+	if len(p.Steps) > 0 {
+		fmt.Fprintln(w, "\nPlanned actions:")
+		for _, step := range p.Steps {
+			fmt.Fprintf(w, "  - %s\n", step)
+		}
+	}
+
+	changed := false
+	for _, change := range p.Files {
+		if change.Diff == "" {
+			continue
+		}
+		if !changed {
+			fmt.Fprintln(w, "\nPlanned file changes:")
+			if p.pendingBuild() {
+				fmt.Fprintf(w, "Commands shown as %s are only known once the servers are built;\nthe entries written by a real run will have them in their place.\n", pendingBuildCommand)
+			}
+			changed = true
+		}
+		if _, err := fmt.Fprintf(w, "\n%s", change.Diff); err != nil {
+			return err
+		}
+	}
+
+	if len(p.Steps) == 0 && !changed {
+		fmt.Fprintln(w, "Nothing to do.")
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Senders of recorded messages
+const (
+	FromClient = "client"
+	FromServer = "server"
+)
+
+// maxRecordedLine bounds the length of a line in a recording
+const maxRecordedLine = 16 << 20
+
+// RecordedMessage is a JSON-RPC message exchanged with a server, as written
+// to a recording, one per line
+type RecordedMessage struct {
+	Time    time.Time       `json:"time"`
+	From    string          `json:"from"`
+	Message json.RawMessage `json:"message"`
+}
+
+// Recorder writes the messages exchanged with a server to a file
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+	// err is the first failure to write a message, after which the
+	// recording is incomplete and nothing more is written
+	err error
+}
+
+// NewRecorder creates or truncates a recording file
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording %s: %w", path, err)
+	}
+	return &Recorder{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// Record writes a message. Lines that are not JSON, such as log output a
+// server wrote to standard output, are left out. Once a message cannot be
+// written, the error is returned for it and every later message.
+func (r *Recorder) Record(from string, line []byte) error {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' || !json.Valid(line) {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	if err := r.enc.Encode(RecordedMessage{Time: time.Now().UTC(), From: from, Message: append(json.RawMessage(nil), line...)}); err != nil {
+		r.err = fmt.Errorf("failed to write recording %s: %w", r.file.Name(), err)
+	}
+	return r.err
+}
+
+// Writer returns a writer that records each line written to it as a message
+// from the given sender. Failures to record do not fail its writes, so the
+// session being recorded goes on; Close reports them.
+func (r *Recorder) Writer(from string) io.Writer {
+	return &recordingWriter{recorder: r, from: from}
+}
+
+// Close closes the recording file, returning the first failure to write a
+// message, if any
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = fmt.Errorf("failed to close recording %s: %w", r.file.Name(), err)
+	}
+	return r.err
+}
+
+// recordingWriter records the lines written to it
+type recordingWriter struct {
+	recorder *Recorder
+	from     string
+	partial  []byte
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.partial = append(w.partial, data...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.recorder.Record(w.from, w.partial[:i])
+		w.partial = w.partial[i+1:]
+	}
+	return len(data), nil
+}
+
+// ReadRecording reads the messages of a recording
+func ReadRecording(path string) ([]RecordedMessage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer file.Close()
+
+	var messages []RecordedMessage
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordedLine)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var message RecordedMessage
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			return nil, fmt.Errorf("failed to parse recording %s line %d: %w", path, line, err)
+		}
+		if message.From != FromClient && message.From != FromServer {
+			return nil, fmt.Errorf("failed to parse recording %s line %d: unknown sender %q", path, line, message.From)
+		}
+		messages = append(messages, message)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording %s: %w", path, err)
+	}
+	return messages, nil
+}
@@ -0,0 +1,89 @@
+package manager
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeSession is a client session with the fake server
+const fakeSession = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}
+{"jsonrpc":"2.0","method":"notifications/initialized"}
+{"jsonrpc":"2.0","id":2,"method":"tools/list"}
+{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hello"}}}
+`
+
+// recordFakeSession runs the fake server through a recorder as 'mcpv run
+// --record' does and returns the recording
+func recordFakeSession(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := fakeServer(nil)
+	process := ServerCommand(&server)
+	process.Stdin = io.TeeReader(strings.NewReader(fakeSession), recorder.Writer(FromClient))
+	process.Stdout = recorder.Writer(FromServer)
+	if err := process.Run(); err != nil {
+		t.Fatalf("fake server failed: %v", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRecorderRecordsBothDirections(t *testing.T) {
+	messages, err := ReadRecording(recordFakeSession(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The order of messages in either direction is kept
+	got := map[string][]string{}
+	for _, recorded := range messages {
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.Unmarshal(recorded.Message, &msg); err != nil {
+			t.Fatal(err)
+		}
+		got[recorded.From] = append(got[recorded.From], msg.Method+string(msg.ID))
+	}
+
+	// The log line the server wrote is left out
+	want := map[string][]string{
+		FromClient: {"initialize1", "notifications/initialized", "tools/list2", "tools/call3"},
+		FromServer: {"1", "2", "3"},
+	}
+	for from, messages := range want {
+		if strings.Join(got[from], ", ") != strings.Join(messages, ", ") {
+			t.Errorf("recorded %v from the %s, want %v", got[from], from, messages)
+		}
+	}
+}
+
+func TestRecorderReportsWriteFailures(t *testing.T) {
+	recorder, err := NewRecorder(filepath.Join(t.TempDir(), "session.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.file.Close()
+
+	if err := recorder.Record(FromClient, []byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)); err == nil {
+		t.Error("recording to a closed file did not fail")
+	}
+	if _, err := recorder.Writer(FromServer).Write([]byte("{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{}}\n")); err != nil {
+		t.Errorf("a failure to record failed the write: %v", err)
+	}
+	if err := recorder.Close(); err == nil {
+		t.Error("Close did not report the failure to record")
+	}
+}
@@ -0,0 +1,322 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/socialviolation/mcpv/internal/mcp"
+)
+
+// maxReplayValueLength bounds how much of a differing value is shown
+const maxReplayValueLength = 80
+
+// ReplayMismatch is a response that differs from the recorded one
+type ReplayMismatch struct {
+	ID          string   `json:"id" yaml:"id"`
+	Method      string   `json:"method" yaml:"method"`
+	Differences []string `json:"differences" yaml:"differences"`
+}
+
+// ReplayResult is the outcome of replaying a recording against a server
+type ReplayResult struct {
+	Server     string           `json:"server" yaml:"server"`
+	Version    string           `json:"version" yaml:"version"`
+	Requests   int              `json:"requests" yaml:"requests"`
+	Mismatches []ReplayMismatch `json:"mismatches" yaml:"mismatches"`
+}
+
+// Replay starts an installed server as 'mcpv run' would and sends it the
+// client's messages from a recording in order, waiting for the response to
+// each request and comparing it with the recorded one. Requests the server
+// makes are answered with the client's recorded responses. Fields matching
+// an ignore pattern, a dot-separated path such as result.content.*.text in
+// which * matches any one key or index and ** any number of them, are not
+// compared.
+func (m *Manager) Replay(name, version, configPath string, messages []RecordedMessage, ignore []string, timeout time.Duration) (*ReplayResult, error) {
+	server, err := m.ResolveServer(name, version, configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	stdio, err := mcp.StartStdio(ServerCommand(server))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", server.Command, err)
+	}
+	defer stdio.Close()
+
+	incoming := make(chan *mcp.Message, 16)
+	go func() {
+		defer close(incoming)
+		for {
+			msg, err := stdio.Receive()
+			if err != nil {
+				return
+			}
+			incoming <- msg
+		}
+	}()
+
+	r := &replay{
+		stdio:     stdio,
+		incoming:  incoming,
+		timeout:   timeout,
+		ignore:    parseIgnorePatterns(ignore),
+		responses: map[string]json.RawMessage{},
+		answers:   map[string]*mcp.Message{},
+		result:    &ReplayResult{Server: server.Name, Version: server.Version, Mismatches: []ReplayMismatch{}},
+	}
+	if err := r.run(messages); err != nil {
+		return nil, err
+	}
+	return r.result, nil
+}
+
+// replay drives a server from a recording
+type replay struct {
+	stdio    *mcp.StdioTransport
+	incoming <-chan *mcp.Message
+	timeout  time.Duration
+	ignore   [][]string
+
+	// responses holds the server's recorded responses by request ID, and
+	// answers the client's recorded responses to requests of the server
+	responses map[string]json.RawMessage
+	answers   map[string]*mcp.Message
+
+	result *ReplayResult
+}
+
+// run replays the client's messages in order
+func (r *replay) run(messages []RecordedMessage) error {
+	var sent []*mcp.Message
+	for i, recorded := range messages {
+		var msg mcp.Message
+		if err := json.Unmarshal(recorded.Message, &msg); err != nil {
+			return fmt.Errorf("failed to parse recorded message %d: %w", i+1, err)
+		}
+		switch {
+		case recorded.From == FromServer && msg.IsResponse():
+			if _, ok := r.responses[string(msg.ID)]; !ok {
+				r.responses[string(msg.ID)] = recorded.Message
+			}
+		case recorded.From == FromClient && msg.IsResponse():
+			r.answers[string(msg.ID)] = &msg
+		case recorded.From == FromClient:
+			sent = append(sent, &msg)
+		}
+	}
+
+	for _, msg := range sent {
+		if err := r.stdio.Send(context.Background(), msg); err != nil {
+			r.mismatch(msg, "failed to send: %v", err)
+			return nil
+		}
+		if !msg.IsRequest() {
+			continue
+		}
+
+		r.result.Requests++
+		response, err := r.await(msg.ID)
+		if err != nil {
+			r.mismatch(msg, "%v", err)
+			if r.exited() {
+				return nil
+			}
+			continue
+		}
+
+		expected, ok := r.responses[string(msg.ID)]
+		if !ok {
+			r.mismatch(msg, "the recording has no response to compare with")
+			continue
+		}
+		if differences := r.compare(expected, response); len(differences) > 0 {
+			r.result.Mismatches = append(r.result.Mismatches, ReplayMismatch{ID: string(msg.ID), Method: msg.Method, Differences: differences})
+		}
+	}
+	return nil
+}
+
+// await waits for the response to a request, answering the server's own
+// requests in the meantime
+func (r *replay) await(id json.RawMessage) (*mcp.Message, error) {
+	timer := time.NewTimer(r.timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case msg, ok := <-r.incoming:
+			if !ok {
+				return nil, fmt.Errorf("no response: %v", r.stdio.ExitError())
+			}
+			switch {
+			case msg.IsResponse() && string(msg.ID) == string(id):
+				return msg, nil
+			case msg.IsRequest():
+				r.answer(msg)
+			}
+		case <-timer.C:
+			return nil, fmt.Errorf("no response within %s", r.timeout)
+		}
+	}
+}
+
+// answer responds to a request of the server with the client's recorded
+// response, if there is one
+func (r *replay) answer(request *mcp.Message) {
+	reply, ok := r.answers[string(request.ID)]
+	switch {
+	case ok:
+		delete(r.answers, string(request.ID))
+	case request.Method == "ping":
+		reply, _ = mcp.NewResponse(request.ID, struct{}{})
+	default:
+		reply = mcp.NewErrorResponse(request.ID, mcp.CodeMethodNotFound, "the recording has no response to %s", request.Method)
+	}
+	r.stdio.Send(context.Background(), reply)
+}
+
+// exited reports whether the server has exited
+func (r *replay) exited() bool {
+	select {
+	case <-r.stdio.Exited():
+		return true
+	default:
+		return false
+	}
+}
+
+// mismatch records a request whose response could not be compared
+func (r *replay) mismatch(msg *mcp.Message, format string, args ...interface{}) {
+	r.result.Mismatches = append(r.result.Mismatches, ReplayMismatch{
+		ID:          string(msg.ID),
+		Method:      msg.Method,
+		Differences: []string{fmt.Sprintf(format, args...)},
+	})
+}
+
+// compare returns the differences between a recorded response and the
+// actual one, leaving out ignored fields
+func (r *replay) compare(expected json.RawMessage, actual *mcp.Message) []string {
+	data, err := json.Marshal(actual)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	var want, got interface{}
+	json.Unmarshal(expected, &want)
+	json.Unmarshal(data, &got)
+
+	var differences []string
+	r.diff(nil, want, got, &differences)
+	return differences
+}
+
+// diff appends the differences between two JSON values at path
+func (r *replay) diff(path []string, want, got interface{}, differences *[]string) {
+	if r.ignored(path) {
+		return
+	}
+
+	switch want := want.(type) {
+	case map[string]interface{}:
+		if got, ok := got.(map[string]interface{}); ok {
+			for _, key := range unionKeys(want, got) {
+				field := append(append([]string{}, path...), key)
+				if r.ignored(field) {
+					continue
+				}
+				wantValue, inWant := want[key]
+				gotValue, inGot := got[key]
+				switch {
+				case !inGot:
+					*differences = append(*differences, fmt.Sprintf("%s: missing, recorded %s", displayPath(field), displayValue(wantValue)))
+				case !inWant:
+					*differences = append(*differences, fmt.Sprintf("%s: unexpected %s", displayPath(field), displayValue(gotValue)))
+				default:
+					r.diff(field, wantValue, gotValue, differences)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if got, ok := got.([]interface{}); ok {
+			if len(want) != len(got) {
+				*differences = append(*differences, fmt.Sprintf("%s: %d items, recorded %d", displayPath(path), len(got), len(want)))
+			}
+			for i := 0; i < len(want) && i < len(got); i++ {
+				r.diff(append(append([]string{}, path...), strconv.Itoa(i)), want[i], got[i], differences)
+			}
+			return
+		}
+	}
+
+	if !jsonEqual(want, got) {
+		*differences = append(*differences, fmt.Sprintf("%s: got %s, recorded %s", displayPath(path), displayValue(got), displayValue(want)))
+	}
+}
+
+// ignored reports whether a path matches one of the ignore patterns
+func (r *replay) ignored(path []string) bool {
+	for _, pattern := range r.ignore {
+		if matchPath(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIgnorePatterns splits ignore patterns into their segments
+func parseIgnorePatterns(patterns []string) [][]string {
+	parsed := make([][]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			parsed = append(parsed, strings.Split(pattern, "."))
+		}
+	}
+	return parsed
+}
+
+// matchPath reports whether a path matches a pattern, in which * matches one
+// segment and ** any number of segments
+func matchPath(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchPath(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 || (pattern[0] != "*" && pattern[0] != path[0]) {
+		return false
+	}
+	return matchPath(pattern[1:], path[1:])
+}
+
+// displayPath names a field of a response
+func displayPath(path []string) string {
+	if len(path) == 0 {
+		return "response"
+	}
+	return strings.Join(path, ".")
+}
+
+// displayValue shows a JSON value, shortened
+func displayValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	if len(data) > maxReplayValueLength {
+		return string(data[:maxReplayValueLength]) + "..."
+	}
+	return string(data)
+}
@@ -0,0 +1,89 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	recording := recordFakeSession(t)
+	messages, err := ReadRecording(recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		env    map[string]string
+		ignore []string
+		// want lists the methods of the mismatched requests and a part of
+		// their differences
+		want []string
+	}{
+		{
+			// The time of the call differs in every run
+			name: "time",
+			want: []string{"tools/call: result._meta.time: got"},
+		},
+		{
+			name:   "match",
+			ignore: []string{"result._meta.*"},
+		},
+		{
+			name:   "match-any-depth",
+			ignore: []string{"**.time"},
+		},
+		{
+			name:   "mismatch",
+			env:    map[string]string{"FAKE_DESCRIPTION": "Echo text back"},
+			ignore: []string{"result._meta"},
+			want:   []string{`tools/list: result.tools.0.description: got "Echo text back", recorded "Echo the text"`},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, project := newTestManager(t)
+			configPath := writeProjectConfig(t, project, &ProjectConfig{Servers: []MCPServer{fakeServer(tc.env)}})
+
+			result, err := m.Replay("fake", "", configPath, messages, tc.ignore, 5*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Requests != 3 {
+				t.Errorf("replayed %d requests, want 3", result.Requests)
+			}
+
+			var got []string
+			for _, mismatch := range result.Mismatches {
+				got = append(got, mismatch.Method+": "+strings.Join(mismatch.Differences, "; "))
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("got mismatches %q, want %q", got, tc.want)
+			}
+			for i := range got {
+				if !strings.HasPrefix(got[i], tc.want[i]) {
+					t.Errorf("got mismatch %q, want %q", got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, path string
+		match         bool
+	}{
+		{pattern: "result._meta.time", path: "result._meta.time", match: true},
+		{pattern: "result.*.time", path: "result._meta.time", match: true},
+		{pattern: "result.*", path: "result._meta.time", match: false},
+		{pattern: "result.**", path: "result._meta.time", match: true},
+		{pattern: "**.time", path: "result.content.0.time", match: true},
+		{pattern: "**.time", path: "time", match: true},
+		{pattern: "result.**.text", path: "result.content.0.type", match: false},
+	} {
+		if got := matchPath(strings.Split(tc.pattern, "."), strings.Split(tc.path, ".")); got != tc.match {
+			t.Errorf("matchPath(%s, %s) = %v, want %v", tc.pattern, tc.path, got, tc.match)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ResolveServer returns how to run an installed local server. Without a
+// version, the version locked or pinned for the server in mcpv.json is used,
+// otherwise the highest installed version. Args and env from mcpv.json apply
+// as they do in agent configurations, with variables and secrets resolved to
+// their values.
+func (m *Manager) ResolveServer(name, version, configPath string) (*MCPServer, error) {
+	config, err := m.loadProject(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &MCPServer{Name: name}
+	for _, configured := range config.Servers {
+		if configured.Name == name {
+			configured := configured
+			server = &configured
+			break
+		}
+	}
+	if server.IsRemote() {
+		return nil, fmt.Errorf("server %s is a remote server at %s and is not run locally", name, server.URL)
+	}
+
+	if version != "" {
+		server.Version = version
+	} else if server.Version == "" || isVersionRange(server.Version) {
+		lock, err := m.LoadLockFile(configPath)
+		if err != nil {
+			return nil, err
+		}
+		if locked := lock.Find(name); locked != nil {
+			server.Version = locked.Version
+		} else {
+			server.Version = currentInstalledVersion(m.installedVersions(name), server.Version)
+		}
+	}
+
+	// Commands recorded in mcpv.json at install time point into the install
+	// directory of one version; run the resolved version instead
+	installDir := filepath.Join(m.dataDir, name) + string(filepath.Separator)
+	if strings.HasPrefix(server.Command, installDir) || containsPrefix(server.Args, installDir) {
+		var args []string
+		for _, arg := range server.Args {
+			if !strings.HasPrefix(arg, installDir) {
+				args = append(args, arg)
+			}
+		}
+		server.Command, server.Args = "", args
+	}
+
+	if server.Command == "" {
+		if server.Version == "" {
+			return nil, fmt.Errorf("server %s is not installed. Use 'mcpv install %s@<version>' to install it", name, name)
+		}
+		server.InstallPath = filepath.Join(m.dataDir, name, server.Version)
+		if _, err := os.Stat(server.InstallPath); os.IsNotExist(err) {
+			return nil, fmt.Errorf("server %s@%s is not installed. Use 'mcpv install %s@%s' to install it", name, server.Version, name, server.Version)
+		}
+
+		m.fillExecutionDetails(server)
+		if server.Command == "" {
+			return nil, fmt.Errorf("could not determine how to run server %s@%s", name, server.Version)
+		}
+	}
+
+	project, err := m.projectSettings()
+	if err != nil {
+		return nil, err
+	}
+	return project.variables.server(server, ""), nil
+}
+
+// containsPrefix reports whether any of the values starts with prefix
+func containsPrefix(values []string, prefix string) bool {
+	for _, value := range values {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// installedVersions returns the installed versions of a server
+func (m *Manager) installedVersions(name string) []string {
+	entries, err := os.ReadDir(filepath.Join(m.dataDir, name))
+	if err != nil {
+		return nil
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			versions = append(versions, entry.Name())
+		}
+	}
+	return versions
+}
+
+// ServerCommand returns the command that runs a resolved server, with extra
+// arguments appended and the server's env added to the current environment.
+// Standard input and output are left for the caller to attach.
+func ServerCommand(server *MCPServer, extraArgs ...string) *exec.Cmd {
+	args := append(append([]string{}, server.Args...), extraArgs...)
+	cmd := exec.Command(server.Command, args...)
+
+	names := make([]string, 0, len(server.Env))
+	for name := range server.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cmd.Env = os.Environ()
+	for _, name := range names {
+		cmd.Env = append(cmd.Env, name+"="+server.Env[name])
+	}
+	return cmd
+}
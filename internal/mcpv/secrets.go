@@ -0,0 +1,487 @@
+package manager
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Secret stores
+const (
+	SecretStoreSecretService = "secret-service"
+	SecretStoreFile          = "file"
+)
+
+const (
+	// secretStoreEnv selects the secret store, overriding the default
+	secretStoreEnv = "MCPV_SECRET_STORE"
+
+	// secretPassphraseEnv holds the passphrase of the file store. Without
+	// it, a key generated on first use is kept next to the store.
+	secretPassphraseEnv = "MCPV_SECRETS_PASSPHRASE"
+
+	secretIndexFileName = "secrets.json"
+	secretFileName      = "secrets.enc"
+	secretKeyFileName   = "secrets.key"
+
+	// secretService is the service attribute of secrets in the Secret Service
+	secretService = "mcpv"
+
+	secretKDFIterations = 600000
+)
+
+// Secret identifies a stored secret. Values are never part of it.
+type Secret struct {
+	Server string `json:"server" yaml:"server"`
+	Name   string `json:"name" yaml:"name"`
+}
+
+// secretStore keeps secret values by server and name
+type secretStore interface {
+	name() string
+	get(server, name string) (string, bool, error)
+	set(server, name, value string) error
+	remove(server, name string) error
+}
+
+// secretIndex lists the names of the secrets stored per server, so secrets can
+// be listed and injected without querying the store for every server
+type secretIndex struct {
+	Store   string              `json:"store"`
+	Servers map[string][]string `json:"servers"`
+}
+
+// SecretStoreName returns the secret store in use
+func (m *Manager) SecretStoreName() string {
+	return m.secretStore().name()
+}
+
+// secretStore returns the configured secret store: the Secret Service when
+// secret-tool is available, an encrypted file otherwise. The store is chosen
+// once per manager so the encrypted file is only decrypted once.
+func (m *Manager) secretStore() secretStore {
+	if m.secrets == nil {
+		m.secrets = m.newSecretStore()
+	}
+	return m.secrets
+}
+
+// newSecretStore chooses the secret store
+func (m *Manager) newSecretStore() secretStore {
+	file := &fileSecretStore{
+		path:    filepath.Join(m.stateDir, secretFileName),
+		keyPath: filepath.Join(m.stateDir, secretKeyFileName),
+	}
+
+	switch os.Getenv(secretStoreEnv) {
+	case SecretStoreFile:
+		return file
+	case SecretStoreSecretService:
+		return secretServiceStore{}
+	}
+
+	if _, err := exec.LookPath("secret-tool"); err == nil {
+		return secretServiceStore{}
+	}
+	return file
+}
+
+// SetSecret stores a secret for a server
+func (m *Manager) SetSecret(server, name, value string) error {
+	if !isVariableName(name) {
+		return fmt.Errorf("invalid secret name %q: use letters, digits and underscores", name)
+	}
+
+	index, err := m.loadSecretIndex()
+	if err != nil {
+		return err
+	}
+	store := m.secretStore()
+	m.checkSecretStore(index, store)
+	if err := store.set(server, name, value); err != nil {
+		return fmt.Errorf("failed to store secret in %s: %w", store.name(), err)
+	}
+	return m.updateSecretIndex(store, server, name, true)
+}
+
+// RemoveSecret deletes a secret of a server, reporting whether it existed
+func (m *Manager) RemoveSecret(server, name string) (bool, error) {
+	index, err := m.loadSecretIndex()
+	if err != nil {
+		return false, err
+	}
+	if !containsString(index.Servers[server], name) {
+		return false, nil
+	}
+
+	store := m.secretStore()
+	m.checkSecretStore(index, store)
+	if err := store.remove(server, name); err != nil {
+		return false, fmt.Errorf("failed to remove secret from %s: %w", store.name(), err)
+	}
+	return true, m.updateSecretIndex(store, server, name, false)
+}
+
+// ListSecrets returns the stored secrets, for one server or all of them
+func (m *Manager) ListSecrets(server string) ([]Secret, error) {
+	index, err := m.loadSecretIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	m.checkSecretStore(index, m.secretStore())
+
+	secrets := []Secret{}
+	for serverName, names := range index.Servers {
+		if server != "" && serverName != server {
+			continue
+		}
+		for _, name := range names {
+			secrets = append(secrets, Secret{Server: serverName, Name: name})
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		if secrets[i].Server != secrets[j].Server {
+			return secrets[i].Server < secrets[j].Server
+		}
+		return secrets[i].Name < secrets[j].Name
+	})
+	return secrets, nil
+}
+
+// serverSecrets returns the values of the secrets stored for a server
+func (m *Manager) serverSecrets(server string) (map[string]string, error) {
+	index, err := m.loadSecretIndex()
+	if err != nil {
+		return nil, err
+	}
+	names := index.Servers[server]
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	store := m.secretStore()
+	m.checkSecretStore(index, store)
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		value, ok, err := store.get(server, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret %s of %s from %s: %w", name, server, store.name(), err)
+		}
+		if ok {
+			values[name] = value
+		}
+	}
+	return values, nil
+}
+
+// checkSecretStore warns, once, when the index records secrets kept in
+// another store than the one in use, whose values the store cannot read
+func (m *Manager) checkSecretStore(index *secretIndex, store secretStore) {
+	if m.secretStoreWarned || len(index.Servers) == 0 || index.Store == "" || index.Store == store.name() {
+		return
+	}
+	m.secretStoreWarned = true
+	fmt.Fprintf(m.out, "Warning: secrets were stored in %s, but %s is in use; set %s=%s to use them\n", index.Store, store.name(), secretStoreEnv, index.Store)
+}
+
+// loadSecretIndex reads the index of stored secrets
+func (m *Manager) loadSecretIndex() (*secretIndex, error) {
+	index := &secretIndex{Servers: map[string][]string{}}
+
+	data, err := os.ReadFile(filepath.Join(m.stateDir, secretIndexFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return nil, fmt.Errorf("failed to read secret index: %w", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse secret index: %w", err)
+	}
+	if index.Servers == nil {
+		index.Servers = map[string][]string{}
+	}
+	return index, nil
+}
+
+// updateSecretIndex records that a secret was stored or removed
+func (m *Manager) updateSecretIndex(store secretStore, server, name string, stored bool) error {
+	index, err := m.loadSecretIndex()
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, existing := range index.Servers[server] {
+		if existing != name {
+			names = append(names, existing)
+		}
+	}
+	if stored {
+		names = append(names, name)
+		sort.Strings(names)
+	}
+	if len(names) == 0 {
+		delete(index.Servers, server)
+	} else {
+		index.Servers[server] = names
+	}
+	index.Store = store.name()
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal secret index: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(m.stateDir, secretIndexFileName), data, 0600)
+}
+
+// secretServiceStore keeps secrets in the freedesktop Secret Service (GNOME
+// Keyring, KWallet) through secret-tool
+type secretServiceStore struct{}
+
+func (secretServiceStore) name() string {
+	return SecretStoreSecretService
+}
+
+func (s secretServiceStore) get(server, name string) (string, bool, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "lookup", "service", secretService, "server", server, "name", name)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// secret-tool exits with status 1 and no output when nothing matches
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+			return "", false, nil
+		}
+		return "", false, secretToolError(err, stderr.String())
+	}
+	return stdout.String(), true, nil
+}
+
+func (s secretServiceStore) set(server, name, value string) error {
+	var stderr bytes.Buffer
+	label := fmt.Sprintf("mcpv %s %s", server, name)
+	cmd := exec.Command("secret-tool", "store", "--label", label, "service", secretService, "server", server, "name", name)
+	cmd.Stdin = strings.NewReader(value)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return secretToolError(err, stderr.String())
+	}
+	return nil
+}
+
+func (s secretServiceStore) remove(server, name string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "clear", "service", secretService, "server", server, "name", name)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return secretToolError(err, stderr.String())
+	}
+	return nil
+}
+
+// secretToolError describes a failed secret-tool invocation
+func secretToolError(err error, stderr string) error {
+	if stderr = strings.TrimSpace(stderr); stderr != "" {
+		return fmt.Errorf("secret-tool: %s (set %s=%s to use an encrypted file instead)", stderr, secretStoreEnv, SecretStoreFile)
+	}
+	return fmt.Errorf("secret-tool: %w (set %s=%s to use an encrypted file instead)", err, secretStoreEnv, SecretStoreFile)
+}
+
+// fileSecretStore keeps secrets in a file encrypted with AES-GCM. The key is
+// derived from MCPV_SECRETS_PASSPHRASE or, without it, generated on first use
+// and kept in a key file readable only by the user.
+type fileSecretStore struct {
+	path    string
+	keyPath string
+
+	// secrets and salt cache the decrypted file
+	secrets map[string]map[string]string
+	salt    []byte
+
+	// aead caches the cipher derived for aeadSalt
+	aead     cipher.AEAD
+	aeadSalt []byte
+}
+
+// encryptedSecrets is the layout of the encrypted secrets file
+type encryptedSecrets struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+func (s *fileSecretStore) name() string {
+	return SecretStoreFile
+}
+
+func (s *fileSecretStore) get(server, name string) (string, bool, error) {
+	secrets, _, err := s.load()
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := secrets[server][name]
+	return value, ok, nil
+}
+
+func (s *fileSecretStore) set(server, name, value string) error {
+	secrets, salt, err := s.load()
+	if err != nil {
+		return err
+	}
+	if secrets[server] == nil {
+		secrets[server] = map[string]string{}
+	}
+	secrets[server][name] = value
+	return s.save(secrets, salt)
+}
+
+func (s *fileSecretStore) remove(server, name string) error {
+	secrets, salt, err := s.load()
+	if err != nil {
+		return err
+	}
+	delete(secrets[server], name)
+	if len(secrets[server]) == 0 {
+		delete(secrets, server)
+	}
+	return s.save(secrets, salt)
+}
+
+// load decrypts the secrets file, returning its secrets and salt
+func (s *fileSecretStore) load() (map[string]map[string]string, []byte, error) {
+	if s.secrets != nil {
+		return s.secrets, s.salt, nil
+	}
+	secrets := map[string]map[string]string{}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return secrets, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to read %s: %w", s.path, err)
+	}
+
+	var file encryptedSecrets
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+
+	aead, err := s.cipher(file.Salt, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt %s: wrong key or passphrase", s.path)
+	}
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse decrypted secrets: %w", err)
+	}
+	s.secrets, s.salt = secrets, file.Salt
+	return secrets, file.Salt, nil
+}
+
+// save encrypts and writes the secrets file
+func (s *fileSecretStore) save(secrets map[string]map[string]string, salt []byte) error {
+	if salt == nil {
+		salt = make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+	}
+
+	aead, err := s.cipher(salt, true)
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(encryptedSecrets{
+		Salt:  salt,
+		Nonce: nonce,
+		Data:  aead.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, data, 0600); err != nil {
+		return err
+	}
+	s.secrets, s.salt = secrets, salt
+	return nil
+}
+
+// cipher returns the AES-GCM cipher for the store, creating the key file when
+// create is set and no passphrase is configured
+func (s *fileSecretStore) cipher(salt []byte, create bool) (cipher.AEAD, error) {
+	if s.aead != nil && bytes.Equal(salt, s.aeadSalt) {
+		return s.aead, nil
+	}
+
+	var key []byte
+	if passphrase := os.Getenv(secretPassphraseEnv); passphrase != "" {
+		var err error
+		if key, err = pbkdf2.Key(sha256.New, passphrase, salt, secretKDFIterations, 32); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if key, err = s.key(create); err != nil {
+			return nil, err
+		}
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	s.aead, s.aeadSalt = aead, salt
+	return aead, nil
+}
+
+// key reads the generated key, creating it when asked to
+func (s *fileSecretStore) key(create bool) ([]byte, error) {
+	key, err := os.ReadFile(s.keyPath)
+	if err == nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("invalid secret key in %s", s.keyPath)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) || !create {
+		return nil, fmt.Errorf("failed to read secret key: %w (set %s if the secrets were stored with a passphrase)", err, secretPassphraseEnv)
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(s.keyPath, key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write secret key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,202 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileSecretStore(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+	}{
+		{"key file", ""},
+		{"passphrase", "correct horse battery staple"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager(t)
+			t.Setenv(secretStoreEnv, SecretStoreFile)
+			t.Setenv(secretPassphraseEnv, tt.passphrase)
+
+			if err := m.SetSecret("github", "TOKEN", "s3cret"); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.SetSecret("github", "ORG", "acme"); err != nil {
+				t.Fatal(err)
+			}
+
+			values, err := m.serverSecrets("github")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := map[string]string{"TOKEN": "s3cret", "ORG": "acme"}; !reflect.DeepEqual(values, want) {
+				t.Errorf("got secrets %v, want %v", values, want)
+			}
+
+			files := []string{secretFileName, secretIndexFileName}
+			if tt.passphrase == "" {
+				files = append(files, secretKeyFileName)
+			} else if _, err := os.Stat(filepath.Join(m.stateDir, secretKeyFileName)); !os.IsNotExist(err) {
+				t.Errorf("a key file was written although a passphrase is set: %v", err)
+			}
+			for _, name := range files {
+				info, err := os.Stat(filepath.Join(m.stateDir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if perm := info.Mode().Perm(); perm != 0600 {
+					t.Errorf("%s has mode %o, want 600", name, perm)
+				}
+			}
+			data, err := os.ReadFile(filepath.Join(m.stateDir, secretFileName))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(data, []byte("s3cret")) {
+				t.Error("the secrets file holds a secret in plain text")
+			}
+
+			removed, err := m.RemoveSecret("github", "TOKEN")
+			if err != nil || !removed {
+				t.Fatalf("failed to remove TOKEN: %v %v", removed, err)
+			}
+			if values, err = m.serverSecrets("github"); err != nil {
+				t.Fatal(err)
+			}
+			if want := map[string]string{"ORG": "acme"}; !reflect.DeepEqual(values, want) {
+				t.Errorf("got secrets %v after removing TOKEN, want %v", values, want)
+			}
+			secrets, err := m.ListSecrets("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []Secret{{Server: "github", Name: "ORG"}}; !reflect.DeepEqual(secrets, want) {
+				t.Errorf("listed %v, want %v", secrets, want)
+			}
+		})
+	}
+}
+
+func TestFileSecretStoreWrongPassphrase(t *testing.T) {
+	m, _ := newTestManager(t)
+	t.Setenv(secretStoreEnv, SecretStoreFile)
+	t.Setenv(secretPassphraseEnv, "right")
+	if err := m.SetSecret("github", "TOKEN", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A later run with another passphrase
+	t.Setenv(secretPassphraseEnv, "wrong")
+	m = &Manager{stateDir: m.stateDir, out: io.Discard}
+	if _, err := m.serverSecrets("github"); err == nil || !strings.Contains(err.Error(), "wrong key or passphrase") {
+		t.Errorf("expected a wrong passphrase error, got %v", err)
+	}
+	if err := m.SetSecret("github", "ORG", "acme"); err == nil {
+		t.Error("a secret was stored with the wrong passphrase, overwriting the others")
+	}
+}
+
+func TestFileSecretStoreCorruptedFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(data []byte) []byte
+		want    string
+	}{
+		{"not json", func([]byte) []byte { return []byte("garbage") }, "failed to parse"},
+		{"tampered data", func(data []byte) []byte {
+			var file encryptedSecrets
+			if err := json.Unmarshal(data, &file); err != nil {
+				t.Fatal(err)
+			}
+			file.Data[0] ^= 0xff
+			data, _ = json.Marshal(file)
+			return data
+		}, "failed to decrypt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager(t)
+			t.Setenv(secretStoreEnv, SecretStoreFile)
+			t.Setenv(secretPassphraseEnv, "")
+			if err := m.SetSecret("github", "TOKEN", "s3cret"); err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join(m.stateDir, secretFileName)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, tt.corrupt(data), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			m = &Manager{stateDir: m.stateDir, out: io.Discard}
+			if _, err := m.serverSecrets("github"); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSecretsInAnotherStoreWarn(t *testing.T) {
+	m, _ := newTestManager(t)
+	var out bytes.Buffer
+	m.SetOutput(&out)
+	t.Setenv(secretStoreEnv, SecretStoreFile)
+
+	index, err := json.Marshal(secretIndex{Store: SecretStoreSecretService, Servers: map[string][]string{"github": {"TOKEN"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(m.stateDir, secretIndexFileName), index, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.ListSecrets(""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.serverSecrets("github"); err != nil {
+		t.Fatal(err)
+	}
+	if warnings := strings.Count(out.String(), "Warning: secrets were stored in secret-service"); warnings != 1 {
+		t.Errorf("expected one warning about the other store, got:\n%s", out.String())
+	}
+}
+
+func TestFileSecretStoreDecryptsOnce(t *testing.T) {
+	m, _ := newTestManager(t)
+	t.Setenv(secretStoreEnv, SecretStoreFile)
+	t.Setenv(secretPassphraseEnv, "correct horse battery staple")
+	for _, server := range []string{"github", "search"} {
+		if err := m.SetSecret(server, "TOKEN", server+"-token"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m = &Manager{stateDir: m.stateDir, out: io.Discard}
+	if _, err := m.serverSecrets("github"); err != nil {
+		t.Fatal(err)
+	}
+	// Later servers' secrets come from the store decrypted for the first
+	path := filepath.Join(m.stateDir, secretFileName)
+	if err := os.WriteFile(path, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	values, err := m.serverSecrets("search")
+	if err != nil {
+		t.Fatalf("the secrets file was read again for another server: %v", err)
+	}
+	if values["TOKEN"] != "search-token" {
+		t.Errorf("got secrets %v for search", values)
+	}
+}
@@ -0,0 +1,155 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/socialviolation/mcpv/internal/mcp"
+)
+
+// sessionExitGrace is how long a server that stopped answering is given to
+// exit, so its exit status can be reported
+const sessionExitGrace = 500 * time.Millisecond
+
+// serverSession is an initialized MCP session with a server: a local server
+// started over stdio, or a remote server reached over HTTP
+type serverSession struct {
+	server      *MCPServer
+	client      *mcp.Client
+	stdio       *mcp.StdioTransport
+	initialized *mcp.InitializeResult
+}
+
+// ConnectableServer resolves a server to connect to: a remote server from
+// mcpv.json with its headers resolved, or an installed local server
+func (m *Manager) ConnectableServer(name, version, configPath string) (*MCPServer, error) {
+	config, err := m.loadProject(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, configured := range config.Servers {
+		if configured.Name != name || !configured.IsRemote() {
+			continue
+		}
+		if version != "" {
+			return nil, fmt.Errorf("server %s is a remote server and has no versions", name)
+		}
+		project, err := m.projectSettings()
+		if err != nil {
+			return nil, err
+		}
+		return project.variables.server(&configured, ""), nil
+	}
+
+	return m.ResolveServer(name, version, configPath)
+}
+
+// openSession connects to a resolved server and performs the initialize
+// handshake. A failure to start or initialize a local server is described
+// with its exit status and the end of its standard error. A local server's
+// standard error is also written to stderr, if set.
+func openSession(ctx context.Context, server *MCPServer, stderr io.Writer) (*serverSession, error) {
+	transport, stdio, err := dialServer(ctx, server, nil, stderr)
+	if err != nil {
+		return nil, err
+	}
+	session := &serverSession{server: server, stdio: stdio}
+	session.client = mcp.NewClient(transport, ClientInfo)
+
+	initialized, err := session.client.Initialize(ctx)
+	if err != nil {
+		if session.stdio != nil && ctx.Err() == nil {
+			select {
+			case <-session.stdio.Exited():
+				err = fmt.Errorf("%w; %v", err, session.stdio.ExitError())
+			case <-time.After(sessionExitGrace):
+			}
+		}
+		session.close()
+		return nil, &sessionError{err: err, stderr: session.stderr()}
+	}
+
+	session.initialized = initialized
+	return session, nil
+}
+
+// dialServer connects to a remote server or starts a local one with extra
+// arguments, returning its transport and, for a local server, the transport
+// of its process. A local server's standard error is written to stderr, if set.
+func dialServer(ctx context.Context, server *MCPServer, args []string, stderr io.Writer) (mcp.Transport, *mcp.StdioTransport, error) {
+	switch server.transport() {
+	case TransportHTTP:
+		return mcp.NewHTTPTransport(server.URL, server.Headers), nil, nil
+	case TransportSSE:
+		sse, err := mcp.DialSSE(ctx, server.URL, server.Headers)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to connect to %s: %w", server.URL, err)
+		}
+		return sse, nil, nil
+	default:
+		cmd := ServerCommand(server, args...)
+		cmd.Stderr = stderr
+		stdio, err := mcp.StartStdio(cmd)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to start %s: %w", server.Command, err)
+		}
+		return stdio, stdio, nil
+	}
+}
+
+// close ends the session, stopping a local server
+func (s *serverSession) close() {
+	s.client.Close()
+}
+
+// stderr returns the end of a local server's standard error
+func (s *serverSession) stderr() string {
+	if s.stdio == nil {
+		return ""
+	}
+	return strings.TrimSpace(s.stdio.Stderr())
+}
+
+// hasCapability reports whether the server offers a capability
+func (s *serverSession) hasCapability(name string) bool {
+	_, ok := s.initialized.Capabilities[name]
+	return ok
+}
+
+// failure describes a request that failed during a session, with the end of
+// a local server's standard error
+func (s *serverSession) failure(err error) error {
+	if stderr := s.stderr(); stderr != "" {
+		return fmt.Errorf("%w\nServer output:\n%s", err, stderr)
+	}
+	return err
+}
+
+// withServerOutput adds the output of a server that failed to open a session
+// to the error
+func withServerOutput(err error) error {
+	var sessionErr *sessionError
+	if errors.As(err, &sessionErr) && sessionErr.stderr != "" {
+		return fmt.Errorf("%w\nServer output:\n%s", err, sessionErr.stderr)
+	}
+	return err
+}
+
+// sessionError is a failure to open a session, with the server's output
+type sessionError struct {
+	err    error
+	stderr string
+}
+
+func (e *sessionError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sessionError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,218 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Statuses of an agent's entry for a server, compared with mcpv.json
+const (
+	EntryMatches      = "matches"
+	EntryMissing      = "missing"
+	EntryDiffers      = "differs"
+	EntryUnmanaged    = "unmanaged"
+	EntryOrphaned     = "orphaned"
+	EntryNotInstalled = "not_installed"
+	EntryError        = "error"
+)
+
+// AgentEntryStatus compares an agent's entry for a server with mcpv.json.
+// Unmanaged entries were added by the user; orphaned entries were written by
+// mcpv for servers no longer in mcpv.json. Fixed is set once a drifted entry
+// has been reconciled.
+type AgentEntryStatus struct {
+	Agent  AgentType `json:"agent" yaml:"agent"`
+	Path   string    `json:"path" yaml:"path"`
+	Server string    `json:"server" yaml:"server"`
+	Status string    `json:"status" yaml:"status"`
+	Fields []string  `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Fixed  bool      `json:"fixed,omitempty" yaml:"fixed,omitempty"`
+	Error  string    `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Drifted reports whether the entry differs from what mcpv.json specifies
+func (s AgentEntryStatus) Drifted() bool {
+	switch s.Status {
+	case EntryMissing, EntryDiffers, EntryOrphaned:
+		return true
+	}
+	return false
+}
+
+// OutOfSync reports whether the entry still needs attention: it has drifted
+// and was not fixed, or it could not be checked
+func (s AgentEntryStatus) OutOfSync() bool {
+	return (s.Drifted() && !s.Fixed) || s.Status == EntryError
+}
+
+// Describe summarizes the status for display
+func (s AgentEntryStatus) Describe() string {
+	description := s.Status
+	switch {
+	case s.Error != "":
+		description = fmt.Sprintf("%s (%s)", s.Status, s.Error)
+	case len(s.Fields) > 0:
+		description = fmt.Sprintf("%s (%s)", s.Status, strings.Join(s.Fields, ", "))
+	}
+	if s.Fixed {
+		description += ", fixed"
+	}
+	return description
+}
+
+// AgentStatus compares the configuration of each agent with the servers in
+// mcpv.json. With fix set, drifted entries are rewritten or removed in a
+// single transaction and reported as fixed.
+func (m *Manager) AgentStatus(configPath string, agentTypes []AgentType, useLocal, fix bool) ([]AgentEntryStatus, error) {
+	config, err := m.loadProject(configPath)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := m.expectedAgentServers(config, configPath)
+	if err != nil {
+		return nil, err
+	}
+	project, err := m.projectSettings()
+	if err != nil {
+		return nil, err
+	}
+	if project.launcher != nil && project.launcher.kind == LauncherServe {
+		expected = []*MCPServer{project.launcher.gateway()}
+	}
+	managed, err := m.loadManagedEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := m.newAgentConfigTransaction()
+	statuses := []AgentEntryStatus{}
+
+	for _, agentType := range agentTypes {
+		file, err := tx.agentFile(agentType, useLocal)
+		if err != nil {
+			statuses = append(statuses, AgentEntryStatus{Agent: agentType, Status: EntryError, Error: err.Error()})
+			continue
+		}
+		entries, err := file.servers()
+		if err != nil {
+			statuses = append(statuses, AgentEntryStatus{Agent: agentType, Path: file.path, Status: EntryError, Error: err.Error()})
+			continue
+		}
+
+		for _, server := range expected {
+			status := AgentEntryStatus{Agent: agentType, Path: file.path, Server: server.Name}
+			entry, exists := entries[server.Name]
+
+			switch {
+			case !server.IsRemote() && server.Command == "":
+				status.Status = EntryNotInstalled
+				status.Error = "server is not installed; run 'mcpv install'"
+			case !exists:
+				status.Status = EntryMissing
+			default:
+				status.Fields = differingFields(entry, file.entryFields(server, false))
+				status.Status = EntryMatches
+				if len(status.Fields) > 0 {
+					status.Status = EntryDiffers
+				}
+			}
+
+			if fix && status.Drifted() {
+				if err := file.setServer(server); err != nil {
+					return nil, err
+				}
+				status.Fixed = true
+			}
+			statuses = append(statuses, status)
+		}
+
+		var others []string
+		for name := range entries {
+			if !containsServer(expected, name) {
+				others = append(others, name)
+			}
+		}
+		sort.Strings(others)
+
+		for _, name := range others {
+			status := AgentEntryStatus{Agent: agentType, Path: file.path, Server: name, Status: EntryUnmanaged}
+			// Entries mcpv wrote for other projects sharing a user-level
+			// file are theirs to remove
+			if managed.owner(file.path, name) == m.projectPath {
+				status.Status = EntryOrphaned
+				if fix {
+					if _, err := file.removeServer(name); err != nil {
+						return nil, err
+					}
+					status.Fixed = true
+				}
+			}
+			statuses = append(statuses, status)
+		}
+	}
+
+	if fix {
+		if err := tx.commit("reconcile agents with mcpv.json"); err != nil {
+			return nil, err
+		}
+	}
+
+	return statuses, nil
+}
+
+// expectedAgentServers returns the servers of mcpv.json with the execution
+// details agents should be configured with
+func (m *Manager) expectedAgentServers(config *ProjectConfig, configPath string) ([]*MCPServer, error) {
+	lock, err := m.LoadLockFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make([]*MCPServer, 0, len(config.Servers))
+	for _, configured := range config.Servers {
+		server := configured
+		if !server.IsRemote() && server.Command == "" {
+			if locked := lock.Find(server.Name); locked != nil && allowsVersion(server.Version, locked.Version) {
+				server.Version = locked.Version
+			}
+			if server.Version == "" {
+				server.Version = "latest"
+			}
+			m.fillExecutionDetails(&server)
+		}
+		servers = append(servers, &server)
+	}
+	return servers, nil
+}
+
+// differingFields returns the names of fields whose value in an agent's entry
+// differs from the expected one
+func differingFields(entry interface{}, fields orderedObject) []string {
+	values, _ := entry.(map[string]interface{})
+
+	var differing []string
+	for _, field := range fields {
+		value, exists := values[field.Key]
+		if field.Value == nil {
+			if exists {
+				differing = append(differing, field.Key)
+			}
+			continue
+		}
+		if !exists || !jsonEqual(value, field.Value) {
+			differing = append(differing, field.Key)
+		}
+	}
+	return differing
+}
+
+// containsServer reports whether a server of the given name is in the list
+func containsServer(servers []*MCPServer, name string) bool {
+	for _, server := range servers {
+		if server.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package manager
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// statusSummary maps each server of a status report to its status and fields
+func statusSummary(statuses []AgentEntryStatus) map[string]string {
+	summary := map[string]string{}
+	for _, status := range statuses {
+		summary[status.Server] = status.Describe()
+	}
+	return summary
+}
+
+func TestAgentStatusAndSync(t *testing.T) {
+	m, project := newTestManager(t)
+	configPath := writeProjectConfig(t, project, &ProjectConfig{Servers: []MCPServer{
+		{Name: "kept", Command: "node", Args: []string{"kept.js"}},
+		{Name: "changed", Command: "node", Args: []string{"new.js"}},
+		{Name: "added", Command: "node", Args: []string{"added.js"}},
+	}})
+	m.UseProject(configPath)
+
+	// mcpv wrote kept, changed and gone for this project, then gone was
+	// dropped from mcpv.json and changed was edited
+	for _, server := range []*MCPServer{
+		{Name: "kept", Command: "node", Args: []string{"kept.js"}},
+		{Name: "changed", Command: "node", Args: []string{"old.js"}},
+		{Name: "gone", Command: "node", Args: []string{"gone.js"}},
+	} {
+		if err := m.AddServerToAgent("cursor", server); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The user added handmade, and another project wrote theirs to the same file
+	path := filepath.Join(project, ".cursor", "mcp.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config map[string]map[string]interface{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	config["mcpServers"]["handmade"] = map[string]interface{}{"command": "handmade"}
+	config["mcpServers"]["theirs"] = map[string]interface{}{"command": "theirs"}
+	if data, err = json.Marshal(config); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	managed, err := m.loadManagedEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	managed.set(path, "theirs", filepath.Join(t.TempDir(), "mcpv.json"))
+	if err := m.saveManagedEntries(managed); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses, err := m.AgentStatus(configPath, []AgentType{"cursor"}, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"kept":     EntryMatches,
+		"changed":  EntryDiffers + " (args)",
+		"added":    EntryMissing,
+		"gone":     EntryOrphaned,
+		"handmade": EntryUnmanaged,
+		"theirs":   EntryUnmanaged,
+	}
+	if got := statusSummary(statuses); !reflect.DeepEqual(got, want) {
+		t.Errorf("got statuses %v, want %v", got, want)
+	}
+
+	result, err := m.SyncAgents(configPath, []AgentType{"cursor"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]string{
+		"kept":     EntryMatches,
+		"changed":  EntryDiffers + " (args), fixed",
+		"added":    EntryMissing + ", fixed",
+		"gone":     EntryOrphaned + ", fixed",
+		"handmade": EntryUnmanaged,
+		"theirs":   EntryUnmanaged,
+	}
+	if got := statusSummary(result.Entries); !reflect.DeepEqual(got, want) {
+		t.Errorf("sync reported %v, want %v", got, want)
+	}
+
+	if data, err = os.ReadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	config = nil
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for name := range config["mcpServers"] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if want := []string{"added", "changed", "handmade", "kept", "theirs"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("after sync cursor has %v, want %v", names, want)
+	}
+	if args := entryArgs(readAgentEntry(t, path, "mcpServers", "changed")); !reflect.DeepEqual(args, []string{"new.js"}) {
+		t.Errorf("changed was not rewritten: args %v", args)
+	}
+
+	statuses, err = m.AgentStatus(configPath, []AgentType{"cursor"}, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.OutOfSync() {
+			t.Errorf("%s is still out of sync after sync: %s", status.Server, status.Describe())
+		}
+	}
+}
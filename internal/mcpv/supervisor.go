@@ -0,0 +1,404 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/socialviolation/mcpv/internal/mcp"
+)
+
+// Restart policies for servers run by mcpv serve and mcpv bridge --shared
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+// Restart defaults
+const (
+	DefaultMaxRestarts    = 5
+	DefaultRestartBackoff = time.Second
+
+	// maxRestartBackoff bounds the delay before a restart
+	maxRestartBackoff = time.Minute
+	// restartStableAfter is how long a process must run for its exit to
+	// reset the backoff and the count of restarts in a row
+	restartStableAfter = time.Minute
+)
+
+// States of supervised processes
+const (
+	ProcessRunning    = "running"
+	ProcessRestarting = "restarting"
+	ProcessStopped    = "stopped"
+)
+
+// maxLogSize is the size at which a server's log is rotated when a process
+// of it starts
+const maxLogSize = 10 << 20
+
+// logsDirName and processesDirName are the directories of the state
+// directory server logs and process records are written to
+const (
+	logsDirName      = "logs"
+	processesDirName = "processes"
+)
+
+// RestartPolicy says whether a local server that exits is restarted by mcpv
+// serve and mcpv bridge --shared
+type RestartPolicy struct {
+	// Policy is never, on-failure (the default: restart unless the server
+	// exited with status 0) or always
+	Policy string `json:"policy,omitempty"`
+	// MaxRestarts is how many times in a row a server is restarted before
+	// mcpv gives up, 5 by default. Restarts of a process that ran for a
+	// minute or more do not count.
+	MaxRestarts int `json:"max_restarts,omitempty"`
+	// Backoff is the delay before the first restart in a row, such as "2s",
+	// doubled for each further one up to a minute; 1s by default
+	Backoff string `json:"backoff,omitempty"`
+}
+
+// validate checks the policy's settings
+func (p *RestartPolicy) validate() error {
+	if p == nil {
+		return nil
+	}
+	switch p.Policy {
+	case "", RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		return fmt.Errorf("unsupported policy %s; use %s, %s or %s", p.Policy, RestartNever, RestartOnFailure, RestartAlways)
+	}
+	if p.MaxRestarts < 0 {
+		return fmt.Errorf("max_restarts must not be negative")
+	}
+	if p.Backoff != "" {
+		if backoff, err := time.ParseDuration(p.Backoff); err != nil || backoff <= 0 {
+			return fmt.Errorf("backoff %q is not a positive duration such as 2s", p.Backoff)
+		}
+	}
+	return nil
+}
+
+// policy returns the restart policy, or the default one
+func (p *RestartPolicy) policy() string {
+	if p == nil || p.Policy == "" {
+		return RestartOnFailure
+	}
+	return p.Policy
+}
+
+// maxRestarts returns the number of restarts in a row allowed
+func (p *RestartPolicy) maxRestarts() int {
+	if p == nil || p.MaxRestarts == 0 {
+		return DefaultMaxRestarts
+	}
+	return p.MaxRestarts
+}
+
+// backoff returns the delay before the first restart in a row
+func (p *RestartPolicy) backoff() time.Duration {
+	if p != nil {
+		if backoff, err := time.ParseDuration(p.Backoff); err == nil {
+			return backoff
+		}
+	}
+	return DefaultRestartBackoff
+}
+
+// ProcessStatus describes a server process run by mcpv serve or mcpv bridge
+type ProcessStatus struct {
+	Server   string    `json:"server" yaml:"server"`
+	Version  string    `json:"version" yaml:"version"`
+	State    string    `json:"state" yaml:"state"`
+	PID      int       `json:"pid,omitempty" yaml:"pid,omitempty"`
+	Started  time.Time `json:"started,omitempty" yaml:"started,omitempty"`
+	Restarts int       `json:"restarts" yaml:"restarts"`
+	LastExit string    `json:"last_exit,omitempty" yaml:"last_exit,omitempty"`
+	Owner    string    `json:"owner" yaml:"owner"`
+	OwnerPID int       `json:"owner_pid" yaml:"owner_pid"`
+	Log      string    `json:"log" yaml:"log"`
+}
+
+// processSeq numbers the process records of this mcpv process
+var processSeq atomic.Int64
+
+// supervisor keeps the log file and process record of a local server run by
+// mcpv serve or mcpv bridge, and restarts it as its restart policy says
+type supervisor struct {
+	server  *MCPServer
+	restart bool
+	console io.Writer
+	path    string
+	log     io.WriteCloser
+
+	// stderr writes the server's standard error to the console, prefixed
+	// with its name, and to its log, with timestamps
+	stderr io.Writer
+
+	mu       sync.Mutex
+	status   ProcessStatus
+	failures int
+}
+
+// newSupervisor opens the log of a server and registers its process for
+// mcpv ps under owner, the command running it. Without restart the process
+// is only logged. A log that cannot be opened is reported to console.
+func (m *Manager) newSupervisor(server *MCPServer, owner string, restart bool, console io.Writer) *supervisor {
+	s := &supervisor{
+		server:  server,
+		restart: restart,
+		console: console,
+		path:    filepath.Join(m.stateDir, processesDirName, fmt.Sprintf("%d-%d.json", os.Getpid(), processSeq.Add(1))),
+		status: ProcessStatus{
+			Server:   server.Name,
+			Version:  server.Version,
+			State:    ProcessStopped,
+			Owner:    owner,
+			OwnerPID: os.Getpid(),
+			Log:      m.LogPath(server.Name),
+		},
+	}
+
+	if err := rotateServerLog(s.status.Log); err != nil {
+		fmt.Fprintf(console, "Warning: %v\n", err)
+	}
+	log, err := openServerLog(s.status.Log)
+	if err != nil {
+		fmt.Fprintf(console, "Warning: %v\n", err)
+		s.log = nopWriteCloser{io.Discard}
+	} else {
+		s.log = log
+	}
+	s.stderr = io.MultiWriter(
+		&prefixWriter{w: console, prefix: "[" + server.Name + "] "},
+		&prefixWriter{w: s.log, stamp: true},
+	)
+	return s
+}
+
+// LogPath returns the log file of a server run by mcpv serve or mcpv bridge
+func (m *Manager) LogPath(name string) string {
+	return filepath.Join(m.stateDir, logsDirName, name+".log")
+}
+
+// rotateServerLog moves a server's log to a .1 file once it grows past
+// maxLogSize
+func rotateServerLog(path string) error {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() <= maxLogSize {
+		return nil
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate log %s: %w", path, err)
+	}
+	return nil
+}
+
+// openServerLog opens a server's log for appending
+func openServerLog(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// started records a new process of the server
+func (s *supervisor) started(stdio *mcp.StdioTransport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.State = ProcessRunning
+	s.status.PID = stdio.PID()
+	s.status.Started = time.Now().UTC()
+	s.logf("started %s@%s (pid %d)", s.server.Name, s.server.Version, s.status.PID)
+	s.save()
+}
+
+// exited records that a process of the server exited or that a restart
+// failed, and returns whether to restart it and after what delay
+func (s *supervisor) exited(status string, failed bool) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.status.Started.IsZero() && time.Since(s.status.Started) >= restartStableAfter {
+		s.failures = 0
+	}
+	s.status.PID = 0
+	s.status.Started = time.Time{}
+	s.status.LastExit = status
+
+	policy := s.server.Restart.policy()
+	restart := s.restart && (policy == RestartAlways || (policy == RestartOnFailure && failed))
+	if restart && s.failures >= s.server.Restart.maxRestarts() {
+		s.status.State = ProcessStopped
+		s.report("%s@%s %s; giving up after %d restarts in a row", s.server.Name, s.server.Version, status, s.failures)
+		s.save()
+		return 0, false
+	}
+	if !restart {
+		s.status.State = ProcessStopped
+		s.report("%s@%s %s", s.server.Name, s.server.Version, status)
+		s.save()
+		return 0, false
+	}
+
+	delay := s.server.Restart.backoff() << s.failures
+	if delay > maxRestartBackoff || delay <= 0 {
+		delay = maxRestartBackoff
+	}
+	s.failures++
+	s.status.Restarts++
+	s.status.State = ProcessRestarting
+	s.report("%s@%s %s; restarting in %s", s.server.Name, s.server.Version, status, delay)
+	s.save()
+	return delay, true
+}
+
+// supervise waits for a process of the server to exit and starts a new one
+// with start as the restart policy says, until the policy gives up or done
+// is closed. It returns once the server is no longer running.
+func (s *supervisor) supervise(stdio *mcp.StdioTransport, start func() (*mcp.StdioTransport, error), done <-chan struct{}) {
+	for {
+		select {
+		case <-stdio.Exited():
+		case <-done:
+			return
+		}
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		delay, restart := s.exited(exitStatus(stdio))
+		for restart {
+			select {
+			case <-time.After(delay):
+			case <-done:
+				return
+			}
+
+			next, err := start()
+			if err == nil {
+				stdio = next
+				s.started(stdio)
+				break
+			}
+			delay, restart = s.exited(fmt.Sprintf("failed to restart: %v", err), true)
+		}
+		if !restart {
+			return
+		}
+	}
+}
+
+// close removes the process record and closes the log. A record that
+// cannot be removed is reported to the console.
+func (s *supervisor) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(s.console, "Warning: failed to remove process record: %v\n", err)
+	}
+	s.log.Close()
+}
+
+// report writes a line about the server to the console and its log
+func (s *supervisor) report(format string, args ...interface{}) {
+	fmt.Fprintf(s.console, format+"\n", args...)
+	s.logf(format, args...)
+}
+
+// logf writes a line of mcpv's own to the server's log
+func (s *supervisor) logf(format string, args ...interface{}) {
+	fmt.Fprintf(s.log, "%s mcpv: %s\n", time.Now().UTC().Format(time.RFC3339), fmt.Sprintf(format, args...))
+}
+
+// save writes the process record. A record that cannot be written is
+// reported to the console.
+func (s *supervisor) save() {
+	data, err := json.MarshalIndent(s.status, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(s.path, data, 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(s.console, "Warning: failed to write process record %s: %v\n", s.path, err)
+	}
+}
+
+// exitStatus describes how a process exited and whether it failed
+func exitStatus(stdio *mcp.StdioTransport) (string, bool) {
+	var exitErr *exec.ExitError
+	if errors.As(stdio.ExitError(), &exitErr) {
+		return "exited with " + exitErr.ProcessState.String(), true
+	}
+	return "exited with exit status 0", false
+}
+
+// Processes returns the server processes run by mcpv serve and mcpv bridge
+// instances that are still running. Records left behind by instances that
+// did not exit cleanly are removed.
+func (m *Manager) Processes() ([]ProcessStatus, error) {
+	dir := filepath.Join(m.stateDir, processesDirName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []ProcessStatus{}, nil
+		}
+		return nil, fmt.Errorf("failed to read processes: %w", err)
+	}
+
+	processes := []ProcessStatus{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var status ProcessStatus
+		if err := json.Unmarshal(data, &status); err != nil {
+			continue
+		}
+		if !processAlive(status.OwnerPID) {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				fmt.Fprintf(m.out, "Warning: failed to remove stale process record: %v\n", err)
+			}
+			continue
+		}
+		processes = append(processes, status)
+	}
+
+	sort.Slice(processes, func(i, j int) bool {
+		if processes[i].Server != processes[j].Server {
+			return processes[i].Server < processes[j].Server
+		}
+		return processes[i].OwnerPID < processes[j].OwnerPID
+	})
+	return processes, nil
+}
+
+// nopWriteCloser adds a Close that does nothing to a writer
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,127 @@
+package manager
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/socialviolation/mcpv/internal/mcp"
+)
+
+func TestSupervisorRestartPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		exit     string
+		restart  *RestartPolicy
+		starts   int
+		lastLine string
+	}{
+		{"on-failure gives up", "3", &RestartPolicy{MaxRestarts: 2, Backoff: "10ms"}, 3, "giving up after 2 restarts in a row"},
+		{"on-failure after success", "0", &RestartPolicy{MaxRestarts: 2, Backoff: "10ms"}, 1, "exited with exit status 0"},
+		{"never", "3", &RestartPolicy{Policy: RestartNever}, 1, "exited with exit status 3"},
+		{"always", "0", &RestartPolicy{Policy: RestartAlways, MaxRestarts: 1, Backoff: "10ms"}, 2, "giving up after 1 restarts in a row"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager(t)
+			server := fakeServer(map[string]string{"FAKE_EXIT": tt.exit})
+			server.Version = "v1.0.0"
+			server.Restart = tt.restart
+
+			var console bytes.Buffer
+			s := m.newSupervisor(&server, "serve", true, &console)
+			var started []*mcp.StdioTransport
+			start := func() (*mcp.StdioTransport, error) {
+				cmd := ServerCommand(&server)
+				cmd.Stderr = s.stderr
+				stdio, err := mcp.StartStdio(cmd)
+				if err == nil {
+					started = append(started, stdio)
+				}
+				return stdio, err
+			}
+			defer func() {
+				for _, stdio := range started {
+					stdio.Close()
+				}
+			}()
+
+			stdio, err := start()
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.started(stdio)
+			s.supervise(stdio, start, make(chan struct{}))
+
+			if len(started) != tt.starts {
+				t.Errorf("the server was started %d times, want %d", len(started), tt.starts)
+			}
+			lines := strings.Split(strings.TrimSpace(console.String()), "\n")
+			if last := lines[len(lines)-1]; !strings.Contains(last, tt.lastLine) {
+				t.Errorf("expected the console to end with %q, got:\n%s", tt.lastLine, console.String())
+			}
+
+			processes, err := m.Processes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(processes) != 1 || processes[0].State != ProcessStopped || processes[0].Restarts != tt.starts-1 {
+				t.Errorf("got process records %+v, want one stopped after %d restarts", processes, tt.starts-1)
+			}
+
+			s.close()
+			log, err := os.ReadFile(m.LogPath("fake"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count := strings.Count(string(log), "fake server exiting"); count != tt.starts {
+				t.Errorf("the log holds the standard error of %d processes, want %d:\n%s", count, tt.starts, log)
+			}
+			if count := strings.Count(string(log), "mcpv: started fake@v1.0.0"); count != tt.starts {
+				t.Errorf("the log records %d starts, want %d:\n%s", count, tt.starts, log)
+			}
+			if processes, err = m.Processes(); err != nil || len(processes) != 0 {
+				t.Errorf("the process record was kept after close: %+v %v", processes, err)
+			}
+		})
+	}
+}
+
+func TestSupervisorRotatesLog(t *testing.T) {
+	m, _ := newTestManager(t)
+	server := fakeServer(nil)
+	path := m.LogPath(server.Name)
+
+	s := m.newSupervisor(&server, "serve", true, &bytes.Buffer{})
+	s.logf("small")
+	s.close()
+	s = m.newSupervisor(&server, "serve", true, &bytes.Buffer{})
+	s.close()
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Fatalf("a log below %d bytes was rotated: %v", maxLogSize, err)
+	}
+
+	if err := os.Truncate(path, maxLogSize+1); err != nil {
+		t.Fatal(err)
+	}
+	s = m.newSupervisor(&server, "serve", true, &bytes.Buffer{})
+	s.logf("after rotation")
+	s.close()
+
+	rotated, err := os.Stat(path + ".1")
+	if err != nil {
+		t.Fatalf("the log was not rotated: %v", err)
+	}
+	if rotated.Size() != maxLogSize+1 {
+		t.Errorf("the rotated log has %d bytes, want %d", rotated.Size(), maxLogSize+1)
+	}
+	log, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(log), "after rotation") || strings.Contains(string(log), "small") {
+		t.Errorf("the new log does not start over:\n%s", log)
+	}
+}
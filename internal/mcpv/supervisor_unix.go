@@ -0,0 +1,22 @@
+//go:build !windows
+
+package manager
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// processAlive reports whether a process exists
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, os.ErrPermission)
+}
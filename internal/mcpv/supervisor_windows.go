@@ -0,0 +1,36 @@
+//go:build windows
+
+package manager
+
+import (
+	"errors"
+	"syscall"
+)
+
+const (
+	// processQueryLimitedInformation is the access right needed to read a
+	// process's exit code
+	processQueryLimitedInformation = 0x1000
+	// stillActive is the exit code of a process that has not exited
+	stillActive = 259
+)
+
+// processAlive reports whether a process exists and has not exited. A
+// process that exited stays openable while handles to it remain, so its exit
+// code is checked too.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return errors.Is(err, syscall.ERROR_ACCESS_DENIED)
+	}
+	defer syscall.CloseHandle(handle)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(handle, &code); err != nil {
+		return true
+	}
+	return code == stillActive
+}
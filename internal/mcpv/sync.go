@@ -0,0 +1,71 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyncResult describes what a sync changed
+type SyncResult struct {
+	Installed []string           `json:"installed,omitempty" yaml:"installed,omitempty"`
+	Entries   []AgentEntryStatus `json:"entries" yaml:"entries"`
+}
+
+// SyncAgents makes the server entries of each agent match mcpv.json exactly.
+// Servers in mcpv.json that are not installed yet are installed and locked
+// first, then missing entries are added, changed entries rewritten and entries
+// mcpv wrote for servers no longer in mcpv.json removed. Entries users added
+// themselves are left alone.
+func (m *Manager) SyncAgents(configPath string, agentTypes []AgentType, useLocal bool) (*SyncResult, error) {
+	installed, err := m.installMissingServers(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := m.AgentStatus(configPath, agentTypes, useLocal, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SyncResult{Installed: installed, Entries: entries}, nil
+}
+
+// installMissingServers installs the servers of mcpv.json that are not
+// installed at the version they resolve to, and records them in the lockfile
+func (m *Manager) installMissingServers(configPath string) ([]string, error) {
+	config, err := m.loadProject(configPath)
+	if err != nil {
+		return nil, err
+	}
+	servers, err := m.expectedAgentServers(config, configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var installed []string
+	for _, server := range servers {
+		if server.IsRemote() || server.Command != "" {
+			continue
+		}
+		if server.Repository == "" {
+			return nil, fmt.Errorf("repository not specified for server %s", server.Name)
+		}
+		if server.Version, err = m.installVersion(configPath, server); err != nil {
+			return nil, err
+		}
+
+		if _, err := m.InstallServer(server.Name, server.Version, server.Repository); err != nil {
+			if !strings.Contains(err.Error(), "already installed") {
+				return nil, fmt.Errorf("failed to install %s@%s: %w", server.Name, server.Version, err)
+			}
+		} else {
+			installed = append(installed, fmt.Sprintf("%s@%s", server.Name, server.Version))
+		}
+
+		if err := m.lockServer(configPath, server.Name, server.Version, server.Repository); err != nil {
+			return nil, err
+		}
+	}
+
+	return installed, nil
+}
@@ -0,0 +1,1086 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// This file implements the subset of TOML needed to edit agent configuration
+// files in place. Like the JSONC editor, statements are located by byte offset
+// so edits only rewrite the lines of the server entry they touch.
+
+// tomlKeyValue is a key/value statement with the offsets it occupies
+type tomlKeyValue struct {
+	keys       []string
+	value      interface{}
+	lineStart  int
+	valueStart int
+	valueEnd   int
+	end        int
+}
+
+// tomlTable is a table header and the statements under it. The implicit root
+// table has no keys. end is where the next table, including comments directly
+// above its header, begins.
+type tomlTable struct {
+	keys      []string
+	array     bool
+	start     int
+	headerEnd int
+	end       int
+	entries   []*tomlKeyValue
+}
+
+// contentEnd returns the offset just past the table's last statement
+func (t *tomlTable) contentEnd() int {
+	if len(t.entries) == 0 {
+		return t.headerEnd
+	}
+	return t.entries[len(t.entries)-1].end
+}
+
+// statement returns the statement of the table with the given keys, or nil
+func (t *tomlTable) statement(keys []string) *tomlKeyValue {
+	for _, kv := range t.entries {
+		if equalKeys(kv.keys, keys) {
+			return kv
+		}
+	}
+	return nil
+}
+
+// statementsEnd returns the offset just past the last statement whose keys
+// start with prefix, or the end of the table's content when there is none
+func (t *tomlTable) statementsEnd(prefix []string) int {
+	end := -1
+	for _, kv := range t.entries {
+		if hasKeyPrefix(kv.keys, prefix) {
+			end = kv.end
+		}
+	}
+	if end < 0 {
+		return t.contentEnd()
+	}
+	return end
+}
+
+// tomlScanner splits a TOML document into tables and statements
+type tomlScanner struct {
+	src []byte
+	pos int
+}
+
+// scanTOML parses a document into its tables, starting with the root table
+func scanTOML(src []byte) ([]*tomlTable, error) {
+	s := &tomlScanner{src: src}
+	current := &tomlTable{}
+	tables := []*tomlTable{current}
+	commentRun := -1
+
+	for s.pos < len(src) {
+		lineStart := s.pos
+		s.skipBlank()
+
+		switch {
+		case s.pos >= len(src):
+		case s.atLineEnd():
+			s.skipLine()
+			commentRun = -1
+		case src[s.pos] == '#':
+			if commentRun < 0 {
+				commentRun = lineStart
+			}
+			s.skipLine()
+		case src[s.pos] == '[':
+			table, err := s.parseHeader(lineStart)
+			if err != nil {
+				return nil, err
+			}
+			current.end = lineStart
+			if commentRun >= 0 {
+				current.end = commentRun
+			}
+			current = table
+			tables = append(tables, table)
+			commentRun = -1
+		default:
+			kv, err := s.parseKeyValue(lineStart)
+			if err != nil {
+				return nil, err
+			}
+			current.entries = append(current.entries, kv)
+			commentRun = -1
+		}
+	}
+
+	current.end = len(src)
+	return tables, nil
+}
+
+func (s *tomlScanner) errorf(format string, args ...interface{}) error {
+	line := bytes.Count(s.src[:min(s.pos, len(s.src))], []byte("\n")) + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skipBlank skips spaces and tabs
+func (s *tomlScanner) skipBlank() {
+	for s.pos < len(s.src) && (s.src[s.pos] == ' ' || s.src[s.pos] == '\t') {
+		s.pos++
+	}
+}
+
+func (s *tomlScanner) atLineEnd() bool {
+	return s.pos >= len(s.src) || s.src[s.pos] == '\n' || bytes.HasPrefix(s.src[s.pos:], []byte("\r\n"))
+}
+
+// skipLine moves past the end of the current line
+func (s *tomlScanner) skipLine() {
+	if i := bytes.IndexByte(s.src[s.pos:], '\n'); i >= 0 {
+		s.pos += i + 1
+	} else {
+		s.pos = len(s.src)
+	}
+}
+
+// finishLine expects only a comment before the end of the line and moves past it
+func (s *tomlScanner) finishLine() error {
+	s.skipBlank()
+	if s.pos < len(s.src) && s.src[s.pos] == '#' {
+		s.skipLine()
+		return nil
+	}
+	if !s.atLineEnd() {
+		return s.errorf("unexpected %q", s.src[s.pos])
+	}
+	s.skipLine()
+	return nil
+}
+
+// skipSpace skips whitespace, newlines and comments inside arrays
+func (s *tomlScanner) skipSpace() {
+	for s.pos < len(s.src) {
+		switch s.src[s.pos] {
+		case ' ', '\t', '\r', '\n':
+			s.pos++
+		case '#':
+			s.skipLine()
+		default:
+			return
+		}
+	}
+}
+
+func (s *tomlScanner) parseHeader(lineStart int) (*tomlTable, error) {
+	table := &tomlTable{start: lineStart}
+	s.pos++
+	if s.pos < len(s.src) && s.src[s.pos] == '[' {
+		table.array = true
+		s.pos++
+	}
+
+	keys, err := s.parseKeys()
+	if err != nil {
+		return nil, err
+	}
+	table.keys = keys
+
+	closing := "]"
+	if table.array {
+		closing = "]]"
+	}
+	if !bytes.HasPrefix(s.src[s.pos:], []byte(closing)) {
+		return nil, s.errorf("expected %q after table name", closing)
+	}
+	s.pos += len(closing)
+
+	if err := s.finishLine(); err != nil {
+		return nil, err
+	}
+	table.headerEnd = s.pos
+	return table, nil
+}
+
+func (s *tomlScanner) parseKeyValue(lineStart int) (*tomlKeyValue, error) {
+	kv := &tomlKeyValue{lineStart: lineStart}
+
+	keys, err := s.parseKeys()
+	if err != nil {
+		return nil, err
+	}
+	kv.keys = keys
+
+	if s.pos >= len(s.src) || s.src[s.pos] != '=' {
+		return nil, s.errorf("expected '=' after key")
+	}
+	s.pos++
+	s.skipBlank()
+
+	kv.valueStart = s.pos
+	if kv.value, err = s.parseValue(); err != nil {
+		return nil, err
+	}
+	kv.valueEnd = s.pos
+
+	if err := s.finishLine(); err != nil {
+		return nil, err
+	}
+	kv.end = s.pos
+	return kv, nil
+}
+
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+
+// parseKeys parses a dotted key
+func (s *tomlScanner) parseKeys() ([]string, error) {
+	var keys []string
+	for {
+		s.skipBlank()
+		if s.pos >= len(s.src) {
+			return nil, s.errorf("unexpected end of input in key")
+		}
+
+		switch s.src[s.pos] {
+		case '"':
+			key, err := s.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		case '\'':
+			key, err := s.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		default:
+			key := tomlBareKey.Find(s.src[s.pos:])
+			if key == nil {
+				return nil, s.errorf("invalid key")
+			}
+			s.pos += len(key)
+			keys = append(keys, string(key))
+		}
+
+		s.skipBlank()
+		if s.pos >= len(s.src) || s.src[s.pos] != '.' {
+			return keys, nil
+		}
+		s.pos++
+	}
+}
+
+func (s *tomlScanner) parseValue() (interface{}, error) {
+	if s.pos >= len(s.src) {
+		return nil, s.errorf("missing value")
+	}
+
+	switch {
+	case bytes.HasPrefix(s.src[s.pos:], []byte(`"""`)):
+		return s.parseMultilineString(`"""`)
+	case bytes.HasPrefix(s.src[s.pos:], []byte(`'''`)):
+		return s.parseMultilineString(`'''`)
+	case s.src[s.pos] == '"':
+		return s.parseBasicString()
+	case s.src[s.pos] == '\'':
+		return s.parseLiteralString()
+	case s.src[s.pos] == '[':
+		return s.parseArray()
+	case s.src[s.pos] == '{':
+		return s.parseInlineTable()
+	}
+
+	start := s.pos
+	for s.pos < len(s.src) && !strings.ContainsRune(" \t\r\n,]}#", rune(s.src[s.pos])) {
+		s.pos++
+	}
+	// Date-times may separate the date and time with a space
+	if s.pos+1 < len(s.src) && s.src[s.pos] == ' ' && s.pos-start == 10 && s.src[s.pos+1] >= '0' && s.src[s.pos+1] <= '9' {
+		s.pos++
+		for s.pos < len(s.src) && !strings.ContainsRune(" \t\r\n,]}#", rune(s.src[s.pos])) {
+			s.pos++
+		}
+	}
+
+	token := string(s.src[start:s.pos])
+	if token == "" {
+		return nil, s.errorf("missing value")
+	}
+	return parseTOMLScalar(token), nil
+}
+
+// parseTOMLScalar converts a bare value. Dates and times are kept as strings.
+func parseTOMLScalar(token string) interface{} {
+	switch token {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "inf", "+inf":
+		return math.Inf(1)
+	case "-inf":
+		return math.Inf(-1)
+	case "nan", "+nan", "-nan":
+		return math.NaN()
+	}
+
+	if i, err := strconv.ParseInt(token, 0, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(strings.ReplaceAll(token, "_", ""), 64); err == nil {
+		return f
+	}
+	return token
+}
+
+func (s *tomlScanner) parseBasicString() (string, error) {
+	s.pos++
+	var buf strings.Builder
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch c {
+		case '"':
+			s.pos++
+			return buf.String(), nil
+		case '\n':
+			return "", s.errorf("unterminated string")
+		case '\\':
+			if err := s.parseEscape(&buf); err != nil {
+				return "", err
+			}
+		default:
+			buf.WriteByte(c)
+			s.pos++
+		}
+	}
+	return "", s.errorf("unterminated string")
+}
+
+func (s *tomlScanner) parseLiteralString() (string, error) {
+	s.pos++
+	end := bytes.IndexAny(s.src[s.pos:], "'\n")
+	if end < 0 || s.src[s.pos+end] != '\'' {
+		return "", s.errorf("unterminated string")
+	}
+	value := string(s.src[s.pos : s.pos+end])
+	s.pos += end + 1
+	return value, nil
+}
+
+func (s *tomlScanner) parseMultilineString(delimiter string) (string, error) {
+	s.pos += len(delimiter)
+	// A newline immediately after the opening delimiter is trimmed
+	if bytes.HasPrefix(s.src[s.pos:], []byte("\r\n")) {
+		s.pos += 2
+	} else if s.pos < len(s.src) && s.src[s.pos] == '\n' {
+		s.pos++
+	}
+
+	var buf strings.Builder
+	for s.pos < len(s.src) {
+		if bytes.HasPrefix(s.src[s.pos:], []byte(delimiter)) {
+			// Up to two quotes may directly precede the closing delimiter
+			for bytes.HasPrefix(s.src[s.pos+1:], []byte(delimiter)) {
+				buf.WriteByte(s.src[s.pos])
+				s.pos++
+			}
+			s.pos += len(delimiter)
+			return buf.String(), nil
+		}
+
+		if delimiter == `"""` && s.src[s.pos] == '\\' {
+			// A backslash at the end of a line trims the following whitespace
+			rest := bytes.TrimLeft(s.src[s.pos+1:], " \t")
+			if len(rest) > 0 && (rest[0] == '\n' || rest[0] == '\r') {
+				s.pos = len(s.src) - len(rest)
+				s.skipSpace()
+				continue
+			}
+			if err := s.parseEscape(&buf); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		buf.WriteByte(s.src[s.pos])
+		s.pos++
+	}
+	return "", s.errorf("unterminated string")
+}
+
+// parseEscape decodes the escape sequence at the current position
+func (s *tomlScanner) parseEscape(buf *strings.Builder) error {
+	if s.pos+1 >= len(s.src) {
+		return s.errorf("unterminated escape sequence")
+	}
+
+	c := s.src[s.pos+1]
+	s.pos += 2
+	switch c {
+	case 'b':
+		buf.WriteByte('\b')
+	case 't':
+		buf.WriteByte('\t')
+	case 'n':
+		buf.WriteByte('\n')
+	case 'f':
+		buf.WriteByte('\f')
+	case 'r':
+		buf.WriteByte('\r')
+	case 'e':
+		buf.WriteByte(0x1b)
+	case '"':
+		buf.WriteByte('"')
+	case '\\':
+		buf.WriteByte('\\')
+	case 'u', 'U':
+		length := 4
+		if c == 'U' {
+			length = 8
+		}
+		if s.pos+length > len(s.src) {
+			return s.errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(string(s.src[s.pos:s.pos+length]), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return s.errorf("invalid unicode escape")
+		}
+		buf.WriteRune(rune(code))
+		s.pos += length
+	default:
+		return s.errorf("invalid escape sequence \\%c", c)
+	}
+	return nil
+}
+
+func (s *tomlScanner) parseArray() (interface{}, error) {
+	s.pos++
+	array := []interface{}{}
+	for {
+		s.skipSpace()
+		if s.pos >= len(s.src) {
+			return nil, s.errorf("unterminated array")
+		}
+		if s.src[s.pos] == ']' {
+			s.pos++
+			return array, nil
+		}
+
+		value, err := s.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+
+		s.skipSpace()
+		if s.pos < len(s.src) && s.src[s.pos] == ',' {
+			s.pos++
+			continue
+		}
+		if s.pos >= len(s.src) || s.src[s.pos] != ']' {
+			return nil, s.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+func (s *tomlScanner) parseInlineTable() (interface{}, error) {
+	s.pos++
+	table := map[string]interface{}{}
+	for {
+		s.skipBlank()
+		if s.pos >= len(s.src) {
+			return nil, s.errorf("unterminated inline table")
+		}
+		if s.src[s.pos] == '}' {
+			s.pos++
+			return table, nil
+		}
+
+		keys, err := s.parseKeys()
+		if err != nil {
+			return nil, err
+		}
+		if s.pos >= len(s.src) || s.src[s.pos] != '=' {
+			return nil, s.errorf("expected '=' after key")
+		}
+		s.pos++
+		s.skipBlank()
+
+		value, err := s.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if err := setTOMLPath(table, keys, value); err != nil {
+			return nil, s.errorf("%v", err)
+		}
+
+		s.skipBlank()
+		if s.pos < len(s.src) && s.src[s.pos] == ',' {
+			s.pos++
+			continue
+		}
+		if s.pos >= len(s.src) || s.src[s.pos] != '}' {
+			return nil, s.errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
+
+// setTOMLPath sets a dotted key in a table, creating intermediate tables
+func setTOMLPath(table map[string]interface{}, keys []string, value interface{}) error {
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := table[key].(map[string]interface{})
+		if !ok {
+			if _, exists := table[key]; exists {
+				return fmt.Errorf("key %s is not a table", key)
+			}
+			next = map[string]interface{}{}
+			table[key] = next
+		}
+		table = next
+	}
+	last := keys[len(keys)-1]
+	if _, exists := table[last]; exists {
+		return fmt.Errorf("key %s is defined more than once", tomlKeyPath(keys))
+	}
+	table[last] = value
+	return nil
+}
+
+// decodeTOML converts scanned tables into nested maps
+func decodeTOML(tables []*tomlTable) (map[string]interface{}, error) {
+	doc := map[string]interface{}{}
+	defined := map[string]bool{}
+	for _, table := range tables {
+		target := doc
+		if len(table.keys) > 0 {
+			name := tomlKeyPath(table.keys)
+			parent := doc
+			for i, key := range table.keys[:len(table.keys)-1] {
+				switch next := parent[key].(type) {
+				case map[string]interface{}:
+					parent = next
+				case []interface{}:
+					last, ok := lastTOMLTable(next)
+					if !ok {
+						return nil, fmt.Errorf("table [%s]: %s is not a table", name, tomlKeyPath(table.keys[:i+1]))
+					}
+					parent = last
+				case nil:
+					created := map[string]interface{}{}
+					parent[key] = created
+					parent = created
+				default:
+					return nil, fmt.Errorf("table [%s]: %s is not a table", name, tomlKeyPath(table.keys[:i+1]))
+				}
+			}
+
+			last := table.keys[len(table.keys)-1]
+			target = map[string]interface{}{}
+			switch existing := parent[last].(type) {
+			case nil:
+				if table.array {
+					parent[last] = []interface{}{target}
+				} else {
+					parent[last] = target
+				}
+			case []interface{}:
+				if !table.array {
+					return nil, fmt.Errorf("table [%s] is already defined as an array of tables", name)
+				}
+				parent[last] = append(existing, target)
+			case map[string]interface{}:
+				if table.array || defined[name] {
+					return nil, fmt.Errorf("table [%s] is defined more than once", name)
+				}
+				target = existing
+			default:
+				return nil, fmt.Errorf("table [%s]: %s is not a table", name, name)
+			}
+			if !table.array {
+				defined[name] = true
+			}
+		}
+
+		for _, kv := range table.entries {
+			if err := setTOMLPath(target, kv.keys, kv.value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return doc, nil
+}
+
+// lastTOMLTable returns the last table of an array of tables
+func lastTOMLTable(array []interface{}) (map[string]interface{}, bool) {
+	if len(array) == 0 {
+		return nil, false
+	}
+	table, ok := array[len(array)-1].(map[string]interface{})
+	return table, ok
+}
+
+// renderTOMLValue renders a value as an inline TOML value
+func renderTOMLValue(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var normalized interface{}
+	if err := dec.Decode(&normalized); err != nil {
+		return "", err
+	}
+	return renderTOMLNormalized(normalized)
+}
+
+func renderTOMLNormalized(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		data, err := marshalJSONValue(v)
+		return string(data), err
+	case bool:
+		return strconv.FormatBool(v), nil
+	case json.Number:
+		return v.String(), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			rendered, err := renderTOMLNormalized(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, rendered)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return "{}", nil
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(v))
+		for _, key := range keys {
+			rendered, err := renderTOMLNormalized(v[key])
+			if err != nil {
+				return "", err
+			}
+			items = append(items, tomlKey(key)+" = "+rendered)
+		}
+		return "{ " + strings.Join(items, ", ") + " }", nil
+	default:
+		return "", fmt.Errorf("cannot represent %v in TOML", value)
+	}
+}
+
+// tomlKey renders a key, quoting it when it is not a bare key
+func tomlKey(key string) string {
+	if key != "" && len(tomlBareKey.FindString(key)) == len(key) {
+		return key
+	}
+	data, _ := marshalJSONValue(key)
+	return string(data)
+}
+
+// tomlKeyPath renders a dotted key
+func tomlKeyPath(keys []string) string {
+	rendered := make([]string, len(keys))
+	for i, key := range keys {
+		rendered[i] = tomlKey(key)
+	}
+	return strings.Join(rendered, ".")
+}
+
+// tomlAgentDocument is an agent configuration in TOML, where each server is a
+// table under the root key, such as [mcp_servers.name]
+type tomlAgentDocument struct {
+	src    []byte
+	tables []*tomlTable
+	root   []string
+}
+
+func newTOMLAgentDocument(data []byte, root []string) (*tomlAgentDocument, error) {
+	tables, err := scanTOML(data)
+	if err != nil {
+		return nil, err
+	}
+	d := &tomlAgentDocument{src: data, tables: tables, root: root}
+	if _, err := d.entries(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// replace substitutes src[start:end] with text and rescans the document
+func (d *tomlAgentDocument) replace(start, end int, text string) error {
+	var buf bytes.Buffer
+	buf.Write(d.src[:start])
+	buf.WriteString(text)
+	buf.Write(d.src[end:])
+
+	tables, err := scanTOML(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("edit produced invalid TOML: %w", err)
+	}
+	d.src = buf.Bytes()
+	d.tables = tables
+	return nil
+}
+
+// table returns the table with the given name, or nil
+func (d *tomlAgentDocument) table(keys []string) *tomlTable {
+	for _, table := range d.tables[1:] {
+		if !table.array && equalKeys(table.keys, keys) {
+			return table
+		}
+	}
+	return nil
+}
+
+// tableAt returns the table with the given name, the root table for no keys
+func (d *tomlAgentDocument) tableAt(keys []string) *tomlTable {
+	if len(keys) == 0 {
+		return d.tables[0]
+	}
+	return d.table(keys)
+}
+
+// equalKeys reports whether two key paths are the same
+func equalKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// hasKeyPrefix reports whether keys starts with prefix
+func hasKeyPrefix(keys, prefix []string) bool {
+	return len(keys) >= len(prefix) && equalKeys(keys[:len(prefix)], prefix)
+}
+
+// entryKeys returns the table name of a server's entry
+func (d *tomlAgentDocument) entryKeys(name string) []string {
+	keys := make([]string, 0, len(d.root)+1)
+	keys = append(keys, d.root...)
+	return append(keys, name)
+}
+
+func (d *tomlAgentDocument) entries() (map[string]interface{}, error) {
+	doc, err := decodeTOML(d.tables)
+	if err != nil {
+		return nil, err
+	}
+
+	// Servers can only be added to tables, so anything else under the root
+	// key, such as an array of tables, is refused rather than overwritten
+	entries := doc
+	for i, key := range d.root {
+		switch node := entries[key].(type) {
+		case nil:
+			return map[string]interface{}{}, nil
+		case map[string]interface{}:
+			entries = node
+		default:
+			return nil, fmt.Errorf("%s is not a table", tomlKeyPath(d.root[:i+1]))
+		}
+	}
+	return entries, nil
+}
+
+// tomlEntry locates the statements defining a server's entry. An entry is
+// either the statements under prefix in the table named tableKeys, such as
+// its own [mcp_servers.name] table or dotted keys like name.command in
+// [mcp_servers], or it lives at inlinePath in the value of an inline table
+// statement.
+type tomlEntry struct {
+	tableKeys  []string
+	prefix     []string
+	inline     *tomlKeyValue
+	inlinePath []string
+}
+
+// locateEntry finds how the entry at keys is defined, returning nil when no
+// statement or table defines it
+func (d *tomlAgentDocument) locateEntry(keys []string) (*tomlEntry, error) {
+	if d.table(keys) != nil {
+		return &tomlEntry{tableKeys: keys}, nil
+	}
+
+	for _, table := range d.tables {
+		if table.array || !hasKeyPrefix(keys, table.keys) {
+			continue
+		}
+		for _, kv := range table.entries {
+			full := append(table.keys[:len(table.keys):len(table.keys)], kv.keys...)
+			switch {
+			case hasKeyPrefix(keys, full):
+				// The entry, or a table holding it, is an inline table
+				if _, ok := kv.value.(map[string]interface{}); !ok {
+					return nil, fmt.Errorf("%s is not a table", tomlKeyPath(full))
+				}
+				return &tomlEntry{inline: kv, inlinePath: keys[len(full):]}, nil
+			case hasKeyPrefix(full, keys):
+				return &tomlEntry{tableKeys: table.keys, prefix: keys[len(table.keys):]}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (d *tomlAgentDocument) setEntry(name string, fields orderedObject) error {
+	keys := d.entryKeys(name)
+
+	entry, err := d.locateEntry(keys)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		if err := d.createEntry(name); err != nil {
+			return err
+		}
+		entry = &tomlEntry{tableKeys: keys}
+	}
+	if entry.inline != nil {
+		return d.setInlineEntry(entry, fields)
+	}
+
+	for _, field := range fields {
+		fieldKeys := append(entry.prefix[:len(entry.prefix):len(entry.prefix)], field.Key)
+		subtableKeys := append(keys[:len(keys):len(keys)], field.Key)
+
+		if field.Value == nil {
+			if err := d.removeField(entry.tableKeys, fieldKeys, subtableKeys); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rendered, err := renderTOMLValue(field.Value)
+		if err != nil {
+			return err
+		}
+
+		if kv := d.tableAt(entry.tableKeys).statement(fieldKeys); kv != nil && d.table(subtableKeys) == nil {
+			if jsonEqual(kv.value, field.Value) {
+				continue
+			}
+			if err := d.replace(kv.valueStart, kv.valueEnd, rendered); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Drop other definitions of the field, such as env.NAME keys or an
+		// [mcp_servers.name.env] table, before writing it as one statement
+		if err := d.removeField(entry.tableKeys, fieldKeys, subtableKeys); err != nil {
+			return err
+		}
+		at := d.tableAt(entry.tableKeys).statementsEnd(entry.prefix)
+		if err := d.replace(at, at, d.lineBreakBefore(at)+tomlKeyPath(fieldKeys)+" = "+rendered+"\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeField deletes the statements defining fieldKeys in a table and the
+// subtable of the same field
+func (d *tomlAgentDocument) removeField(tableKeys, fieldKeys, subtableKeys []string) error {
+	for {
+		var found *tomlKeyValue
+		for _, kv := range d.tableAt(tableKeys).entries {
+			if hasKeyPrefix(kv.keys, fieldKeys) {
+				found = kv
+				break
+			}
+		}
+		if found == nil {
+			break
+		}
+		if err := d.replace(found.lineStart, found.end, ""); err != nil {
+			return err
+		}
+	}
+
+	if subtable := d.table(subtableKeys); subtable != nil {
+		return d.removeTable(subtable)
+	}
+	return nil
+}
+
+// setInlineEntry updates an entry defined in an inline table by rewriting
+// the value of the statement holding it
+func (d *tomlAgentDocument) setInlineEntry(entry *tomlEntry, fields orderedObject) error {
+	value := entry.inline.value.(map[string]interface{})
+	node := value
+	for _, key := range entry.inlinePath {
+		next, ok := node[key].(map[string]interface{})
+		if !ok {
+			if _, exists := node[key]; exists {
+				return fmt.Errorf("key %s is not a table", key)
+			}
+			next = map[string]interface{}{}
+			node[key] = next
+		}
+		node = next
+	}
+
+	changed := len(entry.inlinePath) > 0 && len(node) == 0
+	for _, field := range fields {
+		existing, exists := node[field.Key]
+		switch {
+		case field.Value == nil && exists:
+			delete(node, field.Key)
+			changed = true
+		case field.Value != nil && (!exists || !jsonEqual(existing, field.Value)):
+			node[field.Key] = field.Value
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	rendered, err := renderTOMLValue(value)
+	if err != nil {
+		return err
+	}
+	return d.replace(entry.inline.valueStart, entry.inline.valueEnd, rendered)
+}
+
+// createEntry writes an empty table for a server after the other server
+// tables, for setEntry to fill
+func (d *tomlAgentDocument) createEntry(name string) error {
+	// Insert after the last table under the root key, or at the end
+	at := -1
+	for _, table := range d.tables[1:] {
+		if hasKeyPrefix(table.keys, d.root) {
+			at = table.contentEnd()
+		}
+	}
+	if at < 0 {
+		at = len(d.src)
+	}
+
+	prefix := d.lineBreakBefore(at)
+	if at > 0 {
+		prefix += "\n"
+	}
+	return d.replace(at, at, prefix+"["+tomlKeyPath(d.entryKeys(name))+"]\n")
+}
+
+// lineBreakBefore returns a newline when inserting at offset would otherwise
+// continue an unterminated last line
+func (d *tomlAgentDocument) lineBreakBefore(offset int) string {
+	if offset > 0 && d.src[offset-1] != '\n' {
+		return "\n"
+	}
+	return ""
+}
+
+// removeTable deletes a table with its statements and trailing blank lines
+func (d *tomlAgentDocument) removeTable(table *tomlTable) error {
+	if err := d.replace(table.start, table.end, ""); err != nil {
+		return err
+	}
+	// Don't leave blank lines at the end of the document
+	trimmed := bytes.TrimRight(d.src, "\n")
+	if len(trimmed) < len(d.src) && len(d.src)-len(trimmed) > 1 {
+		return d.replace(len(trimmed), len(d.src), "\n")
+	}
+	return nil
+}
+
+func (d *tomlAgentDocument) removeEntry(name string) (bool, error) {
+	keys := d.entryKeys(name)
+	removed := false
+
+	for {
+		var found *tomlTable
+		for _, table := range d.tables[1:] {
+			if hasKeyPrefix(table.keys, keys) {
+				found = table
+				break
+			}
+		}
+		if found == nil {
+			break
+		}
+		if err := d.removeTable(found); err != nil {
+			return false, err
+		}
+		removed = true
+	}
+
+	// Statements defining the entry with dotted keys or as an inline table
+	for {
+		var found *tomlKeyValue
+		for _, table := range d.tables {
+			if table.array || !hasKeyPrefix(keys, table.keys) {
+				continue
+			}
+			for _, kv := range table.entries {
+				if hasKeyPrefix(append(table.keys[:len(table.keys):len(table.keys)], kv.keys...), keys) {
+					found = kv
+					break
+				}
+			}
+			if found != nil {
+				break
+			}
+		}
+		if found == nil {
+			break
+		}
+		if err := d.replace(found.lineStart, found.end, ""); err != nil {
+			return false, err
+		}
+		removed = true
+	}
+
+	// An entry inside the inline table of a parent
+	if entry, err := d.locateEntry(keys); err == nil && entry != nil && entry.inline != nil {
+		value := entry.inline.value.(map[string]interface{})
+		node := value
+		for _, key := range entry.inlinePath[:len(entry.inlinePath)-1] {
+			if node, _ = node[key].(map[string]interface{}); node == nil {
+				return removed, nil
+			}
+		}
+		last := entry.inlinePath[len(entry.inlinePath)-1]
+		if _, ok := node[last]; !ok {
+			return removed, nil
+		}
+		delete(node, last)
+
+		rendered, err := renderTOMLValue(value)
+		if err != nil {
+			return false, err
+		}
+		if err := d.replace(entry.inline.valueStart, entry.inline.valueEnd, rendered); err != nil {
+			return false, err
+		}
+		removed = true
+	}
+
+	return removed, nil
+}
+
+func (d *tomlAgentDocument) bytes() ([]byte, error) {
+	return d.src, nil
+}
@@ -0,0 +1,34 @@
+package manager
+
+import "testing"
+
+func TestNewTOMLAgentDocumentRejectsInvalidTables(t *testing.T) {
+	for _, src := range []string{
+		"x = []\n[x.y]\n",
+		"x = [1]\n[x.y]\n",
+		"x = 1\n[x.y]\n",
+		"[[x]]\n[x]\n",
+		"[x]\n[[x]]\n",
+		"[x]\n[x]\n",
+		"a = 1\na = 2\n",
+		"[mcp_servers]\na.command = \"x\"\n[mcp_servers.a]\ncommand = \"y\"\n",
+		"[[mcp_servers]]\nname = \"a\"\n",
+		"mcp_servers = 1\n",
+	} {
+		if _, err := newTOMLAgentDocument([]byte(src), []string{"mcp_servers"}); err == nil {
+			t.Errorf("expected an error for %q", src)
+		}
+	}
+}
+
+func TestNewTOMLAgentDocumentAcceptsTables(t *testing.T) {
+	for _, src := range []string{
+		"[x.y]\n[x]\n",
+		"[[x]]\n[x.y]\n",
+		"[x]\na.b = 1\n[x.a.c]\nd = 2\n",
+	} {
+		if _, err := newTOMLAgentDocument([]byte(src), []string{"mcp_servers"}); err != nil {
+			t.Errorf("unexpected error for %q: %v", src, err)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// agentConfigTransaction stages edits to several agent configuration files so
+// they are committed together: either every file is updated or none are
+type agentConfigTransaction struct {
+	m     *Manager
+	files []*agentConfigFile
+}
+
+// newAgentConfigTransaction starts a transaction over agent configuration files
+func (m *Manager) newAgentConfigTransaction() *agentConfigTransaction {
+	return &agentConfigTransaction{m: m}
+}
+
+// file returns the staged configuration file at path, loading it on first use
+func (tx *agentConfigTransaction) file(path string, definition *AgentDefinition) (*agentConfigFile, error) {
+	for _, file := range tx.files {
+		if file.path == path {
+			return file, nil
+		}
+	}
+
+	file, err := tx.m.loadAgentConfigFile(path, definition)
+	if err != nil {
+		return nil, err
+	}
+	tx.files = append(tx.files, file)
+	return file, nil
+}
+
+// agentFile returns the staged configuration file of an agent
+func (tx *agentConfigTransaction) agentFile(agentType AgentType, useLocal bool) (*agentConfigFile, error) {
+	definition, err := tx.m.agentDefinition(agentType)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := definition.configPath(useLocal)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.file(path, definition)
+}
+
+// addServer stages adding an MCP server to an agent's configuration
+func (tx *agentConfigTransaction) addServer(agentType AgentType, server *MCPServer, useLocal bool) error {
+	file, err := tx.agentFile(agentType, useLocal)
+	if err != nil {
+		return err
+	}
+
+	return file.setServer(server)
+}
+
+// removeServer stages removing an MCP server from an agent's configuration,
+// reporting whether the agent had it configured
+func (tx *agentConfigTransaction) removeServer(agentType AgentType, serverName string, useLocal bool) (bool, error) {
+	file, err := tx.agentFile(agentType, useLocal)
+	if err != nil {
+		return false, err
+	}
+
+	return file.removeServer(serverName)
+}
+
+// commit backs up every staged file that changed and writes them atomically.
+// If a write fails, the files already written are restored to their original
+// contents so the agents are left as they were.
+func (tx *agentConfigTransaction) commit(description string) error {
+	var changed []*agentConfigFile
+	var contents [][]byte
+	for _, file := range tx.files {
+		if !file.modified {
+			continue
+		}
+
+		data, err := file.render()
+		if err != nil {
+			return err
+		}
+		if file.exists && bytes.Equal(data, file.original) {
+			continue
+		}
+		changed = append(changed, file)
+		contents = append(contents, data)
+	}
+
+	// Shims are written first so entries never refer to a missing one
+	for _, file := range tx.files {
+		if err := tx.m.writeShims(file.launcher); err != nil {
+			return err
+		}
+	}
+
+	if tx.m.plan != nil {
+		for i, file := range changed {
+			if err := tx.m.writeFile(file.path, contents[i], file.mode); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(changed) == 0 {
+		tx.recordManagedEntries()
+		return nil
+	}
+
+	backup, err := tx.m.createBackup(description, changed)
+	if err != nil {
+		return fmt.Errorf("failed to back up agent configurations: %w", err)
+	}
+
+	for i, file := range changed {
+		if err := writeFileAtomic(file.path, contents[i], file.mode); err != nil {
+			if rollbackErr := rollbackAgentConfigs(changed[:i]); rollbackErr != nil {
+				return fmt.Errorf("failed to write agent config %s: %w (rollback failed: %v; restore with 'mcpv agents restore')", file.path, err, rollbackErr)
+			}
+			return fmt.Errorf("failed to write agent config %s: %w (all agent configurations were restored from backup %s)", file.path, err, backup.ID)
+		}
+	}
+
+	if err := tx.m.pruneBackups(); err != nil {
+		fmt.Fprintf(tx.m.out, "Warning: Failed to prune old agent configuration backups: %v\n", err)
+	}
+
+	tx.recordManagedEntries()
+	return nil
+}
+
+// recordManagedEntries notes which entries the transaction wrote and removed.
+// A failure only loses track of ownership, so it is reported as a warning.
+func (tx *agentConfigTransaction) recordManagedEntries() {
+	if err := tx.m.recordManagedEntries(tx.files); err != nil {
+		fmt.Fprintf(tx.m.out, "Warning: Failed to record managed agent entries: %v\n", err)
+	}
+}
+
+// rollbackAgentConfigs restores files to the contents they had when loaded
+func rollbackAgentConfigs(files []*agentConfigFile) error {
+	var errors []string
+	for _, file := range files {
+		var err error
+		if file.exists {
+			err = writeFileAtomic(file.path, file.original, file.mode)
+		} else if removeErr := os.Remove(file.path); removeErr != nil && !os.IsNotExist(removeErr) {
+			err = removeErr
+		}
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %v", file.path, err))
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%v", errors)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces a file by writing a temporary file in the same
+// directory and renaming it over the target, so readers never observe a
+// partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stageServer stages adding a server to a cursor configuration at path
+func stageServer(t *testing.T, tx *agentConfigTransaction, path, name string) {
+	t.Helper()
+
+	definition, err := tx.m.agentDefinition("cursor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := tx.file(path, definition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := file.setServer(&MCPServer{Name: name, Command: "node", Args: []string{"index.js"}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCommitRestoresWrittenFilesWhenAWriteFails(t *testing.T) {
+	m, project := newTestManager(t)
+
+	original := []byte("{\n  // kept\n  \"mcpServers\": {}\n}\n")
+	existing := filepath.Join(project, "existing", "mcp.json")
+	if err := os.MkdirAll(filepath.Dir(existing), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(existing, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+	created := filepath.Join(project, "created", "mcp.json")
+	failing := filepath.Join(project, "failing", "mcp.json")
+
+	tx := m.newAgentConfigTransaction()
+	stageServer(t, tx, existing, "github")
+	stageServer(t, tx, created, "github")
+	stageServer(t, tx, failing, "github")
+
+	// A file in place of its directory makes the last write fail
+	if err := os.WriteFile(filepath.Dir(failing), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := tx.commit("add github")
+	if err == nil || !strings.Contains(err.Error(), "were restored") {
+		t.Fatalf("expected the commit to fail and restore the files, got %v", err)
+	}
+
+	data, err := os.ReadFile(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(original) {
+		t.Errorf("%s was not restored:\n%s", existing, data)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("%s was created by the failed commit and not removed: %v", created, err)
+	}
+}
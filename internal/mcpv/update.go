@@ -0,0 +1,375 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-git/go-git/v5"
+)
+
+// UpdateResult describes the outcome of updating a server
+type UpdateResult struct {
+	Name            string
+	Repository      string
+	PreviousVersion string
+	Version         string
+	Updated         bool
+	RemovedPrevious bool
+}
+
+// serverSource describes where a server's repository and current version were found
+type serverSource struct {
+	Repository string
+	Version    string
+	Constraint string
+}
+
+// findServerSource looks up the repository and current version of a server in
+// mcpv.json, then the lockfile, then the install manifests of installed versions
+func (m *Manager) findServerSource(name, configPath string) (*serverSource, error) {
+	config, err := m.loadProject(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &serverSource{}
+	for _, server := range config.Servers {
+		if server.Name == name {
+			source.Repository = server.Repository
+			source.Version = server.Version
+			source.Constraint = server.Version
+			break
+		}
+	}
+
+	lock, err := m.LoadLockFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if locked := lock.Find(name); locked != nil {
+		if source.Repository == "" {
+			source.Repository = locked.Repository
+		}
+		// The lockfile records the resolved version of a constraint
+		source.Version = locked.Version
+	}
+
+	if source.Repository == "" || source.Version == "" {
+		installed, err := m.ListInstalledServers()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list installed servers: %w", err)
+		}
+
+		var versions []string
+		for _, server := range installed {
+			if server.Name == name {
+				versions = append(versions, server.Version)
+			}
+		}
+
+		if source.Version == "" {
+			source.Version = currentInstalledVersion(versions, "")
+		}
+		if source.Repository == "" && source.Version != "" {
+			if manifest, err := m.readInstallManifest(name, source.Version); err == nil {
+				source.Repository = manifest.Repository
+			}
+			if source.Repository == "" {
+				source.Repository = m.installedRepository(name, source.Version)
+			}
+		}
+	}
+
+	if source.Repository == "" {
+		return nil, fmt.Errorf("no repository found for server %s in %s, %s or installed servers", name, configPath, LockFilePath(configPath))
+	}
+
+	return source, nil
+}
+
+// isVersionRange reports whether a mcpv.json version is a range constraint
+// (such as ^1.2.0) rather than an exact version or "latest"
+func isVersionRange(version string) bool {
+	if version == "" || version == "latest" {
+		return false
+	}
+	if _, err := semver.StrictNewVersion(strings.TrimPrefix(version, "v")); err == nil {
+		return false
+	}
+	_, err := semver.NewConstraint(version)
+	return err == nil
+}
+
+// UpdateServerByName updates a server to the newest version allowed by mcpv.json.
+// Exact versions are moved to the latest release, while range constraints are
+// resolved to the highest matching release. mcpv.json, the lockfile and agent
+// configurations are switched to the new version. The previously installed
+// version is deleted when removePrevious is set.
+func (m *Manager) UpdateServerByName(name, configPath string, removePrevious bool) (*UpdateResult, error) {
+	source, err := m.findServerSource(name, configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UpdateResult{
+		Name:            name,
+		Repository:      source.Repository,
+		PreviousVersion: source.Version,
+	}
+
+	if isVersionRange(source.Constraint) {
+		result.Version, err = m.ResolveVersion(source.Repository, source.Constraint)
+	} else {
+		result.Version, err = m.getLatestVersion(source.Repository)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve version: %w", err)
+	}
+
+	server, err := m.InstallServer(name, result.Version, source.Repository)
+	if err != nil {
+		if !strings.Contains(err.Error(), "already installed") {
+			return nil, err
+		}
+		if result.Version == "latest" {
+			// Without release tags, "latest" tracks the default branch
+			var pulled bool
+			server, pulled, err = m.pullServer(name, source.Repository)
+			if err != nil {
+				return nil, err
+			}
+			if !pulled && result.PreviousVersion == "latest" {
+				fmt.Fprintf(m.out, "%s is already up to date (%s)\n", name, result.Version)
+				return result, nil
+			}
+			result.Updated = true
+		} else if result.Version == result.PreviousVersion {
+			fmt.Fprintf(m.out, "%s is already up to date (%s)\n", name, result.Version)
+			return result, nil
+		} else {
+			server, err = m.installedServer(name, result.Version, source.Repository)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	result.Updated = result.Updated || result.Version != result.PreviousVersion
+
+	config, err := m.loadProject(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	for i, existing := range config.Servers {
+		if existing.Name != name {
+			continue
+		}
+
+		// Only the version changes; range constraints are kept as written
+		updated := existing
+		if !isVersionRange(existing.Version) {
+			updated.Version = result.Version
+		}
+		// Commands pinned to the previous install directory follow the update
+		installDir := filepath.Join(m.dataDir, name) + string(filepath.Separator)
+		if strings.HasPrefix(existing.Command, installDir) || containsPrefix(existing.Args, installDir) {
+			updated.Command, updated.Args = server.Command, server.Args
+		}
+		if updated.Version != existing.Version || updated.Command != existing.Command {
+			config.Servers[i] = updated
+			if err := m.SaveProjectConfig(config, configPath); err != nil {
+				return nil, err
+			}
+		}
+
+		// Agents get the entry of mcpv.json, completed by the installation
+		agentServer := updated
+		agentServer.Version = result.Version
+		agentServer.InstallPath = server.InstallPath
+		agentServer.Installed = true
+		if agentServer.Command == "" {
+			agentServer.Command, agentServer.Args = server.Command, server.Args
+		}
+		if len(agentServer.Env) == 0 {
+			agentServer.Env = server.Env
+		}
+		server = &agentServer
+		break
+	}
+
+	if err := m.lockServer(configPath, name, result.Version, source.Repository); err != nil {
+		return nil, err
+	}
+
+	if config.DefaultAgent != "" {
+		err = m.AddServerToAgentWithLocal(AgentType(config.DefaultAgent), server, true)
+	} else {
+		err = m.PatchAgentConfigs(server)
+	}
+	if err != nil {
+		fmt.Fprintf(m.out, "Warning: Failed to configure server %s for agents: %v\n", name, err)
+	}
+
+	if removePrevious && result.PreviousVersion != "" && result.PreviousVersion != result.Version {
+		if err := m.removeInstallDir(name, result.PreviousVersion); err != nil {
+			fmt.Fprintf(m.out, "Warning: Failed to remove previous version %s@%s: %v\n", name, result.PreviousVersion, err)
+		} else {
+			result.RemovedPrevious = true
+		}
+	}
+
+	return result, nil
+}
+
+// pullServer fetches the default branch into the "latest" installation of a
+// server and rebuilds it when new commits arrived, reporting whether it did
+func (m *Manager) pullServer(name, repository string) (*MCPServer, bool, error) {
+	serverDir := filepath.Join(m.dataDir, name, "latest")
+
+	if m.plan != nil {
+		m.plan.addStep("pull the default branch of %s into %s", repository, serverDir)
+		m.plan.addStep("install dependencies and build %s@latest", name)
+		server, err := m.installedServer(name, "latest", repository)
+		return server, true, err
+	}
+
+	repo, err := git.PlainOpen(serverDir)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open %s: %w", serverDir, err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open %s: %w", serverDir, err)
+	}
+
+	// Keep the working build to put back if the new one fails its health check
+	restore, discard, err := m.backupInstall(serverDir)
+	if err != nil {
+		return nil, false, err
+	}
+	defer discard()
+
+	err = worktree.Pull(&git.PullOptions{RemoteName: "origin"})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		server, err := m.installedServer(name, "latest", repository)
+		return server, false, err
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to pull %s: %w", repository, err)
+	}
+
+	fmt.Fprintf(m.out, "Pulled new commits of %s, rebuilding...\n", name)
+	server, err := m.buildInstall(name, "latest", repository, serverDir, restore)
+	if err != nil {
+		return nil, false, err
+	}
+	return server, true, nil
+}
+
+// backupInstall copies an install directory next to it so a rebuild that
+// fails its health check can be undone. It returns the function putting the
+// copy back and the one discarding it
+func (m *Manager) backupInstall(serverDir string) (restore func() error, discard func(), err error) {
+	if m.installCheck == nil {
+		return func() error { return nil }, func() {}, nil
+	}
+
+	backup, err := os.MkdirTemp(filepath.Dir(serverDir), "."+filepath.Base(serverDir)+"-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to back up %s: %w", serverDir, err)
+	}
+	discard = func() { os.RemoveAll(backup) }
+	if err := copyDir(serverDir, backup); err != nil {
+		discard()
+		return nil, nil, fmt.Errorf("failed to back up %s: %w", serverDir, err)
+	}
+
+	restore = func() error {
+		if err := os.RemoveAll(serverDir); err != nil {
+			return err
+		}
+		return os.Rename(backup, serverDir)
+	}
+	return restore, discard, nil
+}
+
+// copyDir copies a directory tree, keeping file modes and symbolic links
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+// copyFile copies a regular file
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// installedServer returns the details of an already installed server version
+func (m *Manager) installedServer(name, version, repository string) (*MCPServer, error) {
+	if manifest, err := m.readInstallManifest(name, version); err == nil {
+		return manifest, nil
+	}
+
+	serverDir := filepath.Join(m.dataDir, name, version)
+	command, args, env, err := m.determineExecution(serverDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine execution configuration: %w", err)
+	}
+
+	return &MCPServer{
+		Name:        name,
+		Version:     version,
+		Repository:  repository,
+		InstallPath: serverDir,
+		Installed:   true,
+		Command:     command,
+		Args:        args,
+		Env:         env,
+	}, nil
+}
@@ -0,0 +1,168 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFailedCheckRestoresPreviousBuild(t *testing.T) {
+	m, project := newTestManager(t)
+	m.EnableInstallCheck(writeProjectConfig(t, project, &ProjectConfig{}), time.Second)
+
+	serverDir := filepath.Join(m.dataDir, "srv", "latest")
+	if err := os.MkdirAll(filepath.Join(serverDir, "build"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(serverDir, "build", "index.js"), []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("build", "index.js"), filepath.Join(serverDir, "server")); err != nil {
+		t.Fatal(err)
+	}
+
+	restore, discard, err := m.backupInstall(serverDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer discard()
+
+	// The rebuild changes the install in place
+	if err := os.WriteFile(filepath.Join(serverDir, "build", "index.js"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(serverDir, "extra.js"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	servers, err := m.ListInstalledServers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 1 || servers[0].Version != "latest" {
+		t.Errorf("the backup is listed as an installed version: %+v", servers)
+	}
+
+	err = m.checkInstall(&MCPServer{Name: "srv", Version: "latest", InstallPath: serverDir}, restore)
+	if err == nil || !strings.Contains(err.Error(), "rolled back") {
+		t.Fatalf("expected the failed check to roll the install back, got %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(serverDir, "server"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "old" {
+		t.Errorf("the previous build was not restored: got %q", data)
+	}
+	if info, err := os.Stat(filepath.Join(serverDir, "build", "index.js")); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("the mode of the previous build was not kept: %v %v", info, err)
+	}
+	if _, err := os.Lstat(filepath.Join(serverDir, "extra.js")); !os.IsNotExist(err) {
+		t.Errorf("files of the failed build were kept: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(m.dataDir, "srv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the restored install to remain, got %d entries", len(entries))
+	}
+}
+
+func TestUpdateServerByName(t *testing.T) {
+	m, project := newTestManager(t)
+	repository := newTaggedRepo(t, "v1.0.0", "v1.2.0", "v2.0.0")
+	configPath := writeProjectConfig(t, project, &ProjectConfig{
+		DefaultAgent: "cursor",
+		Servers: []MCPServer{
+			{Name: "exact", Version: "v1.0.0", Repository: repository},
+			{Name: "ranged", Version: "^1.0.0", Repository: repository},
+			{Name: "lockedrepo", Version: "v1.0.0"},
+		},
+	})
+	if err := m.SaveLockFile(&LockFile{Servers: []LockedServer{
+		{Name: "ranged", Version: "v1.0.0", Repository: repository},
+		{Name: "lockedrepo", Version: "v1.0.0", Repository: repository},
+	}}, configPath); err != nil {
+		t.Fatal(err)
+	}
+	for _, server := range []struct{ name, version string }{{"exact", "v1.0.0"}, {"ranged", "v1.0.0"}, {"lockedrepo", "v1.0.0"}, {"manifest", "v1.0.0"}} {
+		if _, err := m.InstallServer(server.name, server.version, repository); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		removePrevious bool
+		want           UpdateResult
+		// declared is the version left in mcpv.json, "" when not declared
+		declared string
+	}{
+		// Exact versions move to the latest release
+		{"exact", true, UpdateResult{PreviousVersion: "v1.0.0", Version: "v2.0.0", Updated: true, RemovedPrevious: true}, "v2.0.0"},
+		// Ranges move to the highest release they allow and are kept as written
+		{"ranged", false, UpdateResult{PreviousVersion: "v1.0.0", Version: "v1.2.0", Updated: true}, "^1.0.0"},
+		// The repository comes from the lockfile
+		{"lockedrepo", false, UpdateResult{PreviousVersion: "v1.0.0", Version: "v2.0.0", Updated: true}, "v2.0.0"},
+		// or from the install manifest of a server mcpv.json does not declare
+		{"manifest", false, UpdateResult{PreviousVersion: "v1.0.0", Version: "v2.0.0", Updated: true}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := m.UpdateServerByName(tt.name, configPath, tt.removePrevious)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.want.Name, tt.want.Repository = tt.name, repository
+			if *result != tt.want {
+				t.Errorf("got %+v\nwant %+v", *result, tt.want)
+			}
+
+			config, err := m.LoadProjectConfig(configPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			declared := ""
+			for _, server := range config.Servers {
+				if server.Name == tt.name {
+					declared = server.Version
+				}
+			}
+			if declared != tt.declared {
+				t.Errorf("mcpv.json declares %q, want %q", declared, tt.declared)
+			}
+
+			lock, err := m.LoadLockFile(configPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if locked := lock.Find(tt.name); locked == nil || locked.Version != tt.want.Version || locked.Repository != repository {
+				t.Errorf("mcpv.lock records %+v, want %s from %s", locked, tt.want.Version, repository)
+			}
+
+			entry := readAgentEntry(t, filepath.Join(project, ".cursor", "mcp.json"), "mcpServers", tt.name)
+			if want := filepath.Join(m.dataDir, tt.name, tt.want.Version, "server"); entry["command"] != want {
+				t.Errorf("cursor runs %v, want %s", entry["command"], want)
+			}
+
+			_, err = os.Stat(filepath.Join(m.dataDir, tt.name, tt.want.PreviousVersion))
+			if tt.removePrevious != os.IsNotExist(err) {
+				t.Errorf("with removePrevious %v, the previous version's install gives %v", tt.removePrevious, err)
+			}
+		})
+	}
+
+	// A second update finds nothing newer
+	result, err := m.UpdateServerByName("exact", configPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Updated || result.Version != "v2.0.0" {
+		t.Errorf("updating an up to date server gave %+v", result)
+	}
+}
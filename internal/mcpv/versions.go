@@ -0,0 +1,361 @@
+package manager
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// RemoteVersion is a semantic version tag published by a server repository
+type RemoteVersion struct {
+	Tag     string
+	Version *semver.Version
+}
+
+// OutdatedServer describes how an installed server compares to its remote tags
+type OutdatedServer struct {
+	Name       string `json:"name" yaml:"name"`
+	Repository string `json:"repository,omitempty" yaml:"repository,omitempty"`
+	Constraint string `json:"constraint,omitempty" yaml:"constraint,omitempty"`
+	Current    string `json:"current,omitempty" yaml:"current,omitempty"`
+	Wanted     string `json:"wanted,omitempty" yaml:"wanted,omitempty"`
+	Latest     string `json:"latest,omitempty" yaml:"latest,omitempty"`
+	Outdated   bool   `json:"outdated" yaml:"outdated"`
+	Error      string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ListRemoteVersions returns the semantic version tags of a repository, oldest first.
+// Tags that are not valid semantic versions are ignored.
+func (m *Manager) ListRemoteVersions(repoURL string) ([]RemoteVersion, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repoURL},
+	})
+
+	refs, err := remote.List(&git.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote references: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var versions []RemoteVersion
+	for _, ref := range refs {
+		if !ref.Name().IsTag() {
+			continue
+		}
+
+		tag := ref.Name().Short()
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+
+		version, err := semver.NewVersion(tag)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, RemoteVersion{Tag: tag, Version: version})
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version.LessThan(versions[j].Version)
+	})
+
+	return versions, nil
+}
+
+// latestRemoteVersion returns the highest stable version, falling back to the
+// highest pre-release when a repository has only published pre-releases
+func latestRemoteVersion(versions []RemoteVersion) (RemoteVersion, bool) {
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].Version.Prerelease() == "" {
+			return versions[i], true
+		}
+	}
+	if len(versions) > 0 {
+		return versions[len(versions)-1], true
+	}
+	return RemoteVersion{}, false
+}
+
+// wantedRemoteVersion returns the highest version satisfying a constraint.
+// An empty constraint or "latest" matches the latest version.
+func wantedRemoteVersion(versions []RemoteVersion, constraint string) (RemoteVersion, bool, error) {
+	if constraint == "" || constraint == "latest" {
+		latest, ok := latestRemoteVersion(versions)
+		return latest, ok, nil
+	}
+
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return RemoteVersion{}, false, fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+	}
+
+	for i := len(versions) - 1; i >= 0; i-- {
+		if c.Check(versions[i].Version) {
+			return versions[i], true, nil
+		}
+	}
+	return RemoteVersion{}, false, nil
+}
+
+// ResolveVersion returns the tag of the highest remote version satisfying a constraint
+func (m *Manager) ResolveVersion(repoURL, constraint string) (string, error) {
+	versions, err := m.ListRemoteVersions(repoURL)
+	if err != nil {
+		return "", err
+	}
+
+	wanted, ok, err := wantedRemoteVersion(versions, constraint)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("no version of %s matches %q", repoURL, constraint)
+	}
+	return wanted.Tag, nil
+}
+
+// installVersion returns the version to install for a server of mcpv.json:
+// the version locked in mcpv.lock when the entry allows it, otherwise the
+// highest release matching a range, or the entry's version as written.
+// Entries without a version install "latest".
+func (m *Manager) installVersion(configPath string, server *MCPServer) (string, error) {
+	lock, err := m.LoadLockFile(configPath)
+	if err != nil {
+		return "", err
+	}
+	if locked := lock.Find(server.Name); locked != nil && allowsVersion(server.Version, locked.Version) {
+		return locked.Version, nil
+	}
+
+	switch {
+	case isVersionRange(server.Version):
+		version, err := m.ResolveVersion(server.Repository, server.Version)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve version %s of %s: %w", server.Version, server.Name, err)
+		}
+		return version, nil
+	case server.Version == "":
+		return "latest", nil
+	}
+	return server.Version, nil
+}
+
+// DeclaredVersionInUse returns the version written in mcpv.json for the entry
+// of a server that uses an installed version: the version locked in mcpv.lock
+// when the entry allows it, otherwise any version the entry accepts. ok is
+// false when no entry of the server uses the version.
+func (m *Manager) DeclaredVersionInUse(configPath, name, version string) (declared string, ok bool, err error) {
+	config, err := m.LoadProjectConfig(configPath)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to load config: %w", err)
+	}
+	lock, err := m.LoadLockFile(configPath)
+	if err != nil {
+		return "", false, err
+	}
+	locked := lock.Find(name)
+
+	for _, server := range config.Servers {
+		if server.Name != name {
+			continue
+		}
+		if locked != nil && allowsVersion(server.Version, locked.Version) {
+			if locked.Version == version {
+				return server.Version, true, nil
+			}
+			continue
+		}
+		if allowsVersion(server.Version, version) {
+			return server.Version, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// allowsVersion reports whether a mcpv.json version accepts a locked version:
+// a missing version accepts any, a range the versions it matches and an exact
+// version only itself
+func allowsVersion(configured, locked string) bool {
+	switch {
+	case locked == "":
+		return false
+	case configured == "" || configured == locked:
+		return true
+	case !isVersionRange(configured):
+		return false
+	}
+
+	version, err := semver.NewVersion(locked)
+	if err != nil {
+		return false
+	}
+	c, err := semver.NewConstraint(configured)
+	return err == nil && c.Check(version)
+}
+
+// installedRepository returns the origin URL of an installed server checkout
+func (m *Manager) installedRepository(name, version string) string {
+	repo, err := git.PlainOpen(filepath.Join(m.dataDir, name, version))
+	if err != nil {
+		return ""
+	}
+
+	remote, err := repo.Remote("origin")
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return ""
+	}
+	return remote.Config().URLs[0]
+}
+
+// currentInstalledVersion picks the installed version that best represents the
+// server: the highest one satisfying the constraint, otherwise the highest one
+func currentInstalledVersion(installed []string, constraint string) string {
+	var c *semver.Constraints
+	if constraint != "" && constraint != "latest" {
+		c, _ = semver.NewConstraint(constraint)
+	}
+
+	var best, bestMatching *semver.Version
+	var bestTag, bestMatchingTag string
+	for _, tag := range installed {
+		if tag == constraint {
+			return tag
+		}
+
+		version, err := semver.NewVersion(tag)
+		if err != nil {
+			continue
+		}
+		if best == nil || version.GreaterThan(best) {
+			best, bestTag = version, tag
+		}
+		if c != nil && c.Check(version) && (bestMatching == nil || version.GreaterThan(bestMatching)) {
+			bestMatching, bestMatchingTag = version, tag
+		}
+	}
+
+	if bestMatchingTag != "" {
+		return bestMatchingTag
+	}
+	if bestTag != "" {
+		return bestTag
+	}
+	if len(installed) > 0 {
+		return installed[len(installed)-1]
+	}
+	return ""
+}
+
+// CheckOutdated compares servers against the tags published by their repositories.
+// Servers are taken from the project config when it declares any, otherwise from
+// the installed servers. The current version of a server is the one locked in
+// mcpv.lock, or else the installed version that best matches its constraint.
+func (m *Manager) CheckOutdated(configPath string) ([]OutdatedServer, error) {
+	config, err := m.LoadProjectConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := m.LoadLockFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	installed, err := m.ListInstalledServers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list installed servers: %w", err)
+	}
+
+	installedVersions := make(map[string][]string)
+	var names []string
+	for _, server := range installed {
+		if _, ok := installedVersions[server.Name]; !ok {
+			names = append(names, server.Name)
+		}
+		installedVersions[server.Name] = append(installedVersions[server.Name], server.Version)
+	}
+
+	servers := config.Servers
+	if len(servers) == 0 {
+		for _, name := range names {
+			versions := installedVersions[name]
+			servers = append(servers, MCPServer{
+				Name:       name,
+				Repository: m.installedRepository(name, versions[len(versions)-1]),
+			})
+		}
+	}
+
+	report := []OutdatedServer{}
+	for _, server := range servers {
+		if server.IsRemote() {
+			// Remote servers are not versioned by mcpv
+			continue
+		}
+		entry := OutdatedServer{
+			Name:       server.Name,
+			Repository: server.Repository,
+			Constraint: server.Version,
+			Current:    currentInstalledVersion(installedVersions[server.Name], server.Version),
+		}
+		if locked := lock.Find(server.Name); locked != nil && locked.Version != "" {
+			entry.Current = locked.Version
+		}
+
+		if entry.Repository == "" {
+			entry.Error = "no repository specified"
+			report = append(report, entry)
+			continue
+		}
+
+		versions, err := m.ListRemoteVersions(entry.Repository)
+		if err != nil {
+			entry.Error = err.Error()
+			report = append(report, entry)
+			continue
+		}
+
+		if latest, ok := latestRemoteVersion(versions); ok {
+			entry.Latest = latest.Tag
+		}
+		wanted, ok, err := wantedRemoteVersion(versions, server.Version)
+		if err != nil {
+			entry.Error = err.Error()
+		} else if ok {
+			entry.Wanted = wanted.Tag
+		}
+
+		entry.Outdated = isBehind(entry.Current, entry.Wanted) || isBehind(entry.Current, entry.Latest)
+		report = append(report, entry)
+	}
+
+	return report, nil
+}
+
+// isBehind reports whether current is older than target. A missing current
+// version is always behind an available target.
+func isBehind(current, target string) bool {
+	if target == "" {
+		return false
+	}
+	if current == "" {
+		return true
+	}
+
+	// Versions such as "latest" or a branch name cannot be compared
+	currentVersion, err := semver.NewVersion(current)
+	if err != nil {
+		return false
+	}
+	targetVersion, err := semver.NewVersion(target)
+	if err != nil {
+		return false
+	}
+	return currentVersion.LessThan(targetVersion)
+}
@@ -0,0 +1,51 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckOutdated(t *testing.T) {
+	m, project := newTestManager(t)
+	repository := newTaggedRepo(t, "v1.0.0", "v1.1.0", "v2.0.0")
+	configPath := writeProjectConfig(t, project, &ProjectConfig{Servers: []MCPServer{
+		{Name: "locked", Version: "^1.0.0", Repository: repository},
+		{Name: "ranged", Version: "^1.0.0", Repository: repository},
+		{Name: "pinned", Version: "v2.0.0", Repository: repository},
+		{Name: "missing", Version: "v2.0.0", Repository: repository},
+		{Name: "norepo", Version: "v1.0.0"},
+		{Name: "remote", URL: "https://example.com/mcp"},
+	}})
+
+	for _, installed := range []string{"locked/v1.0.0", "locked/v1.1.0", "ranged/v1.1.0", "pinned/v2.0.0"} {
+		if err := os.MkdirAll(filepath.Join(m.dataDir, installed), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The lock pins the project to the older of the installed versions
+	if err := m.SaveLockFile(&LockFile{Servers: []LockedServer{{Name: "locked", Version: "v1.0.0", Repository: repository}}}, configPath); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := m.CheckOutdated(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []OutdatedServer{
+		{Name: "locked", Repository: repository, Constraint: "^1.0.0", Current: "v1.0.0", Wanted: "v1.1.0", Latest: "v2.0.0", Outdated: true},
+		{Name: "ranged", Repository: repository, Constraint: "^1.0.0", Current: "v1.1.0", Wanted: "v1.1.0", Latest: "v2.0.0", Outdated: true},
+		{Name: "pinned", Repository: repository, Constraint: "v2.0.0", Current: "v2.0.0", Wanted: "v2.0.0", Latest: "v2.0.0"},
+		{Name: "missing", Repository: repository, Constraint: "v2.0.0", Wanted: "v2.0.0", Latest: "v2.0.0", Outdated: true},
+		{Name: "norepo", Constraint: "v1.0.0", Error: "no repository specified"},
+	}
+	if len(report) != len(want) {
+		t.Fatalf("got %d servers, want %d: %+v", len(report), len(want), report)
+	}
+	for i := range want {
+		if report[i] != want[i] {
+			t.Errorf("got %+v\nwant %+v", report[i], want[i])
+		}
+	}
+}
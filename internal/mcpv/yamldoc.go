@@ -0,0 +1,645 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlAgentDocument is an agent configuration in YAML. Entries are either a
+// mapping keyed by server name or, when nameKey is set, a list of entries that
+// carry the server name in that field. Edits are made to the parsed node tree
+// and only the edited entry is rendered back and spliced into the original
+// text, so indentation, blank lines and comments elsewhere are kept.
+type yamlAgentDocument struct {
+	src     []byte
+	doc     *yaml.Node
+	lines   []int
+	root    []string
+	nameKey string
+}
+
+func newYAMLAgentDocument(data []byte, root []string, nameKey string) (*yamlAgentDocument, error) {
+	d := &yamlAgentDocument{root: root, nameKey: nameKey}
+	if err := d.parse(data); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// parse reads the node tree of src and indexes its lines
+func (d *yamlAgentDocument) parse(src []byte) error {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(src, doc); err != nil {
+		return err
+	}
+	if len(doc.Content) > 0 && doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("top-level value is not a mapping")
+	}
+
+	d.src = src
+	d.doc = doc
+	d.lines = d.lines[:0]
+	for i := 0; i < len(src); {
+		d.lines = append(d.lines, i)
+		next := bytes.IndexByte(src[i:], '\n')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return nil
+}
+
+// replace substitutes src[start:end] with text and re-parses the document
+func (d *yamlAgentDocument) replace(start, end int, text string) error {
+	var buf bytes.Buffer
+	buf.Write(d.src[:start])
+	buf.WriteString(text)
+	buf.Write(d.src[end:])
+
+	if err := d.parse(buf.Bytes()); err != nil {
+		return fmt.Errorf("edit produced invalid YAML: %w", err)
+	}
+	return nil
+}
+
+// top returns the top-level mapping, or nil for an empty document
+func (d *yamlAgentDocument) top() *yaml.Node {
+	if len(d.doc.Content) == 0 {
+		return nil
+	}
+	return d.doc.Content[0]
+}
+
+// container returns the node holding server entries, creating it in the node
+// tree if asked
+func (d *yamlAgentDocument) container(create bool) (*yaml.Node, error) {
+	if d.top() == nil {
+		if !create {
+			return nil, nil
+		}
+		d.doc.Kind = yaml.DocumentNode
+		d.doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+
+	node := d.top()
+	for i, key := range d.root {
+		kind, tag := yaml.MappingNode, "!!map"
+		if i == len(d.root)-1 && d.nameKey != "" {
+			kind, tag = yaml.SequenceNode, "!!seq"
+		}
+
+		value := yamlMappingValue(node, key)
+		if value == nil || value.Kind != kind {
+			if !create {
+				return nil, nil
+			}
+			value = &yaml.Node{Kind: kind, Tag: tag}
+			if err := yamlSetMappingValue(node, key, value); err != nil {
+				return nil, err
+			}
+		}
+		node = value
+	}
+	return node, nil
+}
+
+// entry returns a server's entry and its index in the container, or nil
+func (d *yamlAgentDocument) entry(container *yaml.Node, name string) (*yaml.Node, int) {
+	if d.nameKey == "" {
+		for i := 0; i+1 < len(container.Content); i += 2 {
+			if container.Content[i].Value == name {
+				return container.Content[i+1], i
+			}
+		}
+		return nil, -1
+	}
+
+	for i, item := range container.Content {
+		if item.Kind != yaml.MappingNode {
+			continue
+		}
+		if value := yamlMappingValue(item, d.nameKey); value != nil && value.Value == name {
+			return item, i
+		}
+	}
+	return nil, -1
+}
+
+func (d *yamlAgentDocument) entries() (map[string]interface{}, error) {
+	container, err := d.container(false)
+	if err != nil || container == nil {
+		return map[string]interface{}{}, err
+	}
+
+	entries := map[string]interface{}{}
+	if d.nameKey == "" {
+		if err := container.Decode(&entries); err != nil {
+			return nil, err
+		}
+		return entries, nil
+	}
+
+	for _, item := range container.Content {
+		var entry map[string]interface{}
+		if err := item.Decode(&entry); err != nil {
+			continue
+		}
+		if name, ok := entry[d.nameKey].(string); ok {
+			entries[name] = entry
+		}
+	}
+	return entries, nil
+}
+
+func (d *yamlAgentDocument) setEntry(name string, fields orderedObject) error {
+	container, err := d.container(false)
+	if err != nil {
+		return err
+	}
+	if container == nil || !yamlIsBlock(container) || len(container.Content) == 0 {
+		// The container is written out together with the new entry
+		return d.rewriteContainer(func(container *yaml.Node) error {
+			_, err := d.setEntryNode(container, name, fields)
+			return err
+		})
+	}
+
+	_, index := d.entry(container, name)
+	if index < 0 {
+		// Add the entry after the last one, at the same indentation
+		start, end, indent := d.entryLines(container, len(container.Content)-d.entrySize())
+		if _, err := d.setEntryNode(container, name, fields); err != nil {
+			return err
+		}
+		text, err := d.render(d.entryNode(container, len(container.Content)-d.entrySize()), indent)
+		if err != nil {
+			return err
+		}
+		// Separate it by a blank line when the entries are
+		if start = d.headStart(start, indent); start > 1 && d.isBlank(start-1) {
+			text = "\n" + text
+		}
+		at := d.lineStart(end)
+		return d.replace(at, at, d.lineBreakBefore(at)+text)
+	}
+
+	start, end, indent := d.entryLines(container, index)
+	tail := d.src[d.lineStart(end):d.lineStart(d.nextContentLine(end))]
+	changed, err := d.setEntryNode(container, name, fields)
+	if err != nil || !changed {
+		return err
+	}
+
+	node := d.entryNode(container, index)
+	clearYAMLFootComments(node, tail)
+	text, err := d.render(node, indent)
+	if err != nil {
+		return err
+	}
+	return d.replace(d.lineStart(start), d.lineStart(end), text)
+}
+
+// setEntryNode applies fields to a server's entry in the node tree, creating
+// the entry if needed, and reports whether anything changed
+func (d *yamlAgentDocument) setEntryNode(container *yaml.Node, name string, fields orderedObject) (bool, error) {
+	changed := false
+	entry, _ := d.entry(container, name)
+	if entry == nil || entry.Kind != yaml.MappingNode {
+		changed = true
+		entry = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if d.nameKey == "" {
+			if err := yamlSetMappingValue(container, name, entry); err != nil {
+				return false, err
+			}
+		} else {
+			nameNode := &yaml.Node{}
+			if err := nameNode.Encode(name); err != nil {
+				return false, err
+			}
+			if err := yamlSetMappingValue(entry, d.nameKey, nameNode); err != nil {
+				return false, err
+			}
+			container.Content = append(container.Content, entry)
+		}
+	}
+
+	// Decoding the whole entry resolves merge keys, so values the entry
+	// already gets from an anchor are not written again
+	var current map[string]interface{}
+	entry.Decode(&current)
+	for _, field := range fields {
+		if field.Value == nil {
+			if yamlRemoveMappingKey(entry, field.Key) {
+				changed = true
+			}
+			continue
+		}
+
+		if value, ok := current[field.Key]; ok && jsonEqual(value, field.Value) {
+			continue
+		}
+
+		value := &yaml.Node{}
+		if err := value.Encode(field.Value); err != nil {
+			return false, err
+		}
+		if err := yamlSetMappingValue(entry, field.Key, value); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	return changed, nil
+}
+
+func (d *yamlAgentDocument) removeEntry(name string) (bool, error) {
+	container, err := d.container(false)
+	if err != nil || container == nil {
+		return false, err
+	}
+
+	entry, index := d.entry(container, name)
+	if entry == nil {
+		return false, nil
+	}
+
+	size := d.entrySize()
+	if !yamlIsBlock(container) || len(container.Content) == size {
+		return true, d.rewriteContainer(func(container *yaml.Node) error {
+			_, index := d.entry(container, name)
+			container.Content = append(container.Content[:index], container.Content[index+size:]...)
+			return nil
+		})
+	}
+
+	// The comment above an entry goes with it, as does one of the blank
+	// lines around it
+	start, end, indent := d.entryLines(container, index)
+	start = d.headStart(start, indent)
+	if start > 1 && d.isBlank(start-1) && (end > len(d.lines) || d.isBlank(end) || d.indent(end) < indent) {
+		start--
+	}
+	return true, d.replace(d.lineStart(start), d.lineStart(end), "")
+}
+
+// rewriteContainer edits the container in the node tree and renders it back
+// with its key, for containers that are missing, empty or written in flow
+// style. The key is written into the closest enclosing block mapping.
+func (d *yamlAgentDocument) rewriteContainer(edit func(container *yaml.Node) error) error {
+	parent := d.top()
+	depth := 0
+	for ; parent != nil && depth < len(d.root)-1; depth++ {
+		value := yamlMappingValue(parent, d.root[depth])
+		if value == nil || value.Kind != yaml.MappingNode || !yamlIsBlock(value) || len(value.Content) == 0 {
+			break
+		}
+		parent = value
+	}
+	key := d.root[depth]
+
+	// Locate the text of the key being rewritten, or where to add it
+	start, end, indent := -1, -1, 0
+	if parent != nil {
+		for i := 0; i+1 < len(parent.Content); i += 2 {
+			if parent.Content[i].Value == key {
+				start, end, indent = d.entryLinesAt(parent.Content[i])
+			}
+		}
+		if start < 0 && parent != d.top() && len(parent.Content) > 0 {
+			_, end, indent = d.entryLinesAt(parent.Content[len(parent.Content)-2])
+		}
+	}
+
+	container, err := d.container(true)
+	if err != nil {
+		return err
+	}
+	if err := edit(container); err != nil {
+		return err
+	}
+	if parent == nil {
+		parent = d.top()
+	}
+
+	// Everything from the rewritten key down is written in block style, with
+	// the comment of a flow value moved to its key
+	node := parent
+	for _, name := range d.root[depth:] {
+		key := yamlMappingKey(node, name)
+		node = yamlMappingValue(node, name)
+		if !yamlIsBlock(node) && node.LineComment != "" && key.LineComment == "" {
+			key.LineComment, node.LineComment = node.LineComment, ""
+		}
+		node.Style &^= yaml.FlowStyle
+	}
+
+	var pair *yaml.Node
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			keyNode := *parent.Content[i]
+			keyNode.HeadComment = ""
+			pair = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{&keyNode, parent.Content[i+1]}}
+		}
+	}
+	text, err := d.render(pair, indent)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case start >= 0:
+		return d.replace(d.lineStart(start), d.lineStart(end), text)
+	case end >= 0:
+		at := d.lineStart(end)
+		return d.replace(at, at, d.lineBreakBefore(at)+text)
+	default:
+		// A new top-level key goes at the end of the file
+		return d.replace(len(d.src), len(d.src), d.lineBreakBefore(len(d.src))+text)
+	}
+}
+
+// entrySize returns the number of container nodes making up an entry: its
+// key and value in a mapping, or a single list item
+func (d *yamlAgentDocument) entrySize() int {
+	if d.nameKey == "" {
+		return 2
+	}
+	return 1
+}
+
+// entryNode returns the node rendering the entry at index of the container:
+// the key and value of a mapping entry, or a list holding a list item
+func (d *yamlAgentDocument) entryNode(container *yaml.Node, index int) *yaml.Node {
+	if d.nameKey == "" {
+		key := *container.Content[index]
+		key.HeadComment = ""
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{&key, container.Content[index+1]}}
+	}
+	item := *container.Content[index]
+	item.HeadComment = ""
+	return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{&item}}
+}
+
+// entryLines returns the lines of the entry at index of a block container
+func (d *yamlAgentDocument) entryLines(container *yaml.Node, index int) (start, end, indent int) {
+	return d.entryLinesAt(container.Content[index])
+}
+
+// entryLinesAt returns the first line of the mapping key or list item that
+// starts at node, the line after its last content line and its indentation.
+// Comments directly below an entry are left to the entry that follows.
+func (d *yamlAgentDocument) entryLinesAt(node *yaml.Node) (start, end, indent int) {
+	start = node.Line
+	// A list item may start with a dash on a line of its own
+	for start > 1 && !d.isListItem(start) && string(bytes.TrimSpace(d.line(start-1))) == "-" {
+		start--
+	}
+	indent = d.indent(start)
+
+	// The value of a mapping key may be a list whose dashes sit at the key's
+	// own column
+	isKey := !d.isListItem(start)
+	end = start + 1
+	for ; end <= len(d.lines); end++ {
+		if d.isBlankOrComment(end) || d.indent(end) > indent {
+			continue
+		}
+		if !isKey || d.indent(end) < indent || !d.isListItem(end) {
+			break
+		}
+	}
+	for end > start+1 && d.isBlankOrComment(end-1) {
+		end--
+	}
+	return start, end, indent
+}
+
+// headStart returns the first line of the comment directly above the entry
+// starting at line start, or start when there is none
+func (d *yamlAgentDocument) headStart(start, indent int) int {
+	for start > 1 && !d.isBlank(start-1) && d.isBlankOrComment(start-1) && d.indent(start-1) == indent {
+		start--
+	}
+	return start
+}
+
+// nextContentLine returns the first line from line on that is not blank or a comment
+func (d *yamlAgentDocument) nextContentLine(line int) int {
+	for line <= len(d.lines) && d.isBlankOrComment(line) {
+		line++
+	}
+	return line
+}
+
+// line returns the text of a line, numbered from 1, without its line break
+func (d *yamlAgentDocument) line(n int) []byte {
+	if n < 1 || n > len(d.lines) {
+		return nil
+	}
+	return bytes.TrimRight(d.src[d.lines[n-1]:d.lineStart(n+1)], "\r\n")
+}
+
+// lineStart returns the offset of a line, or the end of the document past
+// the last line
+func (d *yamlAgentDocument) lineStart(n int) int {
+	if n > len(d.lines) {
+		return len(d.src)
+	}
+	return d.lines[n-1]
+}
+
+func (d *yamlAgentDocument) indent(n int) int {
+	line := d.line(n)
+	return len(line) - len(bytes.TrimLeft(line, " "))
+}
+
+// isListItem reports whether a line starts a list item
+func (d *yamlAgentDocument) isListItem(n int) bool {
+	line := bytes.TrimLeft(d.line(n), " ")
+	return bytes.Equal(line, []byte("-")) || bytes.HasPrefix(line, []byte("- "))
+}
+
+func (d *yamlAgentDocument) isBlank(n int) bool {
+	return len(bytes.TrimSpace(d.line(n))) == 0
+}
+
+func (d *yamlAgentDocument) isBlankOrComment(n int) bool {
+	line := bytes.TrimSpace(d.line(n))
+	return len(line) == 0 || line[0] == '#'
+}
+
+// lineBreakBefore returns a newline when inserting at offset would otherwise
+// continue an unterminated last line
+func (d *yamlAgentDocument) lineBreakBefore(offset int) string {
+	if offset > 0 && d.src[offset-1] != '\n' {
+		return "\n"
+	}
+	return ""
+}
+
+// indentUnit guesses the indentation step of the document from its first
+// nested block mapping
+func (d *yamlAgentDocument) indentUnit() int {
+	var find func(node *yaml.Node) int
+	find = func(node *yaml.Node) int {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				value := node.Content[i+1]
+				if value.Kind == yaml.MappingNode && yamlIsBlock(value) && len(value.Content) > 0 {
+					if unit := value.Content[0].Column - node.Content[i].Column; unit > 0 {
+						return unit
+					}
+				}
+				if unit := find(value); unit > 0 {
+					return unit
+				}
+			}
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				if unit := find(item); unit > 0 {
+					return unit
+				}
+			}
+		}
+		return 0
+	}
+
+	if top := d.top(); top != nil {
+		if unit := find(top); unit > 0 {
+			return unit
+		}
+	}
+	return 2
+}
+
+// render encodes a node at the given indentation
+func (d *yamlAgentDocument) render(node *yaml.Node, indent int) (string, error) {
+	clearYAMLMergeTags(node)
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(d.indentUnit())
+	if err := enc.Encode(node); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+
+	prefix := strings.Repeat(" ", indent)
+	var text strings.Builder
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line != "" && line != "\n" {
+			text.WriteString(prefix)
+		}
+		text.WriteString(line)
+	}
+	return text.String(), nil
+}
+
+func (d *yamlAgentDocument) bytes() ([]byte, error) {
+	return d.src, nil
+}
+
+// yamlIsBlock reports whether a node is written in block style
+func yamlIsBlock(node *yaml.Node) bool {
+	return node.Style&yaml.FlowStyle == 0
+}
+
+// clearYAMLMergeTags drops the tag the parser gives merge keys, which the
+// encoder would otherwise write out as !!merge <<
+func clearYAMLMergeTags(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!merge" && node.Value == "<<" {
+		node.Tag = ""
+	}
+	for _, child := range node.Content {
+		clearYAMLMergeTags(child)
+	}
+}
+
+// clearYAMLFootComments drops foot comments that are part of tail, the
+// comment lines kept in the text below an entry being rewritten
+func clearYAMLFootComments(node *yaml.Node, tail []byte) {
+	if node.FootComment != "" && bytes.Contains(tail, []byte(strings.TrimSpace(strings.SplitN(node.FootComment, "\n", 2)[0]))) {
+		node.FootComment = ""
+	}
+	for _, child := range node.Content {
+		clearYAMLFootComments(child, tail)
+	}
+}
+
+// yamlMappingKey returns the key node of key in a mapping node, or nil
+func yamlMappingKey(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i]
+		}
+	}
+	return nil
+}
+
+// yamlMappingValue returns the value of key in a mapping node, or nil
+func yamlMappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// yamlSetMappingValue replaces the value of key in a mapping node, appending
+// the key when it is missing
+func yamlSetMappingValue(mapping *yaml.Node, key string, value *yaml.Node) error {
+	if value.Kind == yaml.DocumentNode {
+		value = value.Content[0]
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			// Keep comments attached to the value being replaced
+			value.LineComment = mapping.Content[i+1].LineComment
+			mapping.Content[i+1] = value
+			return nil
+		}
+	}
+
+	keyNode := &yaml.Node{}
+	if err := keyNode.Encode(key); err != nil {
+		return err
+	}
+	mapping.Content = append(mapping.Content, keyNode, value)
+	return nil
+}
+
+// yamlRemoveMappingKey deletes key from a mapping node, reporting whether it existed
+func yamlRemoveMappingKey(mapping *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalYAML renders the fields as a mapping in order
+func (o orderedObject) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, field := range o {
+		key := &yaml.Node{}
+		if err := key.Encode(field.Key); err != nil {
+			return nil, err
+		}
+		value := &yaml.Node{}
+		if err := value.Encode(field.Value); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, key, value)
+	}
+	return node, nil
+}